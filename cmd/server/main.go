@@ -14,6 +14,7 @@ import (
 
 	"open-cluster-management.io/multicluster-controlplane/pkg/cmd/agent"
 	"open-cluster-management.io/multicluster-controlplane/pkg/cmd/controller"
+	"open-cluster-management.io/multicluster-controlplane/pkg/cmd/token"
 )
 
 func init() {
@@ -39,6 +40,7 @@ func newControlPlaneCommand() *cobra.Command {
 
 	cmd.AddCommand(controller.NewController())
 	cmd.AddCommand(agent.NewAgent())
+	cmd.AddCommand(token.NewToken())
 
 	return cmd
 }
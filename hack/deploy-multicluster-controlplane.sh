@@ -19,7 +19,7 @@ image=${IMAGE_NAME:-"quay.io/stolostron/multicluster-controlplane:latest"}
 external_hostname=${EXTERNAL_HOSTNAME:-""}
 node_port=${NODE_PORT:-0}
 etcd_mod=${ETCD_MOD:-""}
-feature_gates=${FEATURE_GATES:-"DefaultClusterSet=true,ManagedClusterAutoApproval=true"}
+feature_gates=${FEATURE_GATES:-"DefaultClusterSet=true,ManagedClusterAutoApproval=true,ManagedServiceAccount=true"}
 
 if [ "$uninstall"x = "uninstall"x ]; then
     helm -n ${HUB_NAME} uninstall multicluster-controlplane
@@ -14,7 +14,7 @@ DATA_DIR=${DATA_DIR:-"${REPO_DIR}/_output/controlplane/.ocm"}
 CONTROLPLANE_PORT=${CONTROLPLANE_PORT:-"9443"}
 ETCD_MODE=${ETCD_MODE:-"embed"}
 BOOTSTRAP_USERS=${BOOTSTRAP_USERS:-""}
-FEATURE_GATES=${FEATURE_GATES:-"DefaultClusterSet=true,ManagedClusterAutoApproval=true"}
+FEATURE_GATES=${FEATURE_GATES:-"DefaultClusterSet=true,ManagedClusterAutoApproval=true,ManagedServiceAccount=true"}
 
 # Stop right away if the build fails
 set -e
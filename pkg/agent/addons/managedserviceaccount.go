@@ -8,19 +8,50 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/component-base/featuregate"
 
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	authv1beta1 "open-cluster-management.io/managed-serviceaccount/apis/authentication/v1beta1"
 	"open-cluster-management.io/managed-serviceaccount/pkg/addon/agent/controller"
 	"open-cluster-management.io/managed-serviceaccount/pkg/common"
+	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 )
 
-func StartManagedServiceAccountAgent(ctx context.Context, hubMgr manager.Manager, clusterName string) error {
+// ManagedServiceAccountAddOnName is the name of the managed serviceaccount addon
+const ManagedServiceAccountAddOnName = "managed-serviceaccount"
+
+func init() {
+	Register(&managedServiceAccountAddOn{})
+}
+
+// managedServiceAccountAddOn syncs the ManagedServiceAccounts of the cluster to the serviceaccounts on the managed
+// cluster, and reports the tokens of the serviceaccounts back to the hub.
+type managedServiceAccountAddOn struct{}
+
+func (a *managedServiceAccountAddOn) Name() string {
+	return ManagedServiceAccountAddOnName
+}
+
+func (a *managedServiceAccountAddOn) FeatureGate() featuregate.Feature {
+	return mcfeature.ManagedServiceAccount
+}
+
+func (a *managedServiceAccountAddOn) AddToHubScheme(scheme *runtime.Scheme) error {
+	return authv1beta1.AddToScheme(scheme)
+}
+
+func (a *managedServiceAccountAddOn) AddToSpokeScheme(_ *runtime.Scheme) error {
+	return nil
+}
+
+func (a *managedServiceAccountAddOn) SetupWithManager(ctx context.Context, hubMgr manager.Manager,
+	addOnCtx *AddOnContext) error {
 	spokeNamespace := util.GetComponentNamespace()
 
 	hubNativeClient, err := kubernetes.NewForConfig(hubMgr.GetConfig())
@@ -28,32 +59,18 @@ func StartManagedServiceAccountAgent(ctx context.Context, hubMgr manager.Manager
 		return fmt.Errorf("unable to instantiate a kubernetes native client")
 	}
 
-	spokeCfg, err := rest.InClusterConfig()
-	if err != nil {
-		return fmt.Errorf("failed build a in-cluster spoke cluster client config")
-	}
-
+	spokeCfg := addOnCtx.SpokeKubeConfig
 	spokeNativeClient, err := kubernetes.NewForConfig(spokeCfg)
 	if err != nil {
 		return fmt.Errorf("unable to build a spoke kubernetes client")
 	}
 
-	resources, err := spokeNativeClient.Discovery().ServerResourcesForGroupVersion("v1")
-	if err != nil {
-		return fmt.Errorf("failed api discovery in the spoke cluster: %v", err)
-	}
-	found := false
-	for _, r := range resources.APIResources {
-		if r.Kind == "TokenRequest" {
-			found = true
-		}
-	}
-	if !found {
-		return fmt.Errorf(`no "serviceaccounts/token" resource discovered in the managed cluster,` +
-			`is --service-account-signing-key-file configured for the kube-apiserver?`)
+	if err := CheckTokenRequestSupport(spokeNativeClient); err != nil {
+		return err
 	}
 
 	spokeCache, err := cache.New(spokeCfg, cache.Options{
+		Scheme: addOnCtx.SpokeScheme,
 		ByObject: map[client.Object]cache.ByObject{
 			&corev1.ServiceAccount{}: {
 				Namespaces: map[string]cache.Config{
@@ -76,7 +93,7 @@ func StartManagedServiceAccountAgent(ctx context.Context, hubMgr manager.Manager
 	}
 
 	ctrl := controller.TokenReconciler{
-		ClusterName:       clusterName,
+		ClusterName:       addOnCtx.ClusterName,
 		Cache:             hubMgr.GetCache(),
 		HubClient:         hubMgr.GetClient(),
 		HubNativeClient:   hubNativeClient,
@@ -88,3 +105,19 @@ func StartManagedServiceAccountAgent(ctx context.Context, hubMgr manager.Manager
 
 	return ctrl.SetupWithManager(hubMgr)
 }
+
+// CheckTokenRequestSupport checks the TokenRequest API is supported by the managed cluster, it is required by the
+// managed serviceaccount addon to request the tokens of the serviceaccounts.
+func CheckTokenRequestSupport(spokeNativeClient kubernetes.Interface) error {
+	resources, err := spokeNativeClient.Discovery().ServerResourcesForGroupVersion("v1")
+	if err != nil {
+		return fmt.Errorf("failed api discovery in the spoke cluster: %v", err)
+	}
+	for _, r := range resources.APIResources {
+		if r.Kind == "TokenRequest" {
+			return nil
+		}
+	}
+	return fmt.Errorf(`no "serviceaccounts/token" resource discovered in the managed cluster,` +
+		`is --service-account-signing-key-file configured for the kube-apiserver?`)
+}
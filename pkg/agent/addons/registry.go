@@ -0,0 +1,129 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package addons
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/featuregate"
+	"open-cluster-management.io/ocm/pkg/features"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// AddOn is an addon that runs in the agent process, it is set up with the hub manager of the agent after the
+// cluster is registered, and it is set up again with a new manager once the hub client certificate is rotated.
+type AddOn interface {
+	// Name returns the name of the addon, it is used to enable or disable the addon by the --addons flag
+	Name() string
+	// FeatureGate returns the feature gate of the addon, the addon is enabled only if the feature is enabled, an
+	// empty feature means the addon has no feature gate
+	FeatureGate() featuregate.Feature
+	// AddToHubScheme adds the hub types of the addon to the scheme of the hub manager
+	AddToHubScheme(scheme *runtime.Scheme) error
+	// AddToSpokeScheme adds the spoke types of the addon to the scheme of the spoke clients
+	AddToSpokeScheme(scheme *runtime.Scheme) error
+	// SetupWithManager sets up the controllers of the addon with the hub manager
+	SetupWithManager(ctx context.Context, hubMgr manager.Manager, addOnCtx *AddOnContext) error
+}
+
+// FlagsAddOn is an addon that has its own flags, the flags are added to the flags of the agent
+type FlagsAddOn interface {
+	AddFlags(fs *pflag.FlagSet)
+}
+
+// AddOnContext is the context of the managed cluster that the addons run for
+type AddOnContext struct {
+	ClusterName     string
+	SpokeKubeConfig *rest.Config
+	// SpokeScheme contains the kubernetes types and the spoke types of the enabled addons
+	SpokeScheme *runtime.Scheme
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]AddOn{}
+)
+
+// Register registers an addon to the agent, it panics if the addon is registered more than once, so it is
+// expected to be called in the init function of the addon package
+func Register(addOn AddOn) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := registry[addOn.Name()]; ok {
+		panic(fmt.Sprintf("the addon %q is registered more than once", addOn.Name()))
+	}
+	registry[addOn.Name()] = addOn
+}
+
+// RegisteredAddOns returns the names of the registered addons
+func RegisteredAddOns() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	return sets.List(sets.KeySet(registry))
+}
+
+// AddFlags adds the flags of the registered addons to the flag set
+func AddFlags(fs *pflag.FlagSet) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	for _, name := range sets.List(sets.KeySet(registry)) {
+		if addOn, ok := registry[name].(FlagsAddOn); ok {
+			addOn.AddFlags(fs)
+		}
+	}
+}
+
+// EnabledAddOns returns the addons that are enabled by the flags and the feature gates, the flags are a list of
+// the addon names, '*' enables all of the addons, 'foo' enables the addon foo and '-foo' disables the addon foo.
+func EnabledAddOns(addOnFlags []string) ([]AddOn, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	all := false
+	enabled, disabled := sets.New[string](), sets.New[string]()
+	for _, flag := range addOnFlags {
+		flag = strings.TrimSpace(flag)
+		switch {
+		case flag == "*":
+			all = true
+			continue
+		case strings.HasPrefix(flag, "-"):
+			disabled.Insert(strings.TrimPrefix(flag, "-"))
+		default:
+			enabled.Insert(flag)
+		}
+	}
+
+	for name := range enabled.Union(disabled) {
+		if _, ok := registry[name]; !ok {
+			return nil, fmt.Errorf("the addon %q is unknown, the known addons are %s",
+				name, strings.Join(sets.List(sets.KeySet(registry)), ", "))
+		}
+	}
+
+	addOns := []AddOn{}
+	for name, addOn := range registry {
+		if disabled.Has(name) || (!all && !enabled.Has(name)) {
+			continue
+		}
+		if feature := addOn.FeatureGate(); len(feature) != 0 && !features.SpokeMutableFeatureGate.Enabled(feature) {
+			continue
+		}
+		addOns = append(addOns, addOn)
+	}
+	sort.Slice(addOns, func(i, j int) bool {
+		return addOns[i].Name() < addOns[j].Name()
+	})
+	return addOns, nil
+}
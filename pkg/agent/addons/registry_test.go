@@ -0,0 +1,93 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package addons
+
+import (
+	"reflect"
+	"testing"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
+)
+
+func init() {
+	utilruntime.Must(features.SpokeMutableFeatureGate.Add(mcfeature.DefaultControlPlaneAgentFeatureGates))
+}
+
+func TestEnabledAddOns(t *testing.T) {
+	allFeatures := map[string]bool{
+		string(mcfeature.ManagedServiceAccount): true,
+		string(mcfeature.ResourceUsageScore):    true,
+	}
+
+	tests := []struct {
+		name     string
+		flags    []string
+		features map[string]bool
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "all addons",
+			flags:    []string{"*"},
+			features: allFeatures,
+			want:     []string{ManagedServiceAccountAddOnName, ResourceUsageScoreAddOnName},
+		},
+		{
+			name:     "disable an addon",
+			flags:    []string{"*", " -" + ManagedServiceAccountAddOnName},
+			features: allFeatures,
+			want:     []string{ResourceUsageScoreAddOnName},
+		},
+		{
+			name:     "enable an addon",
+			flags:    []string{ResourceUsageScoreAddOnName},
+			features: allFeatures,
+			want:     []string{ResourceUsageScoreAddOnName},
+		},
+		{
+			name:     "no addons",
+			features: allFeatures,
+			want:     []string{},
+		},
+		{
+			name:  "the feature gate of the addon is disabled",
+			flags: []string{"*"},
+			features: map[string]bool{
+				string(mcfeature.ManagedServiceAccount): true,
+				string(mcfeature.ResourceUsageScore):    false,
+			},
+			want: []string{ManagedServiceAccountAddOnName},
+		},
+		{
+			name:     "unknown addon",
+			flags:    []string{"*", "-foo"},
+			features: allFeatures,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := features.SpokeMutableFeatureGate.SetFromMap(tt.features); err != nil {
+				t.Fatal(err)
+			}
+
+			addOns, err := EnabledAddOns(tt.flags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnabledAddOns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			names := []string{}
+			for _, addOn := range addOns {
+				names = append(names, addOn.Name())
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("EnabledAddOns() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
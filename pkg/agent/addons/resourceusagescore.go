@@ -0,0 +1,233 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package addons
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/component-base/featuregate"
+	"k8s.io/klog/v2"
+	podresource "k8s.io/kubernetes/pkg/api/v1/resource"
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
+)
+
+const (
+	// ResourceUsageScoreAddOnName is the name of the resource usage score addon
+	ResourceUsageScoreAddOnName = "resource-usage-score"
+
+	// ResourceUsageScoreName is the name of the AddOnPlacementScore that is published to the cluster namespace
+	ResourceUsageScoreName = "resource-usage-score"
+
+	// the scores are the percentages of the allocatable resources of the schedulable nodes that are not requested
+	// by the pods, from 0 (no headroom) to 100 (nothing is requested)
+	ScoreCPUAvailable    = "cpuAvailable"
+	ScoreMemoryAvailable = "memoryAvailable"
+	ScorePodAvailable    = "podAvailable"
+
+	defaultResourceUsageScoreInterval = 60 * time.Second
+)
+
+func init() {
+	Register(&resourceUsageScoreAddOn{interval: defaultResourceUsageScoreInterval})
+}
+
+// resourceUsageScoreAddOn computes the resource headroom of the managed cluster from its nodes and pods, and
+// publishes it as an AddOnPlacementScore in the cluster namespace, so the placements can prioritize the clusters
+// with the scores.
+type resourceUsageScoreAddOn struct {
+	interval time.Duration
+}
+
+func (a *resourceUsageScoreAddOn) Name() string {
+	return ResourceUsageScoreAddOnName
+}
+
+func (a *resourceUsageScoreAddOn) FeatureGate() featuregate.Feature {
+	return mcfeature.ResourceUsageScore
+}
+
+func (a *resourceUsageScoreAddOn) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&a.interval, "resource-usage-score-interval", a.interval,
+		"The interval to publish the resource usage score of the cluster to the controlplane, the score is valid "+
+			"for three intervals")
+}
+
+func (a *resourceUsageScoreAddOn) AddToHubScheme(_ *runtime.Scheme) error {
+	return nil
+}
+
+func (a *resourceUsageScoreAddOn) AddToSpokeScheme(_ *runtime.Scheme) error {
+	return nil
+}
+
+func (a *resourceUsageScoreAddOn) SetupWithManager(_ context.Context, hubMgr manager.Manager,
+	addOnCtx *AddOnContext) error {
+	if a.interval <= 0 {
+		return fmt.Errorf("the resource usage score interval should be greater than 0")
+	}
+
+	hubClusterClient, err := clusterclientset.NewForConfig(hubMgr.GetConfig())
+	if err != nil {
+		return fmt.Errorf("unable to build a hub cluster client, %v", err)
+	}
+	spokeKubeClient, err := kubernetes.NewForConfig(addOnCtx.SpokeKubeConfig)
+	if err != nil {
+		return fmt.Errorf("unable to build a spoke kubernetes client, %v", err)
+	}
+
+	publisher := &resourceUsageScorePublisher{
+		clusterName:      addOnCtx.ClusterName,
+		interval:         a.interval,
+		hubClusterClient: hubClusterClient,
+		spokeKubeClient:  spokeKubeClient,
+	}
+	return hubMgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		wait.JitterUntilWithContext(ctx, func(ctx context.Context) {
+			if err := publisher.publish(ctx); err != nil {
+				klog.Errorf("failed to publish the resource usage score of the cluster %q, %v",
+					publisher.clusterName, err)
+			}
+		}, a.interval, 0.1, true)
+		return nil
+	}))
+}
+
+type resourceUsageScorePublisher struct {
+	clusterName      string
+	interval         time.Duration
+	hubClusterClient clusterclientset.Interface
+	spokeKubeClient  kubernetes.Interface
+}
+
+func (p *resourceUsageScorePublisher) publish(ctx context.Context) error {
+	scores, err := resourceUsageScores(ctx, p.spokeKubeClient)
+	if err != nil {
+		return err
+	}
+
+	scoreClient := p.hubClusterClient.ClusterV1alpha1().AddOnPlacementScores(p.clusterName)
+	score, err := scoreClient.Get(ctx, ResourceUsageScoreName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		score, err = scoreClient.Create(ctx, &clusterv1alpha1.AddOnPlacementScore{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ResourceUsageScoreName,
+				Namespace: p.clusterName,
+			},
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	// the scores are expired if the agent stops publishing them, e.g. the cluster is disconnected
+	validUntil := metav1.NewTime(time.Now().Add(3 * p.interval))
+	status := clusterv1alpha1.AddOnPlacementScoreStatus{
+		Conditions: score.Status.Conditions,
+		Scores:     scores,
+		ValidUntil: &validUntil,
+	}
+	if equality.Semantic.DeepEqual(score.Status.Scores, status.Scores) && score.Status.ValidUntil != nil &&
+		time.Until(score.Status.ValidUntil.Time) > 2*p.interval {
+		return nil
+	}
+
+	score = score.DeepCopy()
+	score.Status = status
+	_, err = scoreClient.UpdateStatus(ctx, score, metav1.UpdateOptions{})
+	return err
+}
+
+// resourceUsageScores returns the percentages of the allocatable cpu, memory and pods of the schedulable nodes
+// that are not requested by the running pods
+func resourceUsageScores(ctx context.Context,
+	kubeClient kubernetes.Interface) ([]clusterv1alpha1.AddOnPlacementScoreItem, error) {
+	nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	schedulableNodes := sets.New[string]()
+	allocatable := corev1.ResourceList{}
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable || !isNodeReady(node) {
+			continue
+		}
+		schedulableNodes.Insert(node.Name)
+		addResources(allocatable, node.Status.Allocatable)
+	}
+
+	requested := corev1.ResourceList{}
+	podCount := int64(0)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !schedulableNodes.Has(pod.Spec.NodeName) {
+			continue
+		}
+		podCount++
+		addResources(requested, podresource.PodRequests(pod, podresource.PodResourcesOptions{}))
+	}
+
+	return []clusterv1alpha1.AddOnPlacementScoreItem{
+		{
+			Name:  ScoreCPUAvailable,
+			Value: headroom(float64(allocatable.Cpu().MilliValue()), float64(requested.Cpu().MilliValue())),
+		},
+		{
+			Name:  ScoreMemoryAvailable,
+			Value: headroom(float64(allocatable.Memory().Value()), float64(requested.Memory().Value())),
+		},
+		{
+			Name:  ScorePodAvailable,
+			Value: headroom(float64(allocatable.Pods().Value()), float64(podCount)),
+		},
+	}, nil
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func addResources(total, resources corev1.ResourceList) {
+	for name, quantity := range resources {
+		if current, ok := total[name]; ok {
+			current.Add(quantity)
+			total[name] = current
+			continue
+		}
+		total[name] = quantity.DeepCopy()
+	}
+}
+
+// headroom returns the percentage of the allocatable that is not requested, it is 0 if nothing is allocatable
+func headroom(allocatable, requested float64) int32 {
+	if allocatable <= 0 || requested >= allocatable {
+		return 0
+	}
+	return int32((allocatable - requested) * 100 / allocatable)
+}
@@ -0,0 +1,143 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package addons
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+)
+
+func TestHeadroom(t *testing.T) {
+	tests := []struct {
+		name        string
+		allocatable float64
+		requested   float64
+		want        int32
+	}{
+		{
+			name:        "nothing is requested",
+			allocatable: 4000,
+			want:        100,
+		},
+		{
+			name:        "a part is requested",
+			allocatable: 4000,
+			requested:   1000,
+			want:        75,
+		},
+		{
+			name:        "the percentage is rounded down",
+			allocatable: 3,
+			requested:   1,
+			want:        66,
+		},
+		{
+			name:        "all is requested",
+			allocatable: 4000,
+			requested:   4000,
+		},
+		{
+			name:        "overcommitted",
+			allocatable: 4000,
+			requested:   5000,
+		},
+		{
+			name:      "nothing is allocatable",
+			requested: 1000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headroom(tt.allocatable, tt.requested); got != tt.want {
+				t.Errorf("headroom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newScoreNode(name string, ready, unschedulable bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2"),
+				corev1.ResourceMemory: resource.MustParse("4Gi"),
+				corev1.ResourcePods:   resource.MustParse("10"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func newScorePod(name, nodeName, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+	}
+}
+
+func TestResourceUsageScores(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    []int32
+	}{
+		{
+			name: "no nodes",
+			want: []int32{0, 0, 0},
+		},
+		{
+			name: "the requests of the pods on the schedulable nodes",
+			objects: []runtime.Object{
+				newScoreNode("node1", true, false),
+				newScoreNode("node2", true, false),
+				newScoreNode("node3", false, false),
+				newScoreNode("node4", true, true),
+				newScorePod("pod1", "node1", "1", "2Gi"),
+				newScorePod("pod2", "node2", "1", "2Gi"),
+				newScorePod("pod3", "node3", "2", "4Gi"),
+				newScorePod("pod4", "node4", "2", "4Gi"),
+			},
+			want: []int32{50, 50, 90},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := resourceUsageScores(context.TODO(), kubefake.NewSimpleClientset(tt.objects...))
+			if err != nil {
+				t.Fatalf("resourceUsageScores() error = %v", err)
+			}
+
+			want := []clusterv1alpha1.AddOnPlacementScoreItem{
+				{Name: ScoreCPUAvailable, Value: tt.want[0]},
+				{Name: ScoreMemoryAvailable, Value: tt.want[1]},
+				{Name: ScorePodAvailable, Value: tt.want[2]},
+			}
+			if !reflect.DeepEqual(items, want) {
+				t.Errorf("resourceUsageScores() = %v, want %v", items, want)
+			}
+		})
+	}
+}
@@ -3,48 +3,52 @@ package agent
 
 import (
 	"context"
-	"embed"
+	"crypto/sha256"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/openshift/library-go/pkg/assets"
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	"github.com/openshift/library-go/pkg/operator/events"
-	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
 	"k8s.io/client-go/informers"
 	kubescheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 	clusterv1informers "open-cluster-management.io/api/client/cluster/informers/externalversions"
-	authv1beta1 "open-cluster-management.io/managed-serviceaccount/apis/authentication/v1beta1"
 	commonoptions "open-cluster-management.io/ocm/pkg/common/options"
 	"open-cluster-management.io/ocm/pkg/features"
+	"open-cluster-management.io/ocm/pkg/registration/register"
 	registrationspoke "open-cluster-management.io/ocm/pkg/registration/spoke"
-	singletonspoke "open-cluster-management.io/ocm/pkg/singleton/spoke"
 	workspoke "open-cluster-management.io/ocm/pkg/work/spoke"
+	grpcoptions "open-cluster-management.io/sdk-go/pkg/cloudevents/generic/options/grpc"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/yaml"
 
 	"open-cluster-management.io/multicluster-controlplane/pkg/agent/addons"
 	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 )
 
-//go:embed crds
-var crds embed.FS
-
-var crdStaticFiles = []string{
-	"crds/0000_01_work.open-cluster-management.io_appliedmanifestworks.crd.yaml",
-	"crds/0000_02_clusters.open-cluster-management.io_clusterclaims.crd.yaml",
-}
+// addOnRestartInterval is the interval to check the rotation of the hub kubeconfig and to restart the addons
+const addOnRestartInterval = 10 * time.Second
 
 var (
 	genericScheme = runtime.NewScheme()
@@ -55,7 +59,6 @@ var (
 func init() {
 	utilruntime.Must(crdv1.AddToScheme(genericScheme))
 	utilruntime.Must(kubescheme.AddToScheme(genericScheme))
-	utilruntime.Must(authv1beta1.AddToScheme(genericScheme))
 }
 
 type AgentOptions struct {
@@ -66,19 +69,77 @@ type AgentOptions struct {
 	KubeConfig  string
 	WorkAgentID string
 
+	// GRPCServerAddress is the address of the controlplane gRPC server, if it is set, the work agent uses
+	// the grpc workload source driver to receive the ManifestWorks.
+	GRPCServerAddress string
+	// GRPCServerCAFile is the CA of the controlplane gRPC server, the CA of the bootstrap kubeconfig is
+	// used if it is not set.
+	GRPCServerCAFile string
+
+	// HubAPIServer, BootstrapToken and DiscoveryTokenCACertHashes are used to join the controlplane with a
+	// bootstrap token, the bootstrap kubeconfig is built from them.
+	HubAPIServer               string
+	BootstrapToken             string
+	DiscoveryTokenCACertHashes []string
+
+	// ProxyURL, NoProxy and CABundleFiles are used by the agents to connect to the controlplane, they are written
+	// to the bootstrap kubeconfig and persisted into the hub kubeconfig by the registration agent.
+	ProxyURL      string
+	NoProxy       []string
+	CABundleFiles []string
+
+	// CRDInstallMode is the mode to install the CRDs of the agent on the managed cluster, it is one of apply,
+	// verify and skip.
+	CRDInstallMode string
+
+	// ClusterClaimsConfigFile and ClusterClaimsRefreshInterval are the options of the cluster claim collectors,
+	// the collectors are started if the ClusterClaimCollector feature is enabled.
+	ClusterClaimsConfigFile      string
+	ClusterClaimsRefreshInterval time.Duration
+
+	// WorkPersistence persists the ManifestWorks of the cluster to the disk or the secrets, the persisted
+	// ManifestWorks are enforced while the hub is unreachable, it is one of none, disk and secret.
+	WorkPersistence             string
+	WorkPersistenceDir          string
+	WorkPersistenceSyncInterval time.Duration
+
+	// AddOns are the addons that run in the agent process, '*' enables all of the addons, 'foo' enables the
+	// addon foo and '-foo' disables the addon foo, the addons with the feature gates also require the features.
+	AddOns []string
+
+	// SecureServing, Authentication and Authorization are the options of the agent health and metrics server,
+	// the server is disabled if the secure port is 0.
+	SecureServing  *genericoptions.SecureServingOptions
+	Authentication *genericoptions.DelegatingAuthenticationOptions
+	Authorization  *genericoptions.DelegatingAuthorizationOptions
+
 	SpokeKubeInformerFactory    informers.SharedInformerFactory
 	SpokeClusterInformerFactory clusterv1informers.SharedInformerFactory
 	SpokeRestMapper             meta.RESTMapper
 
 	eventRecorder events.Recorder
+	health        *agentHealth
+	// configSubDir is the sub directory of the generated config files, it separates the files of the agents
+	// that are run in the hosted mode.
+	configSubDir string
 }
 
 func NewAgentOptions() *AgentOptions {
 	return &AgentOptions{
-		RegistrationAgentOpts: registrationspoke.NewSpokeAgentOptions(),
-		WorkAgentOpts:         workspoke.NewWorkloadAgentOptions(),
-		CommonOpts:            commonoptions.NewAgentOptions(),
-		eventRecorder:         util.NewLoggingRecorder("managed-cluster-agents"),
+		RegistrationAgentOpts:        registrationspoke.NewSpokeAgentOptions(),
+		WorkAgentOpts:                workspoke.NewWorkloadAgentOptions(),
+		CommonOpts:                   commonoptions.NewAgentOptions(),
+		CRDInstallMode:               CRDInstallModeApply,
+		ClusterClaimsRefreshInterval: defaultClusterClaimsRefreshInterval,
+		AddOns:                       []string{"*"},
+		WorkPersistence:              WorkPersistenceNone,
+		WorkPersistenceDir:           defaultWorkPersistenceDir,
+		WorkPersistenceSyncInterval:  defaultWorkPersistenceSyncInterval,
+		SecureServing:                newSecureServingOptions(),
+		Authentication:               newDelegatingAuthenticationOptions(),
+		Authorization:                newDelegatingAuthorizationOptions(),
+		health:                       &agentHealth{},
+		eventRecorder:                util.NewLoggingRecorder("managed-cluster-agents"),
 	}
 }
 
@@ -86,6 +147,51 @@ func (o *AgentOptions) AddFlags(fs *pflag.FlagSet) {
 	o.CommonOpts.AddFlags(fs)
 	o.WorkAgentOpts.AddFlags(fs)
 	o.RegistrationAgentOpts.AddFlags(fs)
+	o.SecureServing.AddFlags(fs)
+	o.Authentication.AddFlags(fs)
+	o.Authorization.AddFlags(fs)
+	fs.StringVar(&o.KubeConfig, "kubeconfig", o.KubeConfig,
+		"The kubeconfig file of the cluster where the agent runs, it is used if the agent does not run in a pod")
+	fs.StringVar(&o.GRPCServerAddress, "grpc-server-address", o.GRPCServerAddress,
+		"The address (host:port) of the controlplane gRPC server, if it is set, the ManifestWorks are received from the gRPC server")
+	fs.StringVar(&o.GRPCServerCAFile, "grpc-server-ca-file", o.GRPCServerCAFile,
+		"The CA file of the controlplane gRPC server, the CA of the bootstrap kubeconfig is used by default")
+	fs.StringVar(&o.HubAPIServer, "hub-apiserver", o.HubAPIServer,
+		"The address of the controlplane apiserver, it is required to join with a bootstrap token")
+	fs.StringVar(&o.BootstrapToken, "bootstrap-token", o.BootstrapToken,
+		"The bootstrap token to join the controlplane, the bootstrap kubeconfig is built from the token")
+	fs.StringSliceVar(&o.DiscoveryTokenCACertHashes, "discovery-token-ca-cert-hash", o.DiscoveryTokenCACertHashes,
+		"The hashes (format: \"sha256:<hex>\") of the controlplane CA, the CA is discovered with the bootstrap token "+
+			"and trusted only if it matches one of the hashes")
+	fs.StringVar(&o.ProxyURL, "proxy-url", o.ProxyURL,
+		"The URL of the proxy (http, https or socks5) to connect to the controlplane, it is written to the hub kubeconfig")
+	fs.StringSliceVar(&o.NoProxy, "no-proxy", o.NoProxy,
+		"A list of the hosts, domains, IPs or CIDRs of the controlplane that are not connected with the proxy")
+	fs.StringSliceVar(&o.CABundleFiles, "ca-bundle-file", o.CABundleFiles,
+		"The additional CA bundle files to trust the controlplane, e.g. the CA of a TLS-inspecting proxy, they "+
+			"are appended to the CA of the hub kubeconfig")
+	fs.StringVar(&o.CRDInstallMode, "crd-install-mode", o.CRDInstallMode,
+		"The mode to install the CRDs of the agent on the managed cluster, 'apply' creates or updates the CRDs, "+
+			"'verify' requires the CRDs are installed with the expected versions and 'skip' does not check the CRDs")
+	fs.StringVar(&o.ClusterClaimsConfigFile, "cluster-claims-config", o.ClusterClaimsConfigFile,
+		"The config file of the cluster claim collectors, it disables the built-in collectors or changes their "+
+			"refresh intervals, and defines the collectors that collect the claims from a configmap or a node label")
+	fs.DurationVar(&o.ClusterClaimsRefreshInterval, "cluster-claims-refresh-interval", o.ClusterClaimsRefreshInterval,
+		"The default refresh interval of the cluster claim collectors")
+	fs.StringVar(&o.WorkPersistence, "work-persistence", o.WorkPersistence,
+		"Persist the ManifestWorks of the cluster to keep enforcing them while the controlplane is unreachable, "+
+			"'none' does not persist the ManifestWorks, 'disk' persists them to the --work-persistence-dir and "+
+			"'secret' persists them to the secrets of the component namespace")
+	fs.StringVar(&o.WorkPersistenceDir, "work-persistence-dir", o.WorkPersistenceDir,
+		"The dir to persist the ManifestWorks if the work persistence is 'disk'")
+	fs.DurationVar(&o.WorkPersistenceSyncInterval, "work-persistence-sync-interval", o.WorkPersistenceSyncInterval,
+		"The interval to persist the ManifestWorks, and to enforce the persisted ManifestWorks while the controlplane "+
+			"is unreachable")
+	fs.StringSliceVar(&o.AddOns, "addons", o.AddOns, fmt.Sprintf(
+		"A list of the addons to run in the agent process, '*' enables all of the addons, 'foo' enables the addon "+
+			"'foo', '-foo' disables the addon 'foo', the addons that have feature gates also require the features "+
+			"are enabled. All addons: %s", strings.Join(addons.RegisteredAddOns(), ", ")))
+	addons.AddFlags(fs)
 }
 
 func (o *AgentOptions) WithClusterName(clusterName string) *AgentOptions {
@@ -124,19 +230,39 @@ func (o *AgentOptions) WithWorkloadSourceDriverConfig(hubKubeConfigFile string)
 	return o
 }
 
+func (o *AgentOptions) WithGRPCServerAddress(address string) *AgentOptions {
+	o.GRPCServerAddress = address
+	return o
+}
+
 func (o *AgentOptions) RunAgent(ctx context.Context) error {
-	cancleCtx, cancel := context.WithCancel(ctx)
-	config := singletonspoke.NewAgentConfig(o.CommonOpts, o.RegistrationAgentOpts, o.WorkAgentOpts, cancel)
-	inClusterKubeConfig, err := rest.InClusterConfig()
-	if err != nil {
-		klog.Warningf("failed to get kubeconfig from cluster inside, will use '--kubeconfig' to build client")
+	if err := validateCRDInstallMode(o.CRDInstallMode); err != nil {
+		return err
+	}
+	if err := validateWorkPersistence(o.WorkPersistence, o.WorkPersistenceSyncInterval); err != nil {
+		return err
+	}
+	if err := o.validateHubTransport(); err != nil {
+		return err
+	}
 
-		inClusterKubeConfig, err = clientcmd.BuildConfigFromFlags("", o.KubeConfig)
-		if err != nil {
-			return fmt.Errorf("unable to load kubeconfig from file %q: %v", o.KubeConfig, err)
+	if len(o.GRPCServerAddress) != 0 {
+		if err := o.prepareGRPCWorkloadSourceConfig(); err != nil {
+			return err
 		}
 	}
 
+	cancleCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	registrationConfig := registrationspoke.NewSpokeAgentConfig(o.CommonOpts, o.RegistrationAgentOpts, cancel)
+	workConfig := workspoke.NewWorkAgentConfig(o.CommonOpts, o.WorkAgentOpts)
+	o.health.setCheckers(registrationConfig.HealthCheckers())
+
+	inClusterKubeConfig, err := o.inClusterKubeConfig()
+	if err != nil {
+		return err
+	}
+
 	// building kubeconfig for the spoke/managed cluster
 	spokeKubeConfig, err := o.CommonOpts.SpokeKubeConfig(inClusterKubeConfig)
 	if err != nil {
@@ -146,101 +272,348 @@ func (o *AgentOptions) RunAgent(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	// TODO(qiujian16) crds should not be created by agent itself.
 	if err := o.ensureCRDs(ctx, apiExtensionsClient); err != nil {
 		return err
 	}
 
+	if features.SpokeMutableFeatureGate.Enabled(mcfeature.ClusterClaimCollector) {
+		claimCollectors, err := o.newClusterClaimCollectors(spokeKubeConfig)
+		if err != nil {
+			return err
+		}
+		go claimCollectors.Run(cancleCtx)
+	}
+
 	controllerContext := &controllercmd.ControllerContext{
 		KubeConfig:        inClusterKubeConfig,
 		EventRecorder:     util.NewLoggingRecorder("managed-cluster-agents"),
 		OperatorNamespace: "open-cluster-management-agent",
 	}
 
-	go utilruntime.Must(config.RunSpokeAgent(cancleCtx, controllerContext))
+	if o.WorkPersistence != WorkPersistenceNone {
+		go o.runWorkCache(cancleCtx, inClusterKubeConfig, spokeKubeConfig)
+	}
 
-	<-cancleCtx.Done()
-	return nil
+	return runSpokeAgents(cancleCtx, registrationConfig, workConfig, controllerContext)
 }
 
-func (o *AgentOptions) ensureCRDs(ctx context.Context, client apiextensionsclient.Interface) error {
-	for _, crdFileName := range crdStaticFiles {
-		template, err := crds.ReadFile(crdFileName)
+// runSpokeAgents runs the registration agent and then the work agent once the hub kubeconfig is valid, it follows
+// the ocm singleton agent, but the failures of the agents are returned instead of exiting the process, so the
+// caller can restart them, e.g. the hosted agents of a spoke cluster do not affect the other spoke clusters.
+func runSpokeAgents(ctx context.Context, registrationConfig *registrationspoke.SpokeAgentConfig,
+	workConfig *workspoke.WorkAgentConfig, controllerContext *controllercmd.ControllerContext) error {
+	errCh := make(chan error, 2)
+
+	go func() {
+		if err := registrationConfig.RunSpokeAgent(ctx, controllerContext); err != nil {
+			errCh <- fmt.Errorf("failed to run the registration agent, %v", err)
+		}
+	}()
+
+	go func() {
+		klog.Info("waiting for the hub kubeconfig and the managed cluster to be ready")
+		if err := wait.PollUntilContextCancel(ctx, time.Second, true, registrationConfig.IsHubKubeConfigValid); err != nil {
+			if ctx.Err() == nil {
+				errCh <- fmt.Errorf("failed to wait for the hub kubeconfig, %v", err)
+			}
+			return
+		}
+
+		if err := workConfig.RunWorkloadAgent(ctx, controllerContext); err != nil {
+			errCh <- fmt.Errorf("failed to run the work agent, %v", err)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func (o *AgentOptions) inClusterKubeConfig() (*rest.Config, error) {
+	inClusterKubeConfig, err := rest.InClusterConfig()
+	if err != nil {
+		klog.Warningf("failed to get kubeconfig from cluster inside, will use '--kubeconfig' to build client")
+
+		inClusterKubeConfig, err = clientcmd.BuildConfigFromFlags("", o.KubeConfig)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("unable to load kubeconfig from file %q: %v", o.KubeConfig, err)
 		}
+	}
+	return inClusterKubeConfig, nil
+}
 
-		objData := assets.MustCreateAssetFromTemplate(crdFileName, template, nil).Data
-		obj, _, err := genericCodec.Decode(objData, nil, nil)
+func (o *AgentOptions) spokeKubeConfig() (*rest.Config, error) {
+	inClusterKubeConfig, err := o.inClusterKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	return o.CommonOpts.SpokeKubeConfig(inClusterKubeConfig)
+}
+
+// prepareGRPCWorkloadSourceConfig writes the grpc workload source config for the work agent, the agent connects
+// to the gRPC server with the hub client certificates that are issued by the registration agent.
+func (o *AgentOptions) prepareGRPCWorkloadSourceConfig() error {
+	configDir, err := agentConfigDir(o.configSubDir)
+	if err != nil {
+		return err
+	}
+
+	caFile := o.GRPCServerCAFile
+	if len(caFile) == 0 {
+		bootstrapConfig, err := clientcmd.BuildConfigFromFlags("", o.RegistrationAgentOpts.BootstrapKubeconfig)
 		if err != nil {
-			return err
+			return fmt.Errorf("unable to load bootstrap kubeconfig from file %q: %v",
+				o.RegistrationAgentOpts.BootstrapKubeconfig, err)
 		}
 
-		switch required := obj.(type) {
-		case *crdv1.CustomResourceDefinition:
-			if _, _, err := resourceapply.ApplyCustomResourceDefinitionV1(
-				ctx,
-				client.ApiextensionsV1(),
-				o.eventRecorder,
-				required,
-			); err != nil {
+		caData := bootstrapConfig.CAData
+		if len(caData) == 0 && len(bootstrapConfig.CAFile) != 0 {
+			caData, err = os.ReadFile(bootstrapConfig.CAFile)
+			if err != nil {
 				return err
 			}
 		}
+		if len(caData) == 0 {
+			return fmt.Errorf("the CA of the gRPC server is not found in the bootstrap kubeconfig")
+		}
+
+		caFile = filepath.Join(configDir, "grpc-ca.crt")
+		if err := os.WriteFile(caFile, caData, 0600); err != nil {
+			return fmt.Errorf("failed to write file %q, %v", caFile, err)
+		}
+	}
+
+	// the gRPC client has no proxy config (the gRPC server that requires the proxy url is rejected by the
+	// validation), it connects to the gRPC server with the proxy of the HTTPS_PROXY and NO_PROXY environment
+	// variables, only the additional CA bundles are appended to the CA of the gRPC server
+	if len(o.CABundleFiles) != 0 {
+		caData, err := o.hubCABundle(nil, caFile)
+		if err != nil {
+			return err
+		}
+
+		caFile = filepath.Join(configDir, "grpc-ca-bundle.crt")
+		if err := os.WriteFile(caFile, caData, 0600); err != nil {
+			return fmt.Errorf("failed to write file %q, %v", caFile, err)
+		}
+	}
+
+	configData, err := yaml.Marshal(&grpcoptions.GRPCConfig{
+		URL:            o.GRPCServerAddress,
+		CAFile:         caFile,
+		ClientCertFile: filepath.Join(o.CommonOpts.HubKubeconfigDir, "tls.crt"),
+		ClientKeyFile:  filepath.Join(o.CommonOpts.HubKubeconfigDir, "tls.key"),
+	})
+	if err != nil {
+		return err
 	}
 
+	configFile := filepath.Join(configDir, "grpc-config.yaml")
+	if err := os.WriteFile(configFile, configData, 0600); err != nil {
+		return fmt.Errorf("failed to write file %q, %v", configFile, err)
+	}
+
+	o.WorkAgentOpts.WorkloadSourceDriver = "grpc"
+	o.WorkAgentOpts.WorkloadSourceConfig = configFile
 	return nil
 }
 
-// RunAddOns runs the addons in the agent
+// agentConfigDir returns the directory of the config files that are generated by the agent
+func agentConfigDir(subDirs ...string) (string, error) {
+	configDir := filepath.Join(append([]string{os.TempDir(), "multicluster-controlplane-agent"}, subDirs...)...)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create dir %q, %v", configDir, err)
+	}
+	return configDir, nil
+}
+
+// RunAddOns runs the addons in the agent, the addons connect to the hub with the hub kubeconfig that is issued by
+// the registration agent, so they are started after the cluster is registered, and they are restarted with the
+// new clients once the client certificate is rotated.
 func (a *AgentOptions) RunAddOns(ctx context.Context) error {
+	enabledAddOns, err := addons.EnabledAddOns(a.AddOns)
+	if err != nil {
+		return err
+	}
+	if len(enabledAddOns) == 0 {
+		return nil
+	}
 
-	startCtrlMgr := false
+	go a.runAddOnsUntil(ctx, enabledAddOns)
 
-	clusterName := a.CommonOpts.SpokeClusterName
+	return nil
+}
 
-	// TODO should use 	hubKubeConfig, err := clientcmd.BuildConfigFromFlags("", a.WorkAgentOpts.WorkloadSourceDriver.Config)
-	hubKubeConfig, err := clientcmd.BuildConfigFromFlags("", a.RegistrationAgentOpts.BootstrapKubeconfig)
+// runAddOnsUntil waits for the hub kubeconfig and runs the addons until the context is done
+func (a *AgentOptions) runAddOnsUntil(ctx context.Context, enabledAddOns []addons.AddOn) {
+	klog.Info("waiting for the hub kubeconfig to start the addons")
+	if err := wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		valid, err := a.isHubKubeConfigValid()
+		if err != nil {
+			klog.V(4).Infof("the hub kubeconfig is not valid, %v", err)
+		}
+		return valid, nil
+	}); err != nil {
+		return
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		a.runAddOns(ctx, enabledAddOns)
+	}, addOnRestartInterval)
+}
+
+// runAddOns runs the addons with the current hub kubeconfig until the hub kubeconfig is changed
+func (a *AgentOptions) runAddOns(ctx context.Context, enabledAddOns []addons.AddOn) {
+	hubKubeConfigHash, err := a.hubKubeConfigHash()
 	if err != nil {
-		return fmt.Errorf("unable to load kubeconfig from file %q: %v", a.KubeConfig, err)
+		klog.Errorf("failed to read the hub kubeconfig, %v", err)
+		return
 	}
 
-	hubManager, err := a.newHubManager(hubKubeConfig)
+	hubKubeConfig, err := a.hubKubeConfig()
 	if err != nil {
-		return err
+		klog.Errorf("failed to build the hub kubeconfig, %v", err)
+		return
+	}
+
+	clusterName, err := a.registeredClusterName()
+	if err != nil {
+		klog.Errorf("failed to read the cluster name, %v", err)
+		return
 	}
 
-	if features.SpokeMutableFeatureGate.Enabled(mcfeature.ManagedServiceAccount) {
-		klog.Info("starting managed serviceaccount addon agent")
-		if err := addons.StartManagedServiceAccountAgent(ctx, hubManager, clusterName); err != nil {
-			klog.Fatalf("failed to setup managed serviceaccount addon, %v", err)
+	spokeKubeConfig, err := a.spokeKubeConfig()
+	if err != nil {
+		klog.Errorf("unable to load the spoke kubeconfig, %v", err)
+		return
+	}
+
+	hubScheme, spokeScheme := runtime.NewScheme(), runtime.NewScheme()
+	utilruntime.Must(kubescheme.AddToScheme(hubScheme))
+	utilruntime.Must(kubescheme.AddToScheme(spokeScheme))
+	for _, addOn := range enabledAddOns {
+		if err := addOn.AddToHubScheme(hubScheme); err != nil {
+			klog.Errorf("failed to add the hub types of the addon %q, %v", addOn.Name(), err)
+			return
 		}
+		if err := addOn.AddToSpokeScheme(spokeScheme); err != nil {
+			klog.Errorf("failed to add the spoke types of the addon %q, %v", addOn.Name(), err)
+			return
+		}
+	}
 
-		startCtrlMgr = true
+	hubManager, err := a.newHubManager(hubKubeConfig, hubScheme, clusterName)
+	if err != nil {
+		klog.Errorf("failed to create the embedded hub controller-runtime manager, %v", err)
+		return
 	}
 
-	if !startCtrlMgr {
-		return nil
+	addOnCtx := &addons.AddOnContext{
+		ClusterName:     clusterName,
+		SpokeKubeConfig: spokeKubeConfig,
+		SpokeScheme:     spokeScheme,
+	}
+	for _, addOn := range enabledAddOns {
+		klog.Infof("starting the addon %q", addOn.Name())
+		if err := addOn.SetupWithManager(ctx, hubManager, addOnCtx); err != nil {
+			klog.Errorf("failed to setup the addon %q, %v", addOn.Name(), err)
+			return
+		}
 	}
 
+	managerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		klog.Info("starting the embedded hub controller-runtime manager in controlplane agent")
-		if err := hubManager.Start(ctx); err != nil {
-			klog.Fatalf("failed to start embedded hub controller-runtime manager, %v", err)
+		if err := hubManager.Start(managerCtx); err != nil {
+			klog.Errorf("failed to start embedded hub controller-runtime manager, %v", err)
 		}
-		<-ctx.Done()
 	}()
 
-	return nil
+	// the client certificate is rotated by the registration agent, the manager is recreated with the new
+	// hub kubeconfig once it is valid
+	if err := wait.PollUntilContextCancel(managerCtx, addOnRestartInterval, false, func(ctx context.Context) (bool, error) {
+		select {
+		case <-stopped:
+			return true, nil
+		default:
+		}
+
+		return a.isHubKubeConfigChanged(hubKubeConfigHash), nil
+	}); err == nil {
+		klog.Info("the hub kubeconfig is changed, restarting the addons")
+	}
+
+	cancel()
+	<-stopped
+}
+
+// isHubKubeConfigChanged returns true if the hub kubeconfig is changed from the given hash and the changed one is
+// valid, so the addons are not restarted with a certificate and a key that are not written completely
+func (a *AgentOptions) isHubKubeConfigChanged(hubKubeConfigHash string) bool {
+	current, err := a.hubKubeConfigHash()
+	if err != nil || current == hubKubeConfigHash {
+		return false
+	}
+	valid, err := a.isHubKubeConfigValid()
+	if err != nil {
+		klog.V(4).Infof("the hub kubeconfig is not valid, %v", err)
+	}
+	return valid
+}
+
+// registeredClusterName returns the cluster name of the agent, it is read from the hub kubeconfig dir if the
+// cluster name is not specified
+func (a *AgentOptions) registeredClusterName() (string, error) {
+	if len(a.CommonOpts.SpokeClusterName) != 0 {
+		return a.CommonOpts.SpokeClusterName, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(a.CommonOpts.HubKubeconfigDir, register.ClusterNameFile))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
 }
 
-func (a *AgentOptions) newHubManager(hubKubeConfig *rest.Config) (manager.Manager, error) {
+func (a *AgentOptions) hubKubeConfigFile() string {
+	return filepath.Join(a.CommonOpts.HubKubeconfigDir, register.KubeconfigFile)
+}
+
+// hubKubeConfigHash returns the hash of the hub kubeconfig and its client certificate and key
+func (a *AgentOptions) hubKubeConfigHash() (string, error) {
+	h := sha256.New()
+	for _, file := range []string{register.KubeconfigFile, corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		data, err := os.ReadFile(filepath.Join(a.CommonOpts.HubKubeconfigDir, file))
+		if err != nil {
+			return "", err
+		}
+		h.Write(data)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// newHubManager returns the manager of the addons, the cache is limited to the cluster namespace, since the
+// cluster only has the permissions in its namespace on the hub
+func (a *AgentOptions) newHubManager(hubKubeConfig *rest.Config, scheme *runtime.Scheme,
+	clusterName string) (manager.Manager, error) {
 	mgr, err := ctrl.NewManager(hubKubeConfig, ctrl.Options{
-		Scheme: genericScheme,
+		Scheme: scheme,
+		Cache: cache.Options{
+			DefaultNamespaces: map[string]cache.Config{clusterName: {}},
+		},
 		Metrics: metricsserver.Options{
 			BindAddress: "0", //TODO think about the mertics later
 		},
 		Logger: ctrl.Log.WithName("ctrl-runtime-manager"),
+		// the manager is recreated once the hub kubeconfig is rotated, the controllers are registered again
+		Controller: ctrlconfig.Controller{SkipNameValidation: ptr.To(true)},
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,92 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"open-cluster-management.io/ocm/pkg/registration/register"
+)
+
+func TestIsHubKubeConfigChanged(t *testing.T) {
+	now := time.Now()
+	cert, key := newCertKeyPEM(t, now.Add(-time.Hour), now.Add(time.Hour))
+	rotatedCert, rotatedKey := newCertKeyPEM(t, now.Add(-time.Minute), now.Add(2*time.Hour))
+	notYetValidCert, notYetValidKey := newCertKeyPEM(t, now.Add(time.Hour), now.Add(2*time.Hour))
+
+	tests := []struct {
+		name string
+		// rotate changes the hub kubeconfig dir after the addons are started
+		rotate func(t *testing.T, dir string)
+		want   bool
+	}{
+		{
+			name:   "unchanged",
+			rotate: func(t *testing.T, dir string) {},
+		},
+		{
+			name: "the certificate is rotated",
+			rotate: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, corev1.TLSCertKey), rotatedCert)
+				writeFile(t, filepath.Join(dir, corev1.TLSPrivateKeyKey), rotatedKey)
+			},
+			want: true,
+		},
+		{
+			name: "the certificate is written but the key is not",
+			rotate: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, corev1.TLSCertKey), rotatedCert)
+			},
+		},
+		{
+			name: "the rotated certificate is not yet valid",
+			rotate: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, corev1.TLSCertKey), notYetValidCert)
+				writeFile(t, filepath.Join(dir, corev1.TLSPrivateKeyKey), notYetValidKey)
+			},
+		},
+		{
+			name: "the key is removed",
+			rotate: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, corev1.TLSPrivateKeyKey)); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "the hub kubeconfig is issued by another hub",
+			rotate: func(t *testing.T, dir string) {
+				writeKubeConfig(t, filepath.Join(dir, register.KubeconfigFile), "https://another-hub:6443")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			hubKubeconfigDir := filepath.Join(dir, "hub-kubeconfig")
+			if err := os.MkdirAll(hubKubeconfigDir, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			bootstrapKubeconfig := filepath.Join(dir, "bootstrap-kubeconfig")
+			writeKubeConfig(t, bootstrapKubeconfig, "https://hub:6443")
+			writeKubeConfig(t, filepath.Join(hubKubeconfigDir, register.KubeconfigFile), "https://hub:6443")
+			writeFile(t, filepath.Join(hubKubeconfigDir, corev1.TLSCertKey), cert)
+			writeFile(t, filepath.Join(hubKubeconfigDir, corev1.TLSPrivateKeyKey), key)
+
+			o := NewAgentOptions().WithBootstrapKubeconfig(bootstrapKubeconfig).WithHubKubeconfigDir(hubKubeconfigDir)
+			hash, err := o.hubKubeConfigHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.rotate(t, hubKubeconfigDir)
+
+			if changed := o.isHubKubeConfigChanged(hash); changed != tt.want {
+				t.Errorf("isHubKubeConfigChanged() = %v, want %v", changed, tt.want)
+			}
+		})
+	}
+}
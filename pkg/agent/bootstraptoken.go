@@ -0,0 +1,128 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+	tokenapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/pubkeypin"
+)
+
+// PrepareBootstrapKubeconfig builds the bootstrap kubeconfig from the bootstrap token if the token is provided,
+// and then writes the proxy url and the additional CA bundles to the bootstrap kubeconfig if they are provided.
+func (o *AgentOptions) PrepareBootstrapKubeconfig(ctx context.Context) error {
+	if len(o.BootstrapToken) != 0 {
+		if err := o.prepareBootstrapTokenKubeconfig(ctx); err != nil {
+			return err
+		}
+	}
+
+	return o.prepareBootstrapTransport()
+}
+
+// prepareBootstrapTokenKubeconfig builds the bootstrap kubeconfig from the bootstrap token. The CA of the
+// controlplane is discovered from the cluster-info configmap, and it is trusted only if it matches one of the
+// given CA cert hashes.
+func (o *AgentOptions) prepareBootstrapTokenKubeconfig(ctx context.Context) error {
+
+	if !bootstraputil.IsValidBootstrapToken(o.BootstrapToken) {
+		return fmt.Errorf("the bootstrap token is invalid")
+	}
+
+	if len(o.HubAPIServer) == 0 {
+		return fmt.Errorf("the hub apiserver is required to join with a bootstrap token")
+	}
+
+	if len(o.DiscoveryTokenCACertHashes) == 0 {
+		return fmt.Errorf("the discovery token CA cert hash is required to join with a bootstrap token")
+	}
+
+	pubKeyPins := pubkeypin.NewSet()
+	if err := pubKeyPins.Allow(o.DiscoveryTokenCACertHashes...); err != nil {
+		return err
+	}
+
+	// the cluster-info is public, it is retrieved without verifying the server, then the CA in the
+	// cluster-info is validated with the CA cert hashes.
+	insecureConfig, err := o.withHubTransport(&rest.Config{
+		Host:            o.HubAPIServer,
+		TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+	})
+	if err != nil {
+		return err
+	}
+	// the server is not verified, the additional CA bundles are not used
+	insecureConfig.TLSClientConfig.CAData = nil
+	insecureClient, err := kubernetes.NewForConfig(insecureConfig)
+	if err != nil {
+		return err
+	}
+
+	clusterInfo, err := insecureClient.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(
+		ctx, tokenapi.ConfigMapClusterInfo, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster-info from %s, %v", o.HubAPIServer, err)
+	}
+
+	clusterInfoConfig, err := clientcmd.Load([]byte(clusterInfo.Data[tokenapi.KubeConfigKey]))
+	if err != nil {
+		return fmt.Errorf("failed to load the kubeconfig of the cluster-info, %v", err)
+	}
+
+	var caData []byte
+	for _, cluster := range clusterInfoConfig.Clusters {
+		caData = cluster.CertificateAuthorityData
+		break
+	}
+
+	certs, err := certutil.ParseCertsPEM(caData)
+	if err != nil {
+		return fmt.Errorf("failed to parse the CA of the cluster-info, %v", err)
+	}
+	if err := pubKeyPins.CheckAny(certs); err != nil {
+		return fmt.Errorf("the CA of the cluster-info cannot be trusted, %v", err)
+	}
+
+	bootstrapConfig := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"hub": {
+				Server:                   o.HubAPIServer,
+				CertificateAuthorityData: caData,
+			},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"bootstrap": {
+				Token: o.BootstrapToken,
+			},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"bootstrap": {
+				Cluster:  "hub",
+				AuthInfo: "bootstrap",
+			},
+		},
+		CurrentContext: "bootstrap",
+	}
+
+	configDir, err := agentConfigDir()
+	if err != nil {
+		return err
+	}
+
+	bootstrapKubeconfig := filepath.Join(configDir, "bootstrap.kubeconfig")
+	if err := clientcmd.WriteToFile(bootstrapConfig, bootstrapKubeconfig); err != nil {
+		return fmt.Errorf("failed to write file %q, %v", bootstrapKubeconfig, err)
+	}
+
+	o.RegistrationAgentOpts.BootstrapKubeconfig = bootstrapKubeconfig
+	return nil
+}
@@ -0,0 +1,39 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent/clusterclaims"
+)
+
+const defaultClusterClaimsRefreshInterval = 10 * time.Minute
+
+// newClusterClaimCollectors returns the cluster claim collectors of the managed cluster, the ClusterClaims are
+// synced to the status of the ManagedCluster by the registration agent.
+func (o *AgentOptions) newClusterClaimCollectors(spokeKubeConfig *rest.Config) (*clusterclaims.ClaimCollectors, error) {
+	if o.ClusterClaimsRefreshInterval <= 0 {
+		return nil, fmt.Errorf("the cluster claims refresh interval must be positive")
+	}
+
+	config, err := clusterclaims.LoadConfig(o.ClusterClaimsConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	spokeKubeClient, err := kubernetes.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	spokeClusterClient, err := clusterv1client.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterclaims.NewClaimCollectors(spokeKubeClient, spokeClusterClient, config, o.ClusterClaimsRefreshInterval), nil
+}
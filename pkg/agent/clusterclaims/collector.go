@@ -0,0 +1,196 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterclaims
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+)
+
+// CollectorLabel is the label of the cluster claims that are collected by the agent, its value is the name of
+// the collector
+const CollectorLabel = "multicluster-controlplane.open-cluster-management.io/claim-collector"
+
+// maxClaimValueLength is the max length of the cluster claim value
+const maxClaimValueLength = 1024
+
+// Collector collects the cluster claims from the managed cluster
+type Collector interface {
+	// Name returns the name of the collector
+	Name() string
+	// Collect returns the cluster claims, they are keyed by the claim names
+	Collect(ctx context.Context, kubeClient kubernetes.Interface) (map[string]string, error)
+}
+
+type scheduledCollector struct {
+	Collector
+	interval time.Duration
+}
+
+// ClaimCollectors refreshes the cluster claims on the managed cluster with the collectors, the claims are synced
+// to the status of the ManagedCluster on the controlplane by the registration agent.
+type ClaimCollectors struct {
+	kubeClient    kubernetes.Interface
+	clusterClient clusterv1client.Interface
+	collectors    []scheduledCollector
+}
+
+// NewClaimCollectors returns the enabled collectors of the config, the collectors are refreshed with the default
+// interval if their intervals are not specified
+func NewClaimCollectors(kubeClient kubernetes.Interface, clusterClient clusterv1client.Interface,
+	config *Config, defaultInterval time.Duration) *ClaimCollectors {
+	return &ClaimCollectors{
+		kubeClient:    kubeClient,
+		clusterClient: clusterClient,
+		collectors:    config.newCollectors(defaultInterval),
+	}
+}
+
+// Run refreshes the cluster claims of each collector with its interval until the context is done, the claims of
+// the disabled or removed collectors are removed once it is started.
+func (c *ClaimCollectors) Run(ctx context.Context) {
+	if err := c.removeStaleClaims(ctx); err != nil {
+		klog.Errorf("failed to remove the stale cluster claims, %v", err)
+	}
+
+	for _, collector := range c.collectors {
+		go wait.JitterUntilWithContext(ctx, func(ctx context.Context) {
+			if err := c.refresh(ctx, collector); err != nil {
+				klog.Errorf("failed to refresh the cluster claims of the collector %q, %v", collector.Name(), err)
+			}
+		}, collector.interval, 0.1, true)
+	}
+
+	<-ctx.Done()
+}
+
+// refresh applies the collected claims of the collector, and removes the claims that are not collected anymore
+func (c *ClaimCollectors) refresh(ctx context.Context, collector Collector) error {
+	claims, err := collector.Collect(ctx, c.kubeClient)
+	if err != nil {
+		return err
+	}
+
+	errs := []string{}
+	for name, value := range claims {
+		if err := c.applyClaim(ctx, collector.Name(), name, value); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	existing, err := c.clusterClient.ClusterV1alpha1().ClusterClaims().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", CollectorLabel, collector.Name()),
+	})
+	if err != nil {
+		return err
+	}
+	for _, claim := range existing.Items {
+		if _, ok := claims[claim.Name]; ok {
+			continue
+		}
+		if err := c.deleteClaim(ctx, claim.Name); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (c *ClaimCollectors) applyClaim(ctx context.Context, collectorName, name, value string) error {
+	if err := validateClaimName(name); err != nil {
+		return err
+	}
+	if len(value) > maxClaimValueLength {
+		return fmt.Errorf("the value of the claim %q is longer than %d", name, maxClaimValueLength)
+	}
+
+	required := &clusterv1alpha1.ClusterClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{CollectorLabel: collectorName},
+		},
+		Spec: clusterv1alpha1.ClusterClaimSpec{Value: value},
+	}
+
+	existing, err := c.clusterClient.ClusterV1alpha1().ClusterClaims().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := c.clusterClient.ClusterV1alpha1().ClusterClaims().Create(ctx, required, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	// the claims that are created by the users or the other collectors are not overwritten
+	if owner := existing.Labels[CollectorLabel]; owner != collectorName {
+		klog.Warningf("the cluster claim %q is not collected by the collector %q, it is not updated", name, collectorName)
+		return nil
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, required.Spec) {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Spec = required.Spec
+	_, err = c.clusterClient.ClusterV1alpha1().ClusterClaims().Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+func (c *ClaimCollectors) deleteClaim(ctx context.Context, name string) error {
+	err := c.clusterClient.ClusterV1alpha1().ClusterClaims().Delete(ctx, name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// removeStaleClaims removes the claims of the collectors that are not enabled
+func (c *ClaimCollectors) removeStaleClaims(ctx context.Context) error {
+	enabled := map[string]bool{}
+	for _, collector := range c.collectors {
+		enabled[collector.Name()] = true
+	}
+
+	claims, err := c.clusterClient.ClusterV1alpha1().ClusterClaims().List(ctx, metav1.ListOptions{
+		LabelSelector: CollectorLabel,
+	})
+	if err != nil {
+		return err
+	}
+	for _, claim := range claims.Items {
+		if enabled[claim.Labels[CollectorLabel]] {
+			continue
+		}
+		if err := c.deleteClaim(ctx, claim.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveClaims removes all of the cluster claims that are collected by the agent
+func RemoveClaims(ctx context.Context, clusterClient clusterv1client.Interface) error {
+	return (&ClaimCollectors{clusterClient: clusterClient}).removeStaleClaims(ctx)
+}
+
+func validateClaimName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		return fmt.Errorf("the claim name %q is invalid, %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterclaims
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+)
+
+type fakeCollector struct {
+	name   string
+	claims map[string]string
+}
+
+func (c *fakeCollector) Name() string {
+	return c.name
+}
+
+func (c *fakeCollector) Collect(_ context.Context, _ kubernetes.Interface) (map[string]string, error) {
+	return c.claims, nil
+}
+
+func newClaim(name, collector, value string) *clusterv1alpha1.ClusterClaim {
+	claim := &clusterv1alpha1.ClusterClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       clusterv1alpha1.ClusterClaimSpec{Value: value},
+	}
+	if len(collector) != 0 {
+		claim.Labels = map[string]string{CollectorLabel: collector}
+	}
+	return claim
+}
+
+func listClaims(t *testing.T, c *ClaimCollectors) map[string]string {
+	claims, err := c.clusterClient.ClusterV1alpha1().ClusterClaims().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{}
+	for _, claim := range claims.Items {
+		values[claim.Name] = claim.Spec.Value
+	}
+	return values
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   []runtime.Object
+		claims     map[string]string
+		wantClaims map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "create the claims",
+			claims:     map[string]string{"team.example.com": "team1"},
+			wantClaims: map[string]string{"team.example.com": "team1"},
+		},
+		{
+			name:       "update the claims",
+			existing:   []runtime.Object{newClaim("team.example.com", "team", "team1")},
+			claims:     map[string]string{"team.example.com": "team2"},
+			wantClaims: map[string]string{"team.example.com": "team2"},
+		},
+		{
+			name: "remove the claims that are not collected anymore",
+			existing: []runtime.Object{
+				newClaim("team.example.com", "team", "team1"),
+				newClaim("owner.example.com", "team", "user1"),
+				newClaim("zone.example.com", "zone", "zone-a"),
+			},
+			claims:     map[string]string{"team.example.com": "team1"},
+			wantClaims: map[string]string{"team.example.com": "team1", "zone.example.com": "zone-a"},
+		},
+		{
+			name: "the claims of the others are not overwritten",
+			existing: []runtime.Object{
+				newClaim("team.example.com", "", "user-team"),
+				newClaim("owner.example.com", "owner", "user1"),
+			},
+			claims:     map[string]string{"team.example.com": "team1", "owner.example.com": "user2"},
+			wantClaims: map[string]string{"team.example.com": "user-team", "owner.example.com": "user1"},
+		},
+		{
+			name:       "invalid claim name",
+			claims:     map[string]string{"Team": "team1", "team.example.com": "team1"},
+			wantClaims: map[string]string{"team.example.com": "team1"},
+			wantErr:    true,
+		},
+		{
+			name:       "claim value is too long",
+			claims:     map[string]string{"team.example.com": strings.Repeat("a", maxClaimValueLength+1)},
+			wantClaims: map[string]string{},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ClaimCollectors{
+				kubeClient:    kubefake.NewSimpleClientset(),
+				clusterClient: clusterfake.NewSimpleClientset(tt.existing...),
+			}
+
+			err := c.refresh(context.TODO(), &fakeCollector{name: "team", claims: tt.claims})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("refresh() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if claims := listClaims(t, c); !reflect.DeepEqual(claims, tt.wantClaims) {
+				t.Errorf("refresh() claims = %v, want %v", claims, tt.wantClaims)
+			}
+		})
+	}
+}
+
+func TestRemoveStaleClaims(t *testing.T) {
+	existing := []runtime.Object{
+		newClaim("nodecount.example.com", "nodecount", "3"),
+		newClaim("platform.example.com", "platform", "AWS"),
+		newClaim("team.example.com", "team", "team1"),
+		newClaim("user.example.com", "", "user1"),
+	}
+
+	tests := []struct {
+		name       string
+		collectors []scheduledCollector
+		wantClaims []string
+	}{
+		{
+			name: "remove the claims of the disabled collectors",
+			collectors: []scheduledCollector{
+				{Collector: &fakeCollector{name: "nodecount"}},
+				{Collector: &fakeCollector{name: "team"}},
+			},
+			wantClaims: []string{"nodecount.example.com", "team.example.com", "user.example.com"},
+		},
+		{
+			name:       "remove all of the collected claims",
+			wantClaims: []string{"user.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ClaimCollectors{
+				clusterClient: clusterfake.NewSimpleClientset(existing...),
+				collectors:    tt.collectors,
+			}
+			if err := c.removeStaleClaims(context.TODO()); err != nil {
+				t.Fatalf("removeStaleClaims() error = %v", err)
+			}
+
+			names := []string{}
+			for name := range listClaims(t, c) {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.wantClaims) {
+				t.Errorf("removeStaleClaims() claims = %v, want %v", names, tt.wantClaims)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterclaims
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ClaimKubeVersion is the kubernetes version of the managed cluster
+	ClaimKubeVersion = "kubeversion.open-cluster-management.io"
+	// ClaimPlatform is the cloud platform of the managed cluster, e.g. AWS, GCP and Azure, it is detected from the
+	// provider ID of the nodes
+	ClaimPlatform = "platform.open-cluster-management.io"
+	// ClaimRegion is the region of the managed cluster, it is the region label of the nodes
+	ClaimRegion = "region.open-cluster-management.io"
+	// ClaimNodeCount is the number of the nodes of the managed cluster
+	ClaimNodeCount = "nodecount.multicluster-controlplane.open-cluster-management.io"
+	// ClaimArchitecture is the CPU architectures of the nodes, the different architectures are sorted and joined
+	// with a comma
+	ClaimArchitecture = "architecture.multicluster-controlplane.open-cluster-management.io"
+)
+
+const (
+	PlatformAWS       = "AWS"
+	PlatformGCP       = "GCP"
+	PlatformAzure     = "Azure"
+	PlatformIBM       = "IBM"
+	PlatformOpenStack = "OpenStack"
+	PlatformVSphere   = "VSphere"
+	PlatformAlibaba   = "AlibabaCloud"
+	PlatformKubeVirt  = "KubeVirt"
+	PlatformOther     = "Other"
+)
+
+// platforms maps the schemes of the node provider IDs to the platforms
+var platforms = map[string]string{
+	"aws":       PlatformAWS,
+	"gce":       PlatformGCP,
+	"azure":     PlatformAzure,
+	"ibm":       PlatformIBM,
+	"openstack": PlatformOpenStack,
+	"vsphere":   PlatformVSphere,
+	"alicloud":  PlatformAlibaba,
+	"kubevirt":  PlatformKubeVirt,
+}
+
+var builtinCollectors = map[string]func() Collector{
+	"kubeversion": func() Collector {
+		return &funcCollector{name: "kubeversion", collect: collectKubeVersion}
+	},
+	"nodecount": func() Collector {
+		return &nodeCollector{name: "nodecount", collect: collectNodeCount}
+	},
+	"architecture": func() Collector {
+		return &nodeCollector{name: "architecture", collect: collectArchitecture}
+	},
+	"platform": func() Collector {
+		return &nodeCollector{name: "platform", collect: collectPlatform}
+	},
+	"region": func() Collector {
+		return &nodeCollector{name: "region", collect: collectRegion}
+	},
+}
+
+func builtinCollectorNames() []string {
+	return sets.List(sets.KeySet(builtinCollectors))
+}
+
+type funcCollector struct {
+	name    string
+	collect func(ctx context.Context, kubeClient kubernetes.Interface) (map[string]string, error)
+}
+
+func (c *funcCollector) Name() string {
+	return c.name
+}
+
+func (c *funcCollector) Collect(ctx context.Context, kubeClient kubernetes.Interface) (map[string]string, error) {
+	return c.collect(ctx, kubeClient)
+}
+
+// nodeCollector collects the claims from the nodes of the managed cluster
+type nodeCollector struct {
+	name    string
+	collect func(nodes []corev1.Node) map[string]string
+}
+
+func (c *nodeCollector) Name() string {
+	return c.name
+}
+
+func (c *nodeCollector) Collect(ctx context.Context, kubeClient kubernetes.Interface) (map[string]string, error) {
+	nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return c.collect(nodes.Items), nil
+}
+
+func collectKubeVersion(_ context.Context, kubeClient kubernetes.Interface) (map[string]string, error) {
+	version, err := kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{ClaimKubeVersion: version.GitVersion}, nil
+}
+
+func collectNodeCount(nodes []corev1.Node) map[string]string {
+	return map[string]string{ClaimNodeCount: strconv.Itoa(len(nodes))}
+}
+
+func collectArchitecture(nodes []corev1.Node) map[string]string {
+	return claimOf(ClaimArchitecture, nodes, func(node corev1.Node) string {
+		if arch := node.Labels[corev1.LabelArchStable]; len(arch) != 0 {
+			return arch
+		}
+		return node.Status.NodeInfo.Architecture
+	})
+}
+
+func collectPlatform(nodes []corev1.Node) map[string]string {
+	return claimOf(ClaimPlatform, nodes, func(node corev1.Node) string {
+		scheme, _, found := strings.Cut(node.Spec.ProviderID, "://")
+		if !found {
+			return PlatformOther
+		}
+		if platform, ok := platforms[scheme]; ok {
+			return platform
+		}
+		return PlatformOther
+	})
+}
+
+func collectRegion(nodes []corev1.Node) map[string]string {
+	return claimOf(ClaimRegion, nodes, func(node corev1.Node) string {
+		if region := node.Labels[corev1.LabelTopologyRegion]; len(region) != 0 {
+			return region
+		}
+		return node.Labels[corev1.LabelFailureDomainBetaRegion]
+	})
+}
+
+// configMapCollector collects the claims from a configmap, no claim is collected if the configmap does not exist
+type configMapCollector struct {
+	name   string
+	source ConfigMapSource
+}
+
+func (c *configMapCollector) Name() string {
+	return c.name
+}
+
+func (c *configMapCollector) Collect(ctx context.Context, kubeClient kubernetes.Interface) (map[string]string, error) {
+	configMap, err := kubeClient.CoreV1().ConfigMaps(c.source.Namespace).Get(ctx, c.source.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]string{}
+	for name, value := range configMap.Data {
+		claims[name] = strings.TrimSpace(value)
+	}
+	return claims, nil
+}
+
+// nodeLabelCollector collects a claim from a label of the nodes
+type nodeLabelCollector struct {
+	name   string
+	source NodeLabelSource
+}
+
+func (c *nodeLabelCollector) Name() string {
+	return c.name
+}
+
+func (c *nodeLabelCollector) Collect(ctx context.Context, kubeClient kubernetes.Interface) (map[string]string, error) {
+	nodes, err := kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return claimOf(c.source.Claim, nodes.Items, func(node corev1.Node) string {
+		return node.Labels[c.source.Label]
+	}), nil
+}
+
+// claimOf returns the claim with the sorted different values of the nodes, no claim is returned if none of the
+// nodes has a value
+func claimOf(name string, nodes []corev1.Node, valueOf func(node corev1.Node) string) map[string]string {
+	values := sets.New[string]()
+	for _, node := range nodes {
+		if value := valueOf(node); len(value) != 0 {
+			values.Insert(value)
+		}
+	}
+	if values.Len() == 0 {
+		return map[string]string{}
+	}
+	return map[string]string{name: strings.Join(sets.List(values), ",")}
+}
@@ -0,0 +1,145 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterclaims
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newNode(name, providerID, arch string, labels map[string]string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{ProviderID: providerID},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{Architecture: arch}},
+	}
+}
+
+func TestNodeCollectors(t *testing.T) {
+	nodes := []corev1.Node{
+		newNode("node1", "aws:///us-east-1a/i-1", "amd64", map[string]string{
+			corev1.LabelArchStable:     "arm64",
+			corev1.LabelTopologyRegion: "us-east-1",
+		}),
+		newNode("node2", "aws:///us-east-1b/i-2", "amd64", map[string]string{
+			corev1.LabelFailureDomainBetaRegion: "us-west-1",
+		}),
+		newNode("node3", "kind://docker/kind/kind-control-plane", "amd64", nil),
+	}
+
+	tests := []struct {
+		name    string
+		collect func(nodes []corev1.Node) map[string]string
+		nodes   []corev1.Node
+		want    map[string]string
+	}{
+		{
+			name:    "node count",
+			collect: collectNodeCount,
+			nodes:   nodes,
+			want:    map[string]string{ClaimNodeCount: "3"},
+		},
+		{
+			name:    "node count without nodes",
+			collect: collectNodeCount,
+			want:    map[string]string{ClaimNodeCount: "0"},
+		},
+		{
+			name:    "architecture",
+			collect: collectArchitecture,
+			nodes:   nodes,
+			want:    map[string]string{ClaimArchitecture: "amd64,arm64"},
+		},
+		{
+			name:    "platform",
+			collect: collectPlatform,
+			nodes:   nodes,
+			want:    map[string]string{ClaimPlatform: "AWS,Other"},
+		},
+		{
+			name:    "platform without provider id",
+			collect: collectPlatform,
+			nodes:   []corev1.Node{newNode("node1", "", "", nil)},
+			want:    map[string]string{ClaimPlatform: PlatformOther},
+		},
+		{
+			name:    "region",
+			collect: collectRegion,
+			nodes:   nodes,
+			want:    map[string]string{ClaimRegion: "us-east-1,us-west-1"},
+		},
+		{
+			name:    "no region",
+			collect: collectRegion,
+			nodes:   []corev1.Node{newNode("node1", "", "", nil)},
+			want:    map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.collect(tt.nodes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomCollectors(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-claims"},
+		Data:       map[string]string{"team.example.com": " team1\n"},
+	}
+	node1 := newNode("node1", "", "", map[string]string{"topology.kubernetes.io/zone": "zone-b"})
+	node2 := newNode("node2", "", "", map[string]string{"topology.kubernetes.io/zone": "zone-a"})
+
+	tests := []struct {
+		name      string
+		collector Collector
+		objects   []runtime.Object
+		want      map[string]string
+	}{
+		{
+			name: "configmap",
+			collector: &configMapCollector{
+				name:   "team",
+				source: ConfigMapSource{Namespace: "default", Name: "cluster-claims"},
+			},
+			objects: []runtime.Object{configMap},
+			want:    map[string]string{"team.example.com": "team1"},
+		},
+		{
+			name: "configmap does not exist",
+			collector: &configMapCollector{
+				name:   "team",
+				source: ConfigMapSource{Namespace: "default", Name: "cluster-claims"},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "node label",
+			collector: &nodeLabelCollector{
+				name:   "zone",
+				source: NodeLabelSource{Label: "topology.kubernetes.io/zone", Claim: "zone.example.com"},
+			},
+			objects: []runtime.Object{&node1, &node2},
+			want:    map[string]string{"zone.example.com": "zone-a,zone-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.collector.Collect(context.TODO(), kubefake.NewSimpleClientset(tt.objects...))
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Collect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
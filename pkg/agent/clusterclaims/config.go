@@ -0,0 +1,173 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterclaims
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Config is the config of the cluster claim collectors, the built-in collectors are enabled by default, they can
+// be disabled or their refresh intervals can be changed by the collectors with the same names, and the other
+// collectors are the custom collectors that read the claims from a configmap or a node label.
+//
+// e.g.
+//
+//	collectors:
+//	- name: nodecount
+//	  interval: 1m
+//	- name: platform
+//	  disabled: true
+//	- name: team
+//	  configMap:
+//	    namespace: default
+//	    name: cluster-claims
+//	- name: zone
+//	  interval: 5m
+//	  nodeLabel:
+//	    label: topology.kubernetes.io/zone
+//	    claim: zone.example.com
+type Config struct {
+	Collectors []CollectorConfig `json:"collectors,omitempty"`
+}
+
+// CollectorConfig is the config of a collector, one of configMap and nodeLabel is required for a custom collector
+type CollectorConfig struct {
+	// Name is the name of the collector, it must be a DNS-1123 label
+	Name string `json:"name"`
+	// Interval is the refresh interval of the collector, the default interval is used if it is not set
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// Disabled disables the collector, the claims that are collected by the collector are removed
+	Disabled bool `json:"disabled,omitempty"`
+
+	ConfigMap *ConfigMapSource `json:"configMap,omitempty"`
+	NodeLabel *NodeLabelSource `json:"nodeLabel,omitempty"`
+}
+
+// ConfigMapSource collects the claims from a configmap, each key of the configmap is a claim name and its value is
+// the claim value
+type ConfigMapSource struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// NodeLabelSource collects a claim from a node label, the claim value is the label value, the different values of
+// the nodes are sorted and joined with a comma
+type NodeLabelSource struct {
+	Label string `json:"label"`
+	Claim string `json:"claim"`
+}
+
+// LoadConfig loads the config from the file, an empty config is returned if the file is not specified
+func LoadConfig(file string) (*Config, error) {
+	config := &Config{}
+	if len(file) == 0 {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cluster claims config %q, %v", file, err)
+	}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse the cluster claims config %q, %v", file, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("the cluster claims config %q is invalid, %v", file, err)
+	}
+	return config, nil
+}
+
+// Validate validates the collectors of the config
+func (c *Config) Validate() error {
+	names := map[string]bool{}
+	for _, collector := range c.Collectors {
+		if errs := validation.IsDNS1123Label(collector.Name); len(errs) != 0 {
+			return fmt.Errorf("the collector name %q is invalid, %s", collector.Name, strings.Join(errs, ", "))
+		}
+		if names[collector.Name] {
+			return fmt.Errorf("the collector %q is duplicated", collector.Name)
+		}
+		names[collector.Name] = true
+
+		if collector.Interval.Duration < 0 {
+			return fmt.Errorf("the interval of the collector %q is negative", collector.Name)
+		}
+
+		_, builtin := builtinCollectors[collector.Name]
+		switch {
+		case collector.ConfigMap != nil && collector.NodeLabel != nil:
+			return fmt.Errorf("the collector %q has both configMap and nodeLabel", collector.Name)
+		case builtin && (collector.ConfigMap != nil || collector.NodeLabel != nil):
+			return fmt.Errorf("the collector %q is a built-in collector, it cannot have configMap or nodeLabel",
+				collector.Name)
+		case !builtin && collector.ConfigMap == nil && collector.NodeLabel == nil:
+			return fmt.Errorf("the collector %q is not a built-in collector, one of configMap and nodeLabel is required",
+				collector.Name)
+		}
+
+		if collector.ConfigMap != nil && (len(collector.ConfigMap.Namespace) == 0 || len(collector.ConfigMap.Name) == 0) {
+			return fmt.Errorf("the namespace and name of the configmap of the collector %q are required", collector.Name)
+		}
+		if collector.NodeLabel != nil {
+			if len(collector.NodeLabel.Label) == 0 {
+				return fmt.Errorf("the node label of the collector %q is required", collector.Name)
+			}
+			if err := validateClaimName(collector.NodeLabel.Claim); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newCollectors returns the enabled collectors with their refresh intervals
+func (c *Config) newCollectors(defaultInterval time.Duration) []scheduledCollector {
+	configs := map[string]CollectorConfig{}
+	for _, collector := range c.Collectors {
+		configs[collector.Name] = collector
+	}
+
+	interval := func(config CollectorConfig) time.Duration {
+		if config.Interval.Duration > 0 {
+			return config.Interval.Duration
+		}
+		return defaultInterval
+	}
+
+	collectors := []scheduledCollector{}
+	for _, name := range builtinCollectorNames() {
+		config := configs[name]
+		if config.Disabled {
+			continue
+		}
+		collectors = append(collectors, scheduledCollector{
+			Collector: builtinCollectors[name](),
+			interval:  interval(config),
+		})
+	}
+
+	for _, config := range c.Collectors {
+		if config.Disabled {
+			continue
+		}
+
+		var collector Collector
+		switch {
+		case config.ConfigMap != nil:
+			collector = &configMapCollector{name: config.Name, source: *config.ConfigMap}
+		case config.NodeLabel != nil:
+			collector = &nodeLabelCollector{name: config.Name, source: *config.NodeLabel}
+		default:
+			continue
+		}
+		collectors = append(collectors, scheduledCollector{Collector: collector, interval: interval(config)})
+	}
+	return collectors
+}
@@ -0,0 +1,157 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterclaims
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	configMap := &ConfigMapSource{Namespace: "default", Name: "cluster-claims"}
+	nodeLabel := &NodeLabelSource{Label: "topology.kubernetes.io/zone", Claim: "zone.example.com"}
+
+	tests := []struct {
+		name       string
+		collectors []CollectorConfig
+		wantErr    bool
+	}{
+		{
+			name: "valid collectors",
+			collectors: []CollectorConfig{
+				{Name: "nodecount", Interval: metav1.Duration{Duration: time.Minute}},
+				{Name: "platform", Disabled: true},
+				{Name: "team", ConfigMap: configMap},
+				{Name: "zone", NodeLabel: nodeLabel},
+			},
+		},
+		{
+			name:       "invalid name",
+			collectors: []CollectorConfig{{Name: "Team", ConfigMap: configMap}},
+			wantErr:    true,
+		},
+		{
+			name:       "duplicated collectors",
+			collectors: []CollectorConfig{{Name: "team", ConfigMap: configMap}, {Name: "team", ConfigMap: configMap}},
+			wantErr:    true,
+		},
+		{
+			name:       "negative interval",
+			collectors: []CollectorConfig{{Name: "nodecount", Interval: metav1.Duration{Duration: -time.Minute}}},
+			wantErr:    true,
+		},
+		{
+			name:       "both configmap and node label",
+			collectors: []CollectorConfig{{Name: "team", ConfigMap: configMap, NodeLabel: nodeLabel}},
+			wantErr:    true,
+		},
+		{
+			name:       "built-in collector with configmap",
+			collectors: []CollectorConfig{{Name: "region", ConfigMap: configMap}},
+			wantErr:    true,
+		},
+		{
+			name:       "custom collector without source",
+			collectors: []CollectorConfig{{Name: "team"}},
+			wantErr:    true,
+		},
+		{
+			name:       "configmap without name",
+			collectors: []CollectorConfig{{Name: "team", ConfigMap: &ConfigMapSource{Namespace: "default"}}},
+			wantErr:    true,
+		},
+		{
+			name: "node label with invalid claim name",
+			collectors: []CollectorConfig{
+				{Name: "zone", NodeLabel: &NodeLabelSource{Label: "topology.kubernetes.io/zone", Claim: "Zone"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Collectors: tt.collectors}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "valid config",
+			content: `
+collectors:
+- name: platform
+  disabled: true
+- name: team
+  configMap:
+    namespace: default
+    name: cluster-claims
+`,
+			want: 2,
+		},
+		{
+			name:    "unknown field",
+			content: "collectors:\n- name: nodecount\n  unknown: true\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid config",
+			content: "collectors:\n- name: team\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := path.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadConfig(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(config.Collectors) != tt.want {
+				t.Errorf("LoadConfig() returns %d collectors, want %d", len(config.Collectors), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCollectors(t *testing.T) {
+	config := &Config{Collectors: []CollectorConfig{
+		{Name: "nodecount", Interval: metav1.Duration{Duration: time.Minute}},
+		{Name: "platform", Disabled: true},
+		{Name: "team", ConfigMap: &ConfigMapSource{Namespace: "default", Name: "cluster-claims"}},
+		{Name: "zone", Disabled: true, NodeLabel: &NodeLabelSource{Label: "zone", Claim: "zone.example.com"}},
+	}}
+
+	intervals := map[string]time.Duration{}
+	for _, collector := range config.newCollectors(10 * time.Minute) {
+		intervals[collector.Name()] = collector.interval
+	}
+
+	want := map[string]time.Duration{
+		"architecture": 10 * time.Minute,
+		"kubeversion":  10 * time.Minute,
+		"nodecount":    time.Minute,
+		"region":       10 * time.Minute,
+		"team":         10 * time.Minute,
+	}
+	if !reflect.DeepEqual(intervals, want) {
+		t.Errorf("newCollectors() = %v, want %v", intervals, want)
+	}
+}
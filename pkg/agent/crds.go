@@ -0,0 +1,193 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/assets"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// CRDInstallModeApply creates or updates the CRDs of the agent on the managed cluster
+	CRDInstallModeApply = "apply"
+	// CRDInstallModeVerify requires the CRDs are installed with the expected versions, e.g. by the cluster admin
+	CRDInstallModeVerify = "verify"
+	// CRDInstallModeSkip does not check the CRDs
+	CRDInstallModeSkip = "skip"
+)
+
+// crdSchemaHashAnnotation is the hash of the spec of the agent CRDs, it is stamped on the CRDs that are applied by
+// the agent and on the files in pkg/agent/crds, so the installed CRDs are verified without comparing the defaulted
+// schemas.
+const crdSchemaHashAnnotation = "multicluster-controlplane.open-cluster-management.io/crd-schema-hash"
+
+//go:embed crds
+var crds embed.FS
+
+var crdStaticFiles = []string{
+	"crds/0000_01_work.open-cluster-management.io_appliedmanifestworks.crd.yaml",
+	"crds/0000_02_clusters.open-cluster-management.io_clusterclaims.crd.yaml",
+}
+
+func validateCRDInstallMode(mode string) error {
+	switch mode {
+	case CRDInstallModeApply, CRDInstallModeVerify, CRDInstallModeSkip:
+		return nil
+	}
+	return fmt.Errorf("unsupported crd install mode %q, it should be one of %s, %s and %s",
+		mode, CRDInstallModeApply, CRDInstallModeVerify, CRDInstallModeSkip)
+}
+
+// ensureCRDs applies or verifies the CRDs of the agent by the crd install mode
+func (o *AgentOptions) ensureCRDs(ctx context.Context, client apiextensionsclient.Interface) error {
+	switch o.CRDInstallMode {
+	case CRDInstallModeSkip:
+		klog.Info("the crds of the agent are skipped")
+		return nil
+	case CRDInstallModeVerify:
+		return verifyCRDs(ctx, client)
+	case CRDInstallModeApply:
+		return o.applyCRDs(ctx, client)
+	}
+	return validateCRDInstallMode(o.CRDInstallMode)
+}
+
+func (o *AgentOptions) applyCRDs(ctx context.Context, client apiextensionsclient.Interface) error {
+	requiredCRDs, err := loadCRDs()
+	if err != nil {
+		return err
+	}
+
+	for _, required := range requiredCRDs {
+		if _, _, err := resourceapply.ApplyCustomResourceDefinitionV1(
+			ctx,
+			client.ApiextensionsV1(),
+			o.eventRecorder,
+			required,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyCRDs checks the CRDs are installed and established, each version of the agent CRDs is served with the
+// same storage version and the schemas are not outdated, so the agent does not need the permission to manage the CRDs.
+func verifyCRDs(ctx context.Context, client apiextensionsclient.Interface) error {
+	requiredCRDs, err := loadCRDs()
+	if err != nil {
+		return err
+	}
+
+	errs := []string{}
+	for _, required := range requiredCRDs {
+		if err := verifyCRD(ctx, client, required); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("the crds of the agent are not ready, install them with the expected versions or "+
+			"use --crd-install-mode=%s: %s", CRDInstallModeApply, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func verifyCRD(ctx context.Context, client apiextensionsclient.Interface, required *crdv1.CustomResourceDefinition) error {
+	existing, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, required.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("the crd %s is not found", required.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the crd %s, %v", required.Name, err)
+	}
+
+	established := false
+	for _, condition := range existing.Status.Conditions {
+		if condition.Type == crdv1.Established && condition.Status == crdv1.ConditionTrue {
+			established = true
+		}
+	}
+	if !established {
+		return fmt.Errorf("the crd %s is not established", required.Name)
+	}
+
+	for _, requiredVersion := range required.Spec.Versions {
+		var existingVersion *crdv1.CustomResourceDefinitionVersion
+		for i := range existing.Spec.Versions {
+			if existing.Spec.Versions[i].Name == requiredVersion.Name {
+				existingVersion = &existing.Spec.Versions[i]
+			}
+		}
+
+		switch {
+		case existingVersion == nil:
+			return fmt.Errorf("the version %s of the crd %s is not found", requiredVersion.Name, required.Name)
+		case requiredVersion.Served && !existingVersion.Served:
+			return fmt.Errorf("the version %s of the crd %s is not served", requiredVersion.Name, required.Name)
+		case requiredVersion.Storage && !existingVersion.Storage:
+			return fmt.Errorf("the storage version of the crd %s is not %s", required.Name, requiredVersion.Name)
+		}
+	}
+
+	requiredHash := required.Annotations[crdSchemaHashAnnotation]
+	if existingHash := existing.Annotations[crdSchemaHashAnnotation]; existingHash != requiredHash {
+		return fmt.Errorf("the schema of the crd %s is not the expected one, the %s annotation is %q, want %q",
+			required.Name, crdSchemaHashAnnotation, existingHash, requiredHash)
+	}
+
+	return nil
+}
+
+// crdSchemaHash returns the hash of the crd spec
+func crdSchemaHash(crd *crdv1.CustomResourceDefinition) (string, error) {
+	data, err := json.Marshal(crd.Spec)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+func loadCRDs() ([]*crdv1.CustomResourceDefinition, error) {
+	requiredCRDs := []*crdv1.CustomResourceDefinition{}
+	for _, crdFileName := range crdStaticFiles {
+		template, err := crds.ReadFile(crdFileName)
+		if err != nil {
+			return nil, err
+		}
+
+		objData := assets.MustCreateAssetFromTemplate(crdFileName, template, nil).Data
+		obj, _, err := genericCodec.Decode(objData, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		required, ok := obj.(*crdv1.CustomResourceDefinition)
+		if !ok {
+			continue
+		}
+
+		// the embedded files may be updated from the upstream without the hash, so it is always stamped here
+		hash, err := crdSchemaHash(required)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash the crd %s, %v", required.Name, err)
+		}
+		if required.Annotations == nil {
+			required.Annotations = map[string]string{}
+		}
+		required.Annotations[crdSchemaHashAnnotation] = hash
+		requiredCRDs = append(requiredCRDs, required)
+	}
+	return requiredCRDs, nil
+}
@@ -0,0 +1,119 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/assets"
+	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestEmbeddedCRDSchemaHash(t *testing.T) {
+	for _, crdFileName := range crdStaticFiles {
+		t.Run(crdFileName, func(t *testing.T) {
+			template, err := crds.ReadFile(crdFileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj, _, err := genericCodec.Decode(assets.MustCreateAssetFromTemplate(crdFileName, template, nil).Data, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd := obj.(*crdv1.CustomResourceDefinition)
+
+			// the CRDs that are installed from the files by the cluster admin must pass the verification
+			hash, err := crdSchemaHash(crd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if crd.Annotations[crdSchemaHashAnnotation] != hash {
+				t.Errorf("the %s annotation of %s = %q, want %q", crdSchemaHashAnnotation, crdFileName,
+					crd.Annotations[crdSchemaHashAnnotation], hash)
+			}
+		})
+	}
+}
+
+func TestVerifyCRD(t *testing.T) {
+	requiredCRDs, err := loadCRDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	required := requiredCRDs[0]
+
+	established := func(crd *crdv1.CustomResourceDefinition) *crdv1.CustomResourceDefinition {
+		crd.Status.Conditions = append(crd.Status.Conditions, crdv1.CustomResourceDefinitionCondition{
+			Type:   crdv1.Established,
+			Status: crdv1.ConditionTrue,
+		})
+		return crd
+	}
+
+	tests := []struct {
+		name     string
+		existing func() *crdv1.CustomResourceDefinition
+		wantErr  bool
+	}{
+		{
+			name:    "not found",
+			wantErr: true,
+		},
+		{
+			name: "installed",
+			existing: func() *crdv1.CustomResourceDefinition {
+				return established(required.DeepCopy())
+			},
+		},
+		{
+			name: "not established",
+			existing: func() *crdv1.CustomResourceDefinition {
+				return required.DeepCopy()
+			},
+			wantErr: true,
+		},
+		{
+			name: "version is not served",
+			existing: func() *crdv1.CustomResourceDefinition {
+				crd := established(required.DeepCopy())
+				crd.Spec.Versions[0].Served = false
+				return crd
+			},
+			wantErr: true,
+		},
+		{
+			name: "no schema hash",
+			existing: func() *crdv1.CustomResourceDefinition {
+				crd := established(required.DeepCopy())
+				delete(crd.Annotations, crdSchemaHashAnnotation)
+				return crd
+			},
+			wantErr: true,
+		},
+		{
+			name: "outdated schema",
+			existing: func() *crdv1.CustomResourceDefinition {
+				crd := established(required.DeepCopy())
+				crd.Annotations[crdSchemaHashAnnotation] = "outdated"
+				return crd
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []runtime.Object{}
+			if tt.existing != nil {
+				objs = append(objs, tt.existing())
+			}
+			client := apiextensionsfake.NewSimpleClientset(objs...)
+
+			err := verifyCRD(context.TODO(), client, required)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyCRD() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
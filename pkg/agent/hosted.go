@@ -0,0 +1,403 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent/addons"
+)
+
+const (
+	// HostedClusterNameLabel is the label of the spoke kubeconfig secrets, its value is the name of the cluster,
+	// the name of the secret is used if the label value is empty.
+	HostedClusterNameLabel = "multicluster-controlplane.open-cluster-management.io/hosted-cluster"
+
+	hostedKubeconfigKey    = "kubeconfig"
+	hostedKubeconfigSuffix = ".kubeconfig"
+	hostedRestartInterval  = 10 * time.Second
+)
+
+// HostedAgentOptions runs the agents of many spoke clusters in one process, the spoke kubeconfigs are read from
+// a directory or the secrets of the hosting cluster. Each spoke cluster has its own registration and work
+// agents, and its hub credentials are kept in its own directory and secret.
+type HostedAgentOptions struct {
+	// AgentOptions is the template of the agents, the cluster name, the spoke kubeconfig and the hub
+	// credentials are set for each spoke cluster.
+	*AgentOptions
+
+	// SpokeKubeconfigsDir is the directory of the spoke kubeconfigs, the file name (without the .kubeconfig
+	// suffix) is the cluster name, the files that start with a dot are ignored, so a secret can be mounted
+	// as the directory.
+	SpokeKubeconfigsDir string
+	// SpokeKubeconfigSecretNamespace is the namespace of the spoke kubeconfig secrets in the hosting cluster,
+	// the secrets have the HostedClusterNameLabel and contain the spoke kubeconfig in the kubeconfig key.
+	SpokeKubeconfigSecretNamespace string
+	// HubKubeconfigsDir is the parent directory of the hub kubeconfig directories of the spoke clusters
+	HubKubeconfigsDir string
+	// ResyncInterval is the interval to reload the spoke kubeconfigs
+	ResyncInterval time.Duration
+
+	hostingKubeClient kubernetes.Interface
+	// runSpokeCluster runs the agent and the addons of a spoke cluster until the context is done
+	runSpokeCluster func(ctx context.Context, clusterName, spokeKubeconfigFile string)
+
+	sync.RWMutex
+	agents map[string]*hostedAgent
+	synced bool
+}
+
+// hostedAgent is the agent of a spoke cluster that is run in the hosted mode, done is closed after both the
+// agent and the addons of the spoke cluster are stopped.
+type hostedAgent struct {
+	hash   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop stops the agent and waits for the agent and its addons to exit
+func (a *hostedAgent) stop() {
+	a.cancel()
+	<-a.done
+}
+
+func NewHostedAgentOptions() *HostedAgentOptions {
+	o := &HostedAgentOptions{
+		AgentOptions:      NewAgentOptions(),
+		HubKubeconfigsDir: "/spoke/hub-kubeconfigs",
+		ResyncInterval:    30 * time.Second,
+		agents:            map[string]*hostedAgent{},
+	}
+	o.runSpokeCluster = o.runSpokeClusterAgents
+	return o
+}
+
+func (o *HostedAgentOptions) AddFlags(fs *pflag.FlagSet) {
+	o.AgentOptions.AddFlags(fs)
+	fs.StringVar(&o.SpokeKubeconfigsDir, "spoke-kubeconfigs-dir", o.SpokeKubeconfigsDir,
+		"The directory of the spoke kubeconfigs, the file name (without the .kubeconfig suffix) is the cluster name")
+	fs.StringVar(&o.SpokeKubeconfigSecretNamespace, "spoke-kubeconfig-secret-namespace", o.SpokeKubeconfigSecretNamespace,
+		fmt.Sprintf("The namespace of the spoke kubeconfig secrets in the hosting cluster, the secrets are selected "+
+			"by the label %q and the kubeconfig is in the %q key", HostedClusterNameLabel, hostedKubeconfigKey))
+	fs.StringVar(&o.HubKubeconfigsDir, "hub-kubeconfigs-dir", o.HubKubeconfigsDir,
+		"The parent directory of the hub kubeconfig directories of the spoke clusters")
+	fs.DurationVar(&o.ResyncInterval, "spoke-kubeconfigs-resync-interval", o.ResyncInterval,
+		"The interval to reload the spoke kubeconfigs")
+}
+
+func (o *HostedAgentOptions) Validate() error {
+	if len(o.SpokeKubeconfigsDir) == 0 && len(o.SpokeKubeconfigSecretNamespace) == 0 {
+		return fmt.Errorf("either the spoke kubeconfigs dir or the spoke kubeconfig secret namespace is required")
+	}
+	if len(o.HubKubeconfigsDir) == 0 {
+		return fmt.Errorf("the hub kubeconfigs dir is required")
+	}
+	if o.ResyncInterval <= 0 {
+		return fmt.Errorf("the resync interval must be positive")
+	}
+	return nil
+}
+
+// RunHostedAgents runs the agents of the spoke clusters until the context is done, the agents are added,
+// restarted and removed when the spoke kubeconfigs are changed.
+func (o *HostedAgentOptions) RunHostedAgents(ctx context.Context) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	if len(o.SpokeKubeconfigSecretNamespace) != 0 {
+		hostingKubeConfig, err := o.inClusterKubeConfig()
+		if err != nil {
+			return err
+		}
+		if o.hostingKubeClient, err = kubernetes.NewForConfig(hostingKubeConfig); err != nil {
+			return err
+		}
+	}
+
+	wait.UntilWithContext(ctx, o.sync, o.ResyncInterval)
+
+	o.Lock()
+	agents := o.agents
+	o.agents = map[string]*hostedAgent{}
+	o.Unlock()
+
+	for _, agent := range agents {
+		agent.stop()
+	}
+	return nil
+}
+
+// RunServer runs the health and metrics server of the hosted agents, the hosted agents are ready once the spoke
+// kubeconfigs are loaded, the readiness of a spoke cluster does not affect the others.
+func (o *HostedAgentOptions) RunServer(ctx context.Context) error {
+	if o.SecureServing.BindPort == 0 {
+		klog.Info("the secure port of the agent is disabled")
+		return nil
+	}
+
+	return o.runServer(ctx,
+		[]healthz.HealthChecker{healthz.PingHealthz},
+		[]healthz.HealthChecker{healthz.PingHealthz, healthz.NamedCheck("spoke-kubeconfigs", func(_ *http.Request) error {
+			o.RLock()
+			defer o.RUnlock()
+			if !o.synced {
+				return fmt.Errorf("the spoke kubeconfigs are not loaded")
+			}
+			return nil
+		})},
+	)
+}
+
+func (o *HostedAgentOptions) sync(ctx context.Context) {
+	spokeKubeconfigs, err := o.loadSpokeKubeconfigs(ctx)
+	if err != nil {
+		klog.Errorf("failed to load the spoke kubeconfigs, %v", err)
+		return
+	}
+
+	// the changed and removed agents are stopped without holding the lock, and a changed agent is started
+	// again only after its previous agent and addons are stopped.
+	for clusterName, agent := range o.removeAgents(spokeKubeconfigs) {
+		klog.Infof("stopping the hosted agent of the cluster %q", clusterName)
+		agent.stop()
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	for clusterName, kubeconfig := range spokeKubeconfigs {
+		if _, ok := o.agents[clusterName]; ok {
+			continue
+		}
+
+		klog.Infof("starting the hosted agent of the cluster %q", clusterName)
+		if err := o.startAgent(ctx, clusterName, kubeconfig); err != nil {
+			klog.Errorf("failed to start the hosted agent of the cluster %q, %v", clusterName, err)
+		}
+	}
+
+	o.synced = true
+}
+
+// removeAgents removes the agents whose spoke kubeconfigs are changed or removed, and returns the removed agents
+func (o *HostedAgentOptions) removeAgents(spokeKubeconfigs map[string][]byte) map[string]*hostedAgent {
+	o.Lock()
+	defer o.Unlock()
+
+	removed := map[string]*hostedAgent{}
+	for clusterName, agent := range o.agents {
+		kubeconfig, ok := spokeKubeconfigs[clusterName]
+		if ok && agent.hash == hash(kubeconfig) {
+			continue
+		}
+
+		removed[clusterName] = agent
+		delete(o.agents, clusterName)
+	}
+	return removed
+}
+
+// loadSpokeKubeconfigs returns the spoke kubeconfigs that are keyed by the cluster names
+func (o *HostedAgentOptions) loadSpokeKubeconfigs(ctx context.Context) (map[string][]byte, error) {
+	spokeKubeconfigs := map[string][]byte{}
+
+	if len(o.SpokeKubeconfigsDir) != 0 {
+		entries, err := os.ReadDir(o.SpokeKubeconfigsDir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			// the secret volume has the hidden ..data dir and files
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			file := filepath.Join(o.SpokeKubeconfigsDir, entry.Name())
+			if info, err := os.Stat(file); err != nil || info.IsDir() {
+				continue
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			spokeKubeconfigs[strings.TrimSuffix(entry.Name(), hostedKubeconfigSuffix)] = data
+		}
+	}
+
+	if o.hostingKubeClient != nil {
+		secrets, err := o.hostingKubeClient.CoreV1().Secrets(o.SpokeKubeconfigSecretNamespace).List(ctx, metav1.ListOptions{
+			LabelSelector: HostedClusterNameLabel,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, secret := range secrets.Items {
+			clusterName := secret.Labels[HostedClusterNameLabel]
+			if len(clusterName) == 0 {
+				clusterName = secret.Name
+			}
+
+			data, ok := secret.Data[hostedKubeconfigKey]
+			if !ok {
+				klog.Warningf("the secret %s/%s does not have the %q key", secret.Namespace, secret.Name, hostedKubeconfigKey)
+				continue
+			}
+			if _, ok := spokeKubeconfigs[clusterName]; ok {
+				klog.Warningf("the kubeconfig of the cluster %q is duplicated, the secret %s/%s is ignored",
+					clusterName, secret.Namespace, secret.Name)
+				continue
+			}
+			spokeKubeconfigs[clusterName] = data
+		}
+	}
+
+	for clusterName := range spokeKubeconfigs {
+		if errs := validation.IsDNS1123Label(clusterName); len(errs) != 0 {
+			klog.Warningf("the cluster name %q is invalid, %s", clusterName, strings.Join(errs, ", "))
+			delete(spokeKubeconfigs, clusterName)
+		}
+	}
+
+	return spokeKubeconfigs, nil
+}
+
+// startAgent runs the agent of the spoke cluster, the agent is restarted if it is stopped, e.g. the hub
+// kubeconfig is changed, until the agent is removed.
+func (o *HostedAgentOptions) startAgent(ctx context.Context, clusterName string, kubeconfig []byte) error {
+	configDir, err := agentConfigDir("hosted", clusterName)
+	if err != nil {
+		return err
+	}
+
+	spokeKubeconfigFile := filepath.Join(configDir, "spoke-kubeconfig")
+	if err := os.WriteFile(spokeKubeconfigFile, kubeconfig, 0600); err != nil {
+		return fmt.Errorf("failed to write file %q, %v", spokeKubeconfigFile, err)
+	}
+
+	agentCtx, cancel := context.WithCancel(ctx)
+	agent := &hostedAgent{
+		hash:   hash(kubeconfig),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	o.agents[clusterName] = agent
+
+	go func() {
+		defer close(agent.done)
+		o.runSpokeCluster(agentCtx, clusterName, spokeKubeconfigFile)
+	}()
+
+	return nil
+}
+
+// runSpokeClusterAgents runs the agent and the addons of the spoke cluster, and returns after both of them
+// are stopped.
+func (o *HostedAgentOptions) runSpokeClusterAgents(ctx context.Context, clusterName, spokeKubeconfigFile string) {
+	var wg sync.WaitGroup
+
+	enabledAddOns, err := addons.EnabledAddOns(o.AddOns)
+	if err != nil {
+		klog.Errorf("failed to run the addons of the cluster %q, %v", clusterName, err)
+	}
+	if len(enabledAddOns) != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o.newSpokeAgentOptions(clusterName, spokeKubeconfigFile).runAddOnsUntil(ctx, enabledAddOns)
+		}()
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := o.runSpokeAgent(ctx, clusterName, spokeKubeconfigFile); err != nil {
+			klog.Errorf("failed to run the hosted agent of the cluster %q, %v", clusterName, err)
+		}
+	}, hostedRestartInterval)
+
+	wg.Wait()
+}
+
+// runSpokeAgent runs the agent of the spoke cluster until it is stopped, the failures and the panics of RunAgent are
+// returned as errors, so the agent is restarted without affecting the agents of the other spoke clusters.
+func (o *HostedAgentOptions) runSpokeAgent(ctx context.Context, clusterName, spokeKubeconfigFile string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the agent panicked, %v", r)
+		}
+	}()
+
+	return o.newSpokeAgentOptions(clusterName, spokeKubeconfigFile).RunAgent(ctx)
+}
+
+// newSpokeAgentOptions copies the template options for the spoke cluster, the hub kubeconfig dir, the hub
+// kubeconfig secret and the generated config files are separated by the cluster name.
+func (o *HostedAgentOptions) newSpokeAgentOptions(clusterName, spokeKubeconfigFile string) *AgentOptions {
+	registrationOpts := *o.RegistrationAgentOpts
+	workOpts := *o.WorkAgentOpts
+	commonOpts := *o.CommonOpts
+	if o.CommonOpts.CommonOpts != nil {
+		options := *o.CommonOpts.CommonOpts
+		commonOpts.CommonOpts = &options
+	}
+
+	hubKubeconfigDir := filepath.Join(o.HubKubeconfigsDir, clusterName)
+	agentOpts := &AgentOptions{
+		RegistrationAgentOpts:        &registrationOpts,
+		WorkAgentOpts:                &workOpts,
+		CommonOpts:                   &commonOpts,
+		KubeConfig:                   o.KubeConfig,
+		GRPCServerAddress:            o.GRPCServerAddress,
+		GRPCServerCAFile:             o.GRPCServerCAFile,
+		HubAPIServer:                 o.HubAPIServer,
+		BootstrapToken:               o.BootstrapToken,
+		DiscoveryTokenCACertHashes:   o.DiscoveryTokenCACertHashes,
+		ProxyURL:                     o.ProxyURL,
+		NoProxy:                      o.NoProxy,
+		CABundleFiles:                o.CABundleFiles,
+		CRDInstallMode:               o.CRDInstallMode,
+		ClusterClaimsConfigFile:      o.ClusterClaimsConfigFile,
+		ClusterClaimsRefreshInterval: o.ClusterClaimsRefreshInterval,
+		AddOns:                       o.AddOns,
+		WorkPersistence:              o.WorkPersistence,
+		WorkPersistenceDir:           o.WorkPersistenceDir,
+		WorkPersistenceSyncInterval:  o.WorkPersistenceSyncInterval,
+		health:                       &agentHealth{},
+		eventRecorder:                o.eventRecorder,
+		configSubDir:                 filepath.Join("hosted", clusterName),
+	}
+
+	agentOpts.
+		WithClusterName(clusterName).
+		WithSpokeKubeconfig(spokeKubeconfigFile).
+		WithHubKubeconfigDir(hubKubeconfigDir).
+		WithHubKubeconfigSecreName(fmt.Sprintf("%s-hub-kubeconfig", clusterName))
+	// the agent id and the hub kubeconfig file are loaded from the hub kubeconfig dir of the cluster
+	agentOpts.CommonOpts.AgentID = ""
+	agentOpts.CommonOpts.HubKubeconfigFile = ""
+	if workOpts.WorkloadSourceDriver == "kube" {
+		agentOpts.WithWorkloadSourceDriverConfig(filepath.Join(hubKubeconfigDir, hostedKubeconfigKey))
+	}
+	return agentOpts
+}
+
+func hash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
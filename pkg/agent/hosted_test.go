@@ -0,0 +1,273 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newSpokeKubeconfigSecret(name, clusterName string, kubeconfig []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "hosted",
+			Name:      name,
+			Labels:    map[string]string{HostedClusterNameLabel: clusterName},
+		},
+		Data: map[string][]byte{hostedKubeconfigKey: kubeconfig},
+	}
+}
+
+func TestLoadSpokeKubeconfigs(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		dirs    []string
+		secrets []runtime.Object
+		want    map[string]string
+	}{
+		{
+			name: "the files in the dir",
+			files: map[string]string{
+				"cluster1.kubeconfig": "kubeconfig1",
+				"cluster2":            "kubeconfig2",
+			},
+			want: map[string]string{"cluster1": "kubeconfig1", "cluster2": "kubeconfig2"},
+		},
+		{
+			name: "the dot files and the dirs are ignored",
+			files: map[string]string{
+				"cluster1":             "kubeconfig1",
+				".cluster2.kubeconfig": "kubeconfig2",
+				"..data/cluster3":      "kubeconfig3",
+			},
+			dirs: []string{"cluster4"},
+			want: map[string]string{"cluster1": "kubeconfig1"},
+		},
+		{
+			name: "the secrets",
+			secrets: []runtime.Object{
+				newSpokeKubeconfigSecret("secret1", "cluster1", []byte("kubeconfig1")),
+				newSpokeKubeconfigSecret("cluster2", "", []byte("kubeconfig2")),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "hosted",
+						Name:      "cluster3",
+						Labels:    map[string]string{HostedClusterNameLabel: ""},
+					},
+				},
+			},
+			want: map[string]string{"cluster1": "kubeconfig1", "cluster2": "kubeconfig2"},
+		},
+		{
+			name:  "the duplicated cluster names",
+			files: map[string]string{"cluster1.kubeconfig": "kubeconfig1"},
+			secrets: []runtime.Object{
+				newSpokeKubeconfigSecret("secret1", "cluster1", []byte("kubeconfig2")),
+			},
+			want: map[string]string{"cluster1": "kubeconfig1"},
+		},
+		{
+			name: "the invalid cluster names",
+			files: map[string]string{
+				"cluster1":   "kubeconfig1",
+				"Cluster2":   "kubeconfig2",
+				"cluster_3":  "kubeconfig3",
+				"cluster4.x": "kubeconfig4",
+			},
+			secrets: []runtime.Object{
+				newSpokeKubeconfigSecret("secret1", "cluster.5", []byte("kubeconfig5")),
+			},
+			want: map[string]string{"cluster1": "kubeconfig1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tt.files {
+				file := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, file, []byte(data))
+			}
+			for _, name := range tt.dirs {
+				if err := os.MkdirAll(filepath.Join(dir, name), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			o := NewHostedAgentOptions()
+			o.SpokeKubeconfigsDir = dir
+			o.SpokeKubeconfigSecretNamespace = "hosted"
+			o.hostingKubeClient = kubefake.NewSimpleClientset(tt.secrets...)
+
+			spokeKubeconfigs, err := o.loadSpokeKubeconfigs(context.TODO())
+			if err != nil {
+				t.Fatalf("loadSpokeKubeconfigs() error = %v", err)
+			}
+			got := map[string]string{}
+			for clusterName, data := range spokeKubeconfigs {
+				got[clusterName] = string(data)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadSpokeKubeconfigs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeSpokeClusters records the running spoke clusters of the hosted agents
+type fakeSpokeClusters struct {
+	sync.Mutex
+	running map[string]int
+	started []string
+}
+
+func (f *fakeSpokeClusters) run(ctx context.Context, clusterName, _ string) {
+	f.Lock()
+	f.running[clusterName]++
+	f.started = append(f.started, clusterName)
+	f.Unlock()
+
+	<-ctx.Done()
+
+	f.Lock()
+	f.running[clusterName]--
+	f.Unlock()
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    map[string]string
+		files       map[string]string
+		wantStarted []string
+		wantRunning []string
+	}{
+		{
+			name:        "add the agents",
+			files:       map[string]string{"cluster1": "kubeconfig1", "cluster2": "kubeconfig2"},
+			wantStarted: []string{"cluster1", "cluster2"},
+			wantRunning: []string{"cluster1", "cluster2"},
+		},
+		{
+			name:        "the agents are not changed",
+			existing:    map[string]string{"cluster1": "kubeconfig1"},
+			files:       map[string]string{"cluster1": "kubeconfig1"},
+			wantRunning: []string{"cluster1"},
+		},
+		{
+			name:        "restart the changed agent",
+			existing:    map[string]string{"cluster1": "kubeconfig1", "cluster2": "kubeconfig2"},
+			files:       map[string]string{"cluster1": "kubeconfig1-new", "cluster2": "kubeconfig2"},
+			wantStarted: []string{"cluster1"},
+			wantRunning: []string{"cluster1", "cluster2"},
+		},
+		{
+			name:        "remove the agent",
+			existing:    map[string]string{"cluster1": "kubeconfig1", "cluster2": "kubeconfig2"},
+			files:       map[string]string{"cluster2": "kubeconfig2"},
+			wantRunning: []string{"cluster2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			dir := t.TempDir()
+			o := NewHostedAgentOptions()
+			o.SpokeKubeconfigsDir = dir
+			spokeClusters := &fakeSpokeClusters{running: map[string]int{}}
+			o.runSpokeCluster = spokeClusters.run
+
+			for clusterName, kubeconfig := range tt.existing {
+				if err := o.startAgent(ctx, clusterName, []byte(kubeconfig)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// wait for the existing agents to run
+			if err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 10*time.Second, true,
+				func(_ context.Context) (bool, error) {
+					spokeClusters.Lock()
+					defer spokeClusters.Unlock()
+					for clusterName := range tt.existing {
+						if spokeClusters.running[clusterName] != 1 {
+							return false, nil
+						}
+					}
+					return true, nil
+				}); err != nil {
+				t.Fatal(err)
+			}
+			spokeClusters.started = nil
+
+			for name, data := range tt.files {
+				writeFile(t, filepath.Join(dir, name), []byte(data))
+			}
+
+			o.sync(ctx)
+
+			if !o.synced {
+				t.Errorf("the spoke kubeconfigs are not synced")
+			}
+			o.Lock()
+			agents := o.agents
+			o.Unlock()
+			if len(agents) != len(tt.wantRunning) {
+				t.Errorf("expected agents %v, but got %v", tt.wantRunning, agents)
+			}
+			for _, clusterName := range tt.wantRunning {
+				agent, ok := agents[clusterName]
+				if !ok {
+					t.Errorf("the agent of the cluster %q is not running", clusterName)
+					continue
+				}
+				if agent.hash != hash([]byte(tt.files[clusterName])) {
+					t.Errorf("the agent of the cluster %q is not run with the current kubeconfig", clusterName)
+				}
+			}
+
+			// the stopped agents are exited before the agents are started again
+			spokeClusters.Lock()
+			for clusterName := range tt.existing {
+				if _, ok := agents[clusterName]; !ok && spokeClusters.running[clusterName] != 0 {
+					t.Errorf("the removed agent of the cluster %q is not stopped", clusterName)
+				}
+				if spokeClusters.running[clusterName] > 1 {
+					t.Errorf("the agent of the cluster %q is run more than once", clusterName)
+				}
+			}
+			spokeClusters.Unlock()
+
+			cancel()
+			for _, agent := range agents {
+				<-agent.done
+			}
+
+			started := map[string]bool{}
+			for _, clusterName := range spokeClusters.started {
+				started[clusterName] = true
+			}
+			if len(started) != len(tt.wantStarted) {
+				t.Errorf("expected started agents %v, but got %v", tt.wantStarted, spokeClusters.started)
+			}
+			for _, clusterName := range tt.wantStarted {
+				if !started[clusterName] {
+					t.Errorf("the agent of the cluster %q is not started", clusterName)
+				}
+			}
+		})
+	}
+}
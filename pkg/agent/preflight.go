@@ -0,0 +1,241 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent/addons"
+	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
+)
+
+const preflightTimeout = 10 * time.Second
+
+// PreflightResult is the result of a preflight check, the check is passed if it is not skipped and has no error
+type PreflightResult struct {
+	Name    string
+	Skipped bool
+	Err     error
+}
+
+// RunPreflightChecks checks the agent can run on the managed cluster with the options before the agent starts,
+// it checks
+//   - the permissions of the agent on the managed cluster
+//   - the connectivity and the permissions of the agent on the controlplane
+//   - the TokenRequest API of the managed cluster, if the managed serviceaccount addon is enabled
+func (o *AgentOptions) RunPreflightChecks(ctx context.Context) []PreflightResult {
+	return []PreflightResult{
+		{Name: "spoke-rbac", Err: o.checkSpokeRBAC(ctx)},
+		{Name: "hub-connectivity", Err: o.checkHubConnectivity(ctx)},
+		o.checkTokenRequest(),
+	}
+}
+
+func (o *AgentOptions) checkSpokeRBAC(ctx context.Context) error {
+	managementKubeConfig, err := o.inClusterKubeConfig()
+	if err != nil {
+		return err
+	}
+	spokeKubeConfig, err := o.CommonOpts.SpokeKubeConfig(managementKubeConfig)
+	if err != nil {
+		return err
+	}
+
+	spokeKubeClient, err := kubernetes.NewForConfig(withTimeout(spokeKubeConfig))
+	if err != nil {
+		return err
+	}
+	managementKubeClient, err := kubernetes.NewForConfig(withTimeout(managementKubeConfig))
+	if err != nil {
+		return err
+	}
+
+	crdVerbs := []string{"get", "create", "update"}
+	switch o.CRDInstallMode {
+	case CRDInstallModeVerify:
+		crdVerbs = []string{"get"}
+	case CRDInstallModeSkip:
+		crdVerbs = nil
+	}
+
+	spokePermissions := []authorizationv1.ResourceAttributes{}
+	for _, verb := range crdVerbs {
+		spokePermissions = append(spokePermissions, authorizationv1.ResourceAttributes{
+			Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions", Verb: verb})
+	}
+	for _, verb := range []string{"get", "list", "watch", "create", "update", "patch", "delete"} {
+		spokePermissions = append(spokePermissions, authorizationv1.ResourceAttributes{
+			Group: "work.open-cluster-management.io", Resource: "appliedmanifestworks", Verb: verb})
+	}
+	for _, verb := range []string{"get", "list", "watch"} {
+		spokePermissions = append(spokePermissions,
+			authorizationv1.ResourceAttributes{Group: "cluster.open-cluster-management.io", Resource: "clusterclaims", Verb: verb},
+			authorizationv1.ResourceAttributes{Resource: "nodes", Verb: verb},
+		)
+	}
+	spokePermissions = append(spokePermissions,
+		authorizationv1.ResourceAttributes{Group: "authorization.k8s.io", Resource: "subjectaccessreviews", Verb: "create"})
+	if features.SpokeMutableFeatureGate.Enabled(mcfeature.ClusterClaimCollector) {
+		for _, verb := range []string{"create", "update", "delete"} {
+			spokePermissions = append(spokePermissions, authorizationv1.ResourceAttributes{
+				Group: "cluster.open-cluster-management.io", Resource: "clusterclaims", Verb: verb})
+		}
+	}
+	if enabledAddOns, err := addons.EnabledAddOns(o.AddOns); err == nil {
+		for _, addOn := range enabledAddOns {
+			if addOn.Name() == addons.ResourceUsageScoreAddOnName {
+				spokePermissions = append(spokePermissions,
+					authorizationv1.ResourceAttributes{Resource: "pods", Verb: "list"})
+			}
+		}
+	}
+
+	// the hub kubeconfig secret is kept in the component namespace of the management cluster
+	managementPermissions := []authorizationv1.ResourceAttributes{}
+	for _, verb := range []string{"get", "list", "watch", "create", "update"} {
+		managementPermissions = append(managementPermissions, authorizationv1.ResourceAttributes{
+			Namespace: o.CommonOpts.ComponentNamespace, Resource: "secrets", Verb: verb})
+	}
+
+	denied, err := deniedPermissions(ctx, spokeKubeClient, spokePermissions)
+	if err != nil {
+		return err
+	}
+	deniedManagement, err := deniedPermissions(ctx, managementKubeClient, managementPermissions)
+	if err != nil {
+		return err
+	}
+	denied = append(denied, deniedManagement...)
+
+	if len(denied) != 0 {
+		return fmt.Errorf("the agent is not allowed to %s", strings.Join(denied, ", "))
+	}
+	return nil
+}
+
+// checkHubConnectivity checks the agent can connect to the controlplane with the bootstrap kubeconfig, and it
+// has the permissions to register the cluster.
+func (o *AgentOptions) checkHubConnectivity(ctx context.Context) error {
+	if err := o.PrepareBootstrapKubeconfig(ctx); err != nil {
+		return err
+	}
+
+	bootstrapKubeConfig, err := clientcmd.BuildConfigFromFlags("", o.RegistrationAgentOpts.BootstrapKubeconfig)
+	if err != nil {
+		return fmt.Errorf("unable to load bootstrap kubeconfig from file %q: %v",
+			o.RegistrationAgentOpts.BootstrapKubeconfig, err)
+	}
+
+	bootstrapKubeConfig, err = o.withHubTransport(bootstrapKubeConfig)
+	if err != nil {
+		return err
+	}
+	hubKubeClient, err := kubernetes.NewForConfig(withTimeout(bootstrapKubeConfig))
+	if err != nil {
+		return err
+	}
+
+	if _, err := hubKubeClient.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("unable to connect to the controlplane %s, %v", bootstrapKubeConfig.Host, err)
+	}
+
+	denied, err := deniedPermissions(ctx, hubKubeClient, []authorizationv1.ResourceAttributes{
+		{Group: "certificates.k8s.io", Resource: "certificatesigningrequests", Verb: "create"},
+		{Group: "cluster.open-cluster-management.io", Resource: "managedclusters", Verb: "create"},
+	})
+	if err != nil {
+		return err
+	}
+	if len(denied) != 0 {
+		return fmt.Errorf("the bootstrap user is not allowed to %s on the controlplane", strings.Join(denied, ", "))
+	}
+
+	if len(o.GRPCServerAddress) != 0 {
+		conn, err := net.DialTimeout("tcp", o.GRPCServerAddress, preflightTimeout)
+		if err != nil {
+			return fmt.Errorf("unable to connect to the controlplane gRPC server %s, %v", o.GRPCServerAddress, err)
+		}
+		conn.Close()
+	}
+
+	return nil
+}
+
+func (o *AgentOptions) checkTokenRequest() PreflightResult {
+	result := PreflightResult{Name: "token-request"}
+	enabledAddOns, err := addons.EnabledAddOns(o.AddOns)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Skipped = true
+	for _, addOn := range enabledAddOns {
+		if addOn.Name() == addons.ManagedServiceAccountAddOnName {
+			result.Skipped = false
+		}
+	}
+	if result.Skipped {
+		return result
+	}
+
+	spokeKubeConfig, err := o.spokeKubeConfig()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	spokeKubeClient, err := kubernetes.NewForConfig(withTimeout(spokeKubeConfig))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Err = addons.CheckTokenRequestSupport(spokeKubeClient)
+	return result
+}
+
+// deniedPermissions returns the permissions that are denied by the self subject access reviews
+func deniedPermissions(ctx context.Context, client kubernetes.Interface,
+	permissions []authorizationv1.ResourceAttributes) ([]string, error) {
+	denied := []string{}
+	for i := range permissions {
+		review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &permissions[i],
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review the permissions, %v", err)
+		}
+		if !review.Status.Allowed {
+			denied = append(denied, describePermission(permissions[i]))
+		}
+	}
+	return denied, nil
+}
+
+func describePermission(p authorizationv1.ResourceAttributes) string {
+	resource := p.Resource
+	if len(p.Group) != 0 {
+		resource = fmt.Sprintf("%s.%s", p.Resource, p.Group)
+	}
+	if len(p.Namespace) != 0 {
+		return fmt.Sprintf("%s %s in the namespace %s", p.Verb, resource, p.Namespace)
+	}
+	return fmt.Sprintf("%s %s", p.Verb, resource)
+}
+
+func withTimeout(config *rest.Config) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.Timeout = preflightTimeout
+	return config
+}
@@ -0,0 +1,211 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	corev1 "k8s.io/api/core/v1"
+	genericserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/apiserver/pkg/server/mux"
+	genericoptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/component-base/metrics/legacyregistry"
+	controllermanagerapp "k8s.io/controller-manager/app"
+	"k8s.io/klog/v2"
+	"open-cluster-management.io/ocm/pkg/registration/register"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var alwaysAllowPaths = []string{"/healthz", "/livez", "/readyz"}
+
+// agentHealth tracks the health of the agent, the health checkers of the registration agent are set once the
+// agent is started.
+type agentHealth struct {
+	sync.RWMutex
+	checkers []healthz.HealthChecker
+}
+
+func (h *agentHealth) setCheckers(checkers []healthz.HealthChecker) {
+	h.Lock()
+	defer h.Unlock()
+	h.checkers = checkers
+}
+
+// livenessCheck runs the health checkers of the registration agent, the agent is unhealthy if its hub client
+// certificate is expired and it cannot be recovered by the bootstrap kubeconfig.
+func (h *agentHealth) livenessCheck() healthz.HealthChecker {
+	return healthz.NamedCheck("agent", func(r *http.Request) error {
+		h.RLock()
+		defer h.RUnlock()
+		for _, checker := range h.checkers {
+			if err := checker.Check(r); err != nil {
+				return fmt.Errorf("%s: %v", checker.Name(), err)
+			}
+		}
+		return nil
+	})
+}
+
+// RunServer runs the secure server of the agent, it serves
+//   - /healthz and /livez, the agent is alive unless its hub client certificate cannot be recovered
+//   - /readyz, the agent is ready once the hub kubeconfig is valid
+//   - /metrics, the metrics of the registration and work agents
+//
+// the requests of the /metrics are authenticated and authorized by the managed cluster. The server is disabled by
+// default, it is enabled by the --secure-port flag.
+func (o *AgentOptions) RunServer(ctx context.Context) error {
+	if o.SecureServing.BindPort == 0 {
+		klog.Info("the secure port of the agent is disabled")
+		return nil
+	}
+
+	return o.runServer(ctx,
+		[]healthz.HealthChecker{healthz.PingHealthz, o.health.livenessCheck()},
+		[]healthz.HealthChecker{healthz.PingHealthz, o.hubKubeConfigCheck()},
+	)
+}
+
+func (o *AgentOptions) runServer(ctx context.Context, livenessChecks, readinessChecks []healthz.HealthChecker) error {
+	// the self-signed certificates are generated in the agent config dir rather than the working dir
+	if len(o.SecureServing.ServerCert.CertDirectory) == 0 {
+		certDir, err := agentConfigDir(o.configSubDir, "serving-certs")
+		if err != nil {
+			return err
+		}
+		o.SecureServing.ServerCert.CertDirectory = certDir
+	}
+	if err := o.SecureServing.MaybeDefaultWithSelfSignedCerts("localhost", nil, nil); err != nil {
+		return fmt.Errorf("failed to create self-signed certificates, %v", err)
+	}
+
+	// the requests are delegated to the managed cluster
+	if len(o.Authentication.RemoteKubeConfigFile) == 0 {
+		o.Authentication.RemoteKubeConfigFile = o.CommonOpts.SpokeKubeconfigFile
+	}
+	if len(o.Authorization.RemoteKubeConfigFile) == 0 {
+		o.Authorization.RemoteKubeConfigFile = o.CommonOpts.SpokeKubeconfigFile
+	}
+
+	var servingInfo *genericserver.SecureServingInfo
+	if err := o.SecureServing.ApplyTo(&servingInfo); err != nil {
+		return err
+	}
+	authenticationInfo := &genericserver.AuthenticationInfo{}
+	if err := o.Authentication.ApplyTo(authenticationInfo, servingInfo, nil); err != nil {
+		return err
+	}
+	authorizationInfo := &genericserver.AuthorizationInfo{}
+	if err := o.Authorization.ApplyTo(authorizationInfo); err != nil {
+		return err
+	}
+
+	pathMux := mux.NewPathRecorderMux("controlplane-agent")
+	healthz.InstallHandler(pathMux, livenessChecks...)
+	healthz.InstallLivezHandler(pathMux, livenessChecks...)
+	healthz.InstallReadyzHandler(pathMux, readinessChecks...)
+	// the workqueue metrics of the agents may be registered to the controller-runtime registry, gather them
+	// together with the metrics of the legacy registry.
+	pathMux.Handle("/metrics", promhttp.HandlerFor(
+		prometheus.Gatherers{legacyregistry.DefaultGatherer, ctrlmetrics.Registry},
+		promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError},
+	))
+
+	handler := controllermanagerapp.BuildHandlerChain(pathMux, authorizationInfo, authenticationInfo)
+	stoppedCh, _, err := servingInfo.Serve(handler, 0, ctx.Done())
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("serving the agent health and metrics on %s", servingInfo.Listener.Addr())
+	<-stoppedCh
+	return nil
+}
+
+// hubKubeConfigCheck returns an error until the hub kubeconfig is issued by the bootstrap hub and its client
+// certificate is in the validity period.
+func (o *AgentOptions) hubKubeConfigCheck() healthz.HealthChecker {
+	return healthz.NamedCheck("hub-kubeconfig", func(_ *http.Request) error {
+		valid, err := o.isHubKubeConfigValid()
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return fmt.Errorf("the hub kubeconfig is not valid")
+		}
+		return nil
+	})
+}
+
+func (o *AgentOptions) isHubKubeConfigValid() (bool, error) {
+	hubKubeConfigFile := o.hubKubeConfigFile()
+	if _, err := os.Stat(hubKubeConfigFile); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	bootstrapKubeConfig, err := clientcmd.LoadFromFile(o.RegistrationAgentOpts.BootstrapKubeconfig)
+	if err != nil {
+		return false, err
+	}
+	hubKubeConfig, err := clientcmd.LoadFromFile(hubKubeConfigFile)
+	if err != nil {
+		return false, err
+	}
+	if valid, err := register.IsHubKubeconfigValid(bootstrapKubeConfig, hubKubeConfig); !valid || err != nil {
+		return valid, err
+	}
+
+	certFile := filepath.Join(o.CommonOpts.HubKubeconfigDir, corev1.TLSCertKey)
+	certs, err := certutil.CertsFromFile(certFile)
+	if err != nil {
+		return false, nil
+	}
+	now := time.Now()
+	for _, cert := range certs {
+		if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			return false, nil
+		}
+	}
+
+	// the certificate and the key are written one by one when the certificate is rotated, they may not match
+	// for a while
+	if _, err := tls.LoadX509KeyPair(certFile, filepath.Join(o.CommonOpts.HubKubeconfigDir, corev1.TLSPrivateKeyKey)); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func newSecureServingOptions() *genericoptions.SecureServingOptions {
+	secureServing := genericoptions.NewSecureServingOptions()
+	// the server is disabled by default, so the existing agents do not bind a new port
+	secureServing.BindPort = 0
+	// the default cert dir is relative to the working dir, it is defaulted to the agent config dir once the
+	// server is started
+	secureServing.ServerCert.CertDirectory = ""
+	return secureServing
+}
+
+func newDelegatingAuthenticationOptions() *genericoptions.DelegatingAuthenticationOptions {
+	authentication := genericoptions.NewDelegatingAuthenticationOptions()
+	authentication.RemoteKubeConfigFileOptional = true
+	// the agent may have no permission to read the client CA of the managed cluster
+	authentication.TolerateInClusterLookupFailure = true
+	return authentication
+}
+
+func newDelegatingAuthorizationOptions() *genericoptions.DelegatingAuthorizationOptions {
+	authorization := genericoptions.NewDelegatingAuthorizationOptions()
+	authorization.RemoteKubeConfigFileOptional = true
+	authorization.AlwaysAllowPaths = alwaysAllowPaths
+	return authorization
+}
@@ -0,0 +1,191 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"open-cluster-management.io/ocm/pkg/registration/register"
+)
+
+// newCertKeyPEM returns a self-signed client certificate and its key that are valid in the given period
+func newCertKeyPEM(t *testing.T, notBefore, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "system:open-cluster-management:cluster1:agent1"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "system:open-cluster-management:cluster1:agent1"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeKubeConfig(t *testing.T, file, server string) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["hub"] = &clientcmdapi.Cluster{Server: server}
+	config.AuthInfos["default-auth"] = &clientcmdapi.AuthInfo{
+		ClientCertificate: corev1.TLSCertKey,
+		ClientKey:         corev1.TLSPrivateKeyKey,
+	}
+	config.Contexts["default-context"] = &clientcmdapi.Context{Cluster: "hub", AuthInfo: "default-auth"}
+	config.CurrentContext = "default-context"
+	if err := clientcmd.WriteToFile(*config, file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, file string, data []byte) {
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLivenessCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkers []healthz.HealthChecker
+		wantErr  bool
+	}{
+		{
+			name: "the agent is not started",
+		},
+		{
+			name:     "healthy",
+			checkers: []healthz.HealthChecker{healthz.PingHealthz},
+		},
+		{
+			name: "unhealthy",
+			checkers: []healthz.HealthChecker{
+				healthz.PingHealthz,
+				healthz.NamedCheck("hub-client-certificate", func(_ *http.Request) error {
+					return fmt.Errorf("the client certificate is expired")
+				}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := &agentHealth{}
+			health.setCheckers(tt.checkers)
+			if err := health.livenessCheck().Check(nil); (err != nil) != tt.wantErr {
+				t.Errorf("livenessCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsHubKubeConfigValid(t *testing.T) {
+	now := time.Now()
+	validCert, validKey := newCertKeyPEM(t, now.Add(-time.Hour), now.Add(time.Hour))
+	expiredCert, expiredKey := newCertKeyPEM(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	notYetValidCert, notYetValidKey := newCertKeyPEM(t, now.Add(time.Hour), now.Add(2*time.Hour))
+
+	tests := []struct {
+		name        string
+		hubServer   string
+		noHubConfig bool
+		cert        []byte
+		key         []byte
+		want        bool
+	}{
+		{
+			name:        "the hub kubeconfig is not issued",
+			noHubConfig: true,
+		},
+		{
+			name:      "valid",
+			hubServer: "https://hub:6443",
+			cert:      validCert,
+			key:       validKey,
+			want:      true,
+		},
+		{
+			name:      "the hub kubeconfig is issued by another hub",
+			hubServer: "https://another-hub:6443",
+			cert:      validCert,
+			key:       validKey,
+		},
+		{
+			name:      "the client certificate is expired",
+			hubServer: "https://hub:6443",
+			cert:      expiredCert,
+			key:       expiredKey,
+		},
+		{
+			name:      "the client certificate is not yet valid",
+			hubServer: "https://hub:6443",
+			cert:      notYetValidCert,
+			key:       notYetValidKey,
+		},
+		{
+			name:      "the client certificate does not match the key",
+			hubServer: "https://hub:6443",
+			cert:      validCert,
+			key:       expiredKey,
+		},
+		{
+			name:      "the client certificate is not written",
+			hubServer: "https://hub:6443",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			hubKubeconfigDir := filepath.Join(dir, "hub-kubeconfig")
+			if err := os.MkdirAll(hubKubeconfigDir, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			bootstrapKubeconfig := filepath.Join(dir, "bootstrap-kubeconfig")
+			writeKubeConfig(t, bootstrapKubeconfig, "https://hub:6443")
+			if !tt.noHubConfig {
+				writeKubeConfig(t, filepath.Join(hubKubeconfigDir, register.KubeconfigFile), tt.hubServer)
+			}
+			if tt.cert != nil {
+				writeFile(t, filepath.Join(hubKubeconfigDir, corev1.TLSCertKey), tt.cert)
+				writeFile(t, filepath.Join(hubKubeconfigDir, corev1.TLSPrivateKeyKey), tt.key)
+			}
+
+			o := NewAgentOptions().WithBootstrapKubeconfig(bootstrapKubeconfig).WithHubKubeconfigDir(hubKubeconfigDir)
+			valid, err := o.isHubKubeConfigValid()
+			if err != nil {
+				t.Fatalf("isHubKubeConfigValid() error = %v", err)
+			}
+			if valid != tt.want {
+				t.Errorf("isHubKubeConfigValid() = %v, want %v", valid, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+)
+
+func (o *AgentOptions) validateHubTransport() error {
+	if len(o.ProxyURL) != 0 {
+		proxyURL, err := url.Parse(o.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("the proxy url %q is invalid, %v", o.ProxyURL, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("the scheme of the proxy url %q should be one of http, https and socks5", o.ProxyURL)
+		}
+	}
+
+	if len(o.NoProxy) != 0 && len(o.ProxyURL) == 0 {
+		return fmt.Errorf("the no proxy list requires the proxy url")
+	}
+
+	// the gRPC client of the work agent has no proxy config, the gRPC server must not be connected directly if it
+	// is expected to be connected with the proxy url
+	if len(o.ProxyURL) != 0 && len(o.GRPCServerAddress) != 0 {
+		proxyURL, err := o.hubProxyURL(fmt.Sprintf("https://%s", o.GRPCServerAddress))
+		if err != nil {
+			return err
+		}
+		if proxyURL != nil {
+			return fmt.Errorf("the gRPC server %s cannot be connected with the proxy url, add it to the no proxy "+
+				"list or use the HTTPS_PROXY environment variable instead of the proxy url", o.GRPCServerAddress)
+		}
+	}
+
+	for _, file := range o.CABundleFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read the CA bundle file %q, %v", file, err)
+		}
+		if _, err := certutil.ParseCertsPEM(data); err != nil {
+			return fmt.Errorf("the CA bundle file %q is invalid, %v", file, err)
+		}
+	}
+	return nil
+}
+
+// hubProxyURL returns the proxy url of the hub server, nil is returned if the proxy url is not set or the server
+// is in the no proxy list
+func (o *AgentOptions) hubProxyURL(server string) (*url.URL, error) {
+	if len(o.ProxyURL) == 0 {
+		return nil, nil
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("the hub server %q is invalid, %v", server, err)
+	}
+
+	proxyConfig := &httpproxy.Config{
+		HTTPProxy:  o.ProxyURL,
+		HTTPSProxy: o.ProxyURL,
+		NoProxy:    strings.Join(o.NoProxy, ","),
+	}
+	return proxyConfig.ProxyFunc()(serverURL)
+}
+
+// hubCABundle appends the additional CA bundles to the CA of the hub
+func (o *AgentOptions) hubCABundle(caData []byte, caFile string) ([]byte, error) {
+	if len(caData) == 0 && len(caFile) != 0 {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file %q, %v", caFile, err)
+		}
+		caData = data
+	}
+
+	bundle := bytes.TrimSpace(caData)
+	for _, file := range o.CABundleFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA bundle file %q, %v", file, err)
+		}
+		data = bytes.TrimSpace(data)
+		// the bootstrap kubeconfig may be prepared more than once
+		if bytes.Contains(bundle, data) {
+			continue
+		}
+		if len(bundle) != 0 {
+			bundle = append(bundle, '\n')
+		}
+		bundle = append(bundle, data...)
+	}
+	return append(bundle, '\n'), nil
+}
+
+// withHubTransport returns a copy of the hub client config with the proxy url and the additional CA bundles, the
+// proxy of the config is kept if the proxy url is not set.
+func (o *AgentOptions) withHubTransport(config *rest.Config) (*rest.Config, error) {
+	config = rest.CopyConfig(config)
+
+	if len(o.ProxyURL) != 0 {
+		proxyURL, err := o.hubProxyURL(config.Host)
+		if err != nil {
+			return nil, err
+		}
+		config.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if len(o.CABundleFiles) != 0 {
+		caData, err := o.hubCABundle(config.TLSClientConfig.CAData, config.TLSClientConfig.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.TLSClientConfig.CAData = caData
+		config.TLSClientConfig.CAFile = ""
+	}
+	return config, nil
+}
+
+// hubKubeConfig returns the client config of the hub with the hub kubeconfig that is issued by the registration
+// agent
+func (o *AgentOptions) hubKubeConfig() (*rest.Config, error) {
+	hubKubeConfig, err := clientcmd.BuildConfigFromFlags("", o.hubKubeConfigFile())
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig from file %q: %v", o.hubKubeConfigFile(), err)
+	}
+	return o.withHubTransport(hubKubeConfig)
+}
+
+// prepareBootstrapTransport writes the proxy url and the additional CA bundles to the bootstrap kubeconfig, the
+// registration agent persists them into the hub kubeconfig that is generated from the bootstrap kubeconfig, so
+// the proxy url and the CA bundles are used by all of the hub clients of the agents.
+func (o *AgentOptions) prepareBootstrapTransport() error {
+	if len(o.ProxyURL) == 0 && len(o.CABundleFiles) == 0 {
+		return nil
+	}
+	if err := o.validateHubTransport(); err != nil {
+		return err
+	}
+
+	bootstrapKubeconfig := o.RegistrationAgentOpts.BootstrapKubeconfig
+	bootstrapConfig, err := clientcmd.LoadFromFile(bootstrapKubeconfig)
+	if err != nil {
+		return fmt.Errorf("unable to load bootstrap kubeconfig from file %q: %v", bootstrapKubeconfig, err)
+	}
+
+	kubeConfigCtx, ok := bootstrapConfig.Contexts[bootstrapConfig.CurrentContext]
+	if !ok {
+		return fmt.Errorf("the current context of the bootstrap kubeconfig %q is not found", bootstrapKubeconfig)
+	}
+	cluster, ok := bootstrapConfig.Clusters[kubeConfigCtx.Cluster]
+	if !ok {
+		return fmt.Errorf("the cluster of the bootstrap kubeconfig %q is not found", bootstrapKubeconfig)
+	}
+
+	if len(o.ProxyURL) != 0 {
+		proxyURL, err := o.hubProxyURL(cluster.Server)
+		if err != nil {
+			return err
+		}
+		cluster.ProxyURL = ""
+		if proxyURL != nil {
+			cluster.ProxyURL = proxyURL.String()
+		}
+	}
+
+	if len(o.CABundleFiles) != 0 {
+		// the CA file is relative to the bootstrap kubeconfig
+		caFile := cluster.CertificateAuthority
+		if len(caFile) != 0 && !filepath.IsAbs(caFile) {
+			caFile = filepath.Join(filepath.Dir(bootstrapKubeconfig), caFile)
+		}
+		caData, err := o.hubCABundle(cluster.CertificateAuthorityData, caFile)
+		if err != nil {
+			return err
+		}
+		cluster.CertificateAuthorityData = caData
+		cluster.CertificateAuthority = ""
+	}
+
+	// the auth files are relative to the bootstrap kubeconfig, they are resolved before the kubeconfig is moved
+	if err := clientcmd.ResolveLocalPaths(bootstrapConfig); err != nil {
+		return err
+	}
+
+	configDir, err := agentConfigDir(o.configSubDir)
+	if err != nil {
+		return err
+	}
+	transportKubeconfig := filepath.Join(configDir, "bootstrap-transport.kubeconfig")
+	if err := clientcmd.WriteToFile(*bootstrapConfig, transportKubeconfig); err != nil {
+		return fmt.Errorf("failed to write file %q, %v", transportKubeconfig, err)
+	}
+
+	klog.Infof("the proxy url %q and %d additional CA bundles are used to connect to the controlplane %s",
+		cluster.ProxyURL, len(o.CABundleFiles), cluster.Server)
+	o.RegistrationAgentOpts.BootstrapKubeconfig = transportKubeconfig
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestValidateHubTransport(t *testing.T) {
+	invalidCAFile := filepath.Join(t.TempDir(), "invalid-ca.crt")
+	if err := os.WriteFile(invalidCAFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		proxyURL          string
+		noProxy           []string
+		caBundleFiles     []string
+		grpcServerAddress string
+		wantErr           bool
+	}{
+		{
+			name: "no proxy url",
+		},
+		{
+			name:     "http proxy",
+			proxyURL: "http://proxy.example.com:3128",
+		},
+		{
+			name:     "socks5 proxy",
+			proxyURL: "socks5://proxy.example.com:1080",
+		},
+		{
+			name:     "unsupported proxy scheme",
+			proxyURL: "ftp://proxy.example.com",
+			wantErr:  true,
+		},
+		{
+			name:    "no proxy list without proxy url",
+			noProxy: []string{"controlplane.example.com"},
+			wantErr: true,
+		},
+		{
+			name:          "invalid CA bundle",
+			caBundleFiles: []string{invalidCAFile},
+			wantErr:       true,
+		},
+		{
+			name:              "gRPC server without proxy url",
+			grpcServerAddress: "controlplane.example.com:8090",
+		},
+		{
+			name:              "gRPC server with proxy url",
+			proxyURL:          "http://proxy.example.com:3128",
+			grpcServerAddress: "controlplane.example.com:8090",
+			wantErr:           true,
+		},
+		{
+			name:              "gRPC server in the no proxy list",
+			proxyURL:          "http://proxy.example.com:3128",
+			noProxy:           []string{".example.com"},
+			grpcServerAddress: "controlplane.example.com:8090",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &AgentOptions{
+				ProxyURL:          tt.proxyURL,
+				NoProxy:           tt.noProxy,
+				CABundleFiles:     tt.caBundleFiles,
+				GRPCServerAddress: tt.grpcServerAddress,
+			}
+			err := o.validateHubTransport()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateHubTransport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHubProxyURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxyURL string
+		noProxy  []string
+		server   string
+		want     string
+	}{
+		{
+			name:   "no proxy url",
+			server: "https://controlplane.example.com:443",
+		},
+		{
+			name:     "proxy url",
+			proxyURL: "http://proxy.example.com:3128",
+			server:   "https://controlplane.example.com:443",
+			want:     "http://proxy.example.com:3128",
+		},
+		{
+			name:     "domain in the no proxy list",
+			proxyURL: "http://proxy.example.com:3128",
+			noProxy:  []string{".example.com"},
+			server:   "https://controlplane.example.com:443",
+		},
+		{
+			name:     "CIDR in the no proxy list",
+			proxyURL: "http://proxy.example.com:3128",
+			noProxy:  []string{"10.0.0.0/8"},
+			server:   "https://10.0.0.1:443",
+		},
+		{
+			name:     "server is not in the no proxy list",
+			proxyURL: "http://proxy.example.com:3128",
+			noProxy:  []string{"10.0.0.0/8"},
+			server:   "https://controlplane.example.com:443",
+			want:     "http://proxy.example.com:3128",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &AgentOptions{ProxyURL: tt.proxyURL, NoProxy: tt.noProxy}
+			proxyURL, err := o.hubProxyURL(tt.server)
+			if err != nil {
+				t.Fatalf("hubProxyURL() error = %v", err)
+			}
+
+			got := ""
+			if proxyURL != nil {
+				got = proxyURL.String()
+			}
+			if got != tt.want {
+				t.Errorf("hubProxyURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithHubTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxyURL  string
+		noProxy   []string
+		wantProxy bool
+	}{
+		{
+			name: "no proxy url",
+		},
+		{
+			name:      "proxy url",
+			proxyURL:  "http://proxy.example.com:3128",
+			wantProxy: true,
+		},
+		{
+			name:     "server in the no proxy list",
+			proxyURL: "http://proxy.example.com:3128",
+			noProxy:  []string{"controlplane.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &AgentOptions{ProxyURL: tt.proxyURL, NoProxy: tt.noProxy}
+			config, err := o.withHubTransport(&rest.Config{Host: "https://controlplane.example.com:443"})
+			if err != nil {
+				t.Fatalf("withHubTransport() error = %v", err)
+			}
+
+			hasProxy := false
+			if config.Proxy != nil {
+				req, err := http.NewRequest(http.MethodGet, config.Host, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				proxyURL, err := config.Proxy(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				hasProxy = proxyURL != nil
+			}
+			if hasProxy != tt.wantProxy {
+				t.Errorf("withHubTransport() proxy = %v, want %v", hasProxy, tt.wantProxy)
+			}
+		})
+	}
+}
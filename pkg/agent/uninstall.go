@@ -0,0 +1,347 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	clusterv1client "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned"
+	"open-cluster-management.io/ocm/pkg/registration/register"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent/clusterclaims"
+)
+
+const (
+	// AppliedResourcesOrphan keeps the resources that are applied by the ManifestWorks on the managed cluster
+	AppliedResourcesOrphan = "orphan"
+	// AppliedResourcesDelete deletes the resources that are applied by the ManifestWorks from the managed cluster,
+	// the resources that are orphaned by the delete option of the ManifestWorks are kept.
+	AppliedResourcesDelete = "delete"
+
+	// ManagedClusterConditionLeaving is the condition of the ManagedCluster that is set by the agent when it is
+	// uninstalled from the managed cluster, it is informational only, no controller of the controlplane acts on
+	// it, the controlplane admin detaches the cluster by deleting the ManagedCluster.
+	ManagedClusterConditionLeaving = "ManagedClusterLeaving"
+
+	clusterClaimCRDName = "clusterclaims.cluster.open-cluster-management.io"
+
+	uninstallTimeout = 5 * time.Minute
+)
+
+// UninstallOptions removes the agent from the managed cluster, it removes the AppliedManifestWorks, the agent
+// CRDs and the hub kubeconfig secret, and marks the ManagedCluster as leaving on the controlplane if the agent
+// still has the hub credentials.
+type UninstallOptions struct {
+	*AgentOptions
+
+	// AppliedResources is the policy of the resources that are applied by the ManifestWorks, orphan or delete
+	AppliedResources string
+}
+
+func NewUninstallOptions() *UninstallOptions {
+	return &UninstallOptions{
+		AgentOptions:     NewAgentOptions(),
+		AppliedResources: AppliedResourcesOrphan,
+	}
+}
+
+func (o *UninstallOptions) AddFlags(fs *pflag.FlagSet) {
+	o.AgentOptions.AddFlags(fs)
+	fs.StringVar(&o.AppliedResources, "applied-resources", o.AppliedResources,
+		"The policy of the resources that are applied by the ManifestWorks, 'orphan' keeps them on the managed "+
+			"cluster and 'delete' deletes them")
+}
+
+func (o *UninstallOptions) Validate() error {
+	switch o.AppliedResources {
+	case AppliedResourcesOrphan, AppliedResourcesDelete:
+	default:
+		return fmt.Errorf("unsupported applied resources policy %q, it should be %s or %s",
+			o.AppliedResources, AppliedResourcesOrphan, AppliedResourcesDelete)
+	}
+	return validateCRDInstallMode(o.CRDInstallMode)
+}
+
+// Uninstall removes the agent from the managed cluster
+func (o *UninstallOptions) Uninstall(ctx context.Context) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	managementKubeConfig, err := o.inClusterKubeConfig()
+	if err != nil {
+		return err
+	}
+	spokeKubeConfig, err := o.CommonOpts.SpokeKubeConfig(managementKubeConfig)
+	if err != nil {
+		return err
+	}
+	managementKubeClient, err := kubernetes.NewForConfig(managementKubeConfig)
+	if err != nil {
+		return err
+	}
+
+	// the hub credentials are loaded before the hub kubeconfig secret is removed
+	if err := o.markClusterLeaving(ctx, managementKubeClient); err != nil {
+		klog.Warningf("failed to mark the managed cluster as leaving, %v", err)
+	}
+
+	spokeWorkClient, err := workv1client.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return err
+	}
+	if err := o.removeAppliedManifestWorks(ctx, spokeWorkClient); err != nil {
+		return err
+	}
+
+	// the cluster claims that are collected by the agent are removed, the other claims and their CRD are kept
+	spokeClusterClient, err := clusterv1client.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return err
+	}
+	if err := clusterclaims.RemoveClaims(ctx, spokeClusterClient); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove the cluster claims, %v", err)
+	}
+
+	// the CRDs are owned by the agent only if they are applied by the agent
+	if o.CRDInstallMode == CRDInstallModeApply {
+		apiExtensionsClient, err := apiextensionsclient.NewForConfig(spokeKubeConfig)
+		if err != nil {
+			return err
+		}
+		if err := removeCRDs(ctx, apiExtensionsClient, spokeClusterClient); err != nil {
+			return err
+		}
+	}
+
+	// the cluster name is read before the hub kubeconfig is removed
+	if clusterName, err := o.registeredClusterName(); err == nil {
+		if err := o.removeWorkCache(ctx, managementKubeClient, clusterName); err != nil {
+			return fmt.Errorf("failed to remove the persisted ManifestWorks, %v", err)
+		}
+	}
+
+	if err := o.removeHubKubeconfig(ctx, managementKubeClient); err != nil {
+		return err
+	}
+
+	klog.Infof("the agent is uninstalled from the cluster %q", o.CommonOpts.SpokeClusterName)
+	return nil
+}
+
+// appliedResourcesAction returns what happens to the applied resources for the messages
+func (o *UninstallOptions) appliedResourcesAction() string {
+	if o.AppliedResources == AppliedResourcesDelete {
+		return "deleted"
+	}
+	return "orphaned"
+}
+
+// markClusterLeaving sets the leaving condition on the ManagedCluster with the hub kubeconfig of the cluster
+func (o *UninstallOptions) markClusterLeaving(ctx context.Context, managementKubeClient kubernetes.Interface) error {
+	hubKubeConfig, clusterName, err := o.loadHubKubeConfig(ctx, managementKubeClient)
+	if err != nil {
+		return err
+	}
+	if hubKubeConfig == nil {
+		klog.Info("the hub credentials are not found, the managed cluster is not marked as leaving")
+		return nil
+	}
+
+	hubKubeConfig, err = o.withHubTransport(hubKubeConfig)
+	if err != nil {
+		return err
+	}
+	hubClusterClient, err := clusterv1client.NewForConfig(hubKubeConfig)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := hubClusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+		Type:   ManagedClusterConditionLeaving,
+		Status: metav1.ConditionTrue,
+		Reason: "AgentUninstalled",
+		Message: fmt.Sprintf("The agent is uninstalled from the managed cluster, the applied resources are %s",
+			o.appliedResourcesAction()),
+	})
+	if _, err := hubClusterClient.ClusterV1().ManagedClusters().UpdateStatus(ctx, cluster, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	klog.Infof("the managed cluster %q is marked as leaving", clusterName)
+	return nil
+}
+
+// loadHubKubeConfig loads the hub kubeconfig and the cluster name from the hub kubeconfig dir, or from the hub
+// kubeconfig secret if the dir is not available, the hub kubeconfig is nil if the cluster is not registered.
+func (o *UninstallOptions) loadHubKubeConfig(ctx context.Context,
+	managementKubeClient kubernetes.Interface) (*rest.Config, string, error) {
+	if valid, err := o.isHubKubeConfigValid(); err == nil && valid {
+		clusterName, err := o.registeredClusterName()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read the cluster name, %v", err)
+		}
+		hubKubeConfig, err := clientcmd.BuildConfigFromFlags("", o.hubKubeConfigFile())
+		return hubKubeConfig, clusterName, err
+	}
+
+	secret, err := managementKubeClient.CoreV1().Secrets(o.CommonOpts.ComponentNamespace).Get(
+		ctx, o.RegistrationAgentOpts.HubKubeconfigSecret, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	kubeconfigData, ok := secret.Data[register.KubeconfigFile]
+	if !ok {
+		return nil, "", nil
+	}
+	config, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the kubeconfig in the secret refers to the client certificate files in the same secret, so the files are
+	// replaced with the data before the kubeconfig is loaded
+	for _, authInfo := range config.AuthInfos {
+		authInfo.ClientCertificate = ""
+		authInfo.ClientKey = ""
+		authInfo.ClientCertificateData = secret.Data[corev1.TLSCertKey]
+		authInfo.ClientKeyData = secret.Data[corev1.TLSPrivateKeyKey]
+	}
+	hubKubeConfig, err := clientcmd.NewDefaultClientConfig(*config, nil).ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	clusterName := o.CommonOpts.SpokeClusterName
+	if len(clusterName) == 0 {
+		clusterName = string(secret.Data[register.ClusterNameFile])
+	}
+	return hubKubeConfig, clusterName, nil
+}
+
+// removeAppliedManifestWorks removes the finalizers of the AppliedManifestWorks, which are handled by the
+// work agent, and deletes them, the applied resources are owned by the AppliedManifestWorks, so they are
+// orphaned or deleted by the garbage collector of the managed cluster.
+func (o *UninstallOptions) removeAppliedManifestWorks(ctx context.Context, spokeWorkClient workv1client.Interface) error {
+	appliedManifestWorks, err := spokeWorkClient.WorkV1().AppliedManifestWorks().List(ctx, metav1.ListOptions{})
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	propagationPolicy := metav1.DeletePropagationOrphan
+	if o.AppliedResources == AppliedResourcesDelete {
+		propagationPolicy = metav1.DeletePropagationBackground
+	}
+
+	for _, appliedManifestWork := range appliedManifestWorks.Items {
+		if len(appliedManifestWork.Finalizers) != 0 {
+			patch := []byte(fmt.Sprintf(`{"metadata":{"finalizers":null,"resourceVersion":%q}}`,
+				appliedManifestWork.ResourceVersion))
+			if _, err := spokeWorkClient.WorkV1().AppliedManifestWorks().Patch(
+				ctx, appliedManifestWork.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return fmt.Errorf("failed to remove the finalizers of the AppliedManifestWork %s, %v",
+					appliedManifestWork.Name, err)
+			}
+		}
+
+		if err := spokeWorkClient.WorkV1().AppliedManifestWorks().Delete(ctx, appliedManifestWork.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagationPolicy,
+		}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete the AppliedManifestWork %s, %v", appliedManifestWork.Name, err)
+		}
+
+		klog.Infof("the AppliedManifestWork %s is deleted, its applied resources are %s",
+			appliedManifestWork.Name, o.appliedResourcesAction())
+	}
+
+	// the resources are orphaned by the garbage collector before the AppliedManifestWorks are deleted
+	return wait.PollUntilContextTimeout(ctx, time.Second, uninstallTimeout, true, func(ctx context.Context) (bool, error) {
+		appliedManifestWorks, err := spokeWorkClient.WorkV1().AppliedManifestWorks().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		return len(appliedManifestWorks.Items) == 0, nil
+	})
+}
+
+// removeCRDs deletes the agent CRDs, the custom resources are deleted with them, so the ClusterClaim CRD is kept
+// if there are still ClusterClaims that are not collected by the agent.
+func removeCRDs(ctx context.Context, client apiextensionsclient.Interface, clusterClient clusterv1client.Interface) error {
+	requiredCRDs, err := loadCRDs()
+	if err != nil {
+		return err
+	}
+
+	for _, crd := range requiredCRDs {
+		if crd.Name == clusterClaimCRDName {
+			claims, err := clusterClient.ClusterV1alpha1().ClusterClaims().List(ctx, metav1.ListOptions{})
+			if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+				return fmt.Errorf("failed to list the cluster claims, %v", err)
+			}
+			if err == nil && len(claims.Items) != 0 {
+				klog.Infof("the crd %s is kept, there are %d cluster claims that are not collected by the agent",
+					crd.Name, len(claims.Items))
+				continue
+			}
+		}
+
+		err := client.ApiextensionsV1().CustomResourceDefinitions().Delete(ctx, crd.Name, metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete the crd %s, %v", crd.Name, err)
+		}
+		klog.Infof("the crd %s is deleted", crd.Name)
+	}
+	return nil
+}
+
+// removeHubKubeconfig deletes the hub kubeconfig secret and the files in the hub kubeconfig dir
+func (o *UninstallOptions) removeHubKubeconfig(ctx context.Context, managementKubeClient kubernetes.Interface) error {
+	err := managementKubeClient.CoreV1().Secrets(o.CommonOpts.ComponentNamespace).Delete(
+		ctx, o.RegistrationAgentOpts.HubKubeconfigSecret, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the hub kubeconfig secret %s/%s, %v",
+			o.CommonOpts.ComponentNamespace, o.RegistrationAgentOpts.HubKubeconfigSecret, err)
+	}
+
+	for _, file := range []string{register.KubeconfigFile, corev1.TLSCertKey, corev1.TLSPrivateKeyKey,
+		register.ClusterNameFile, register.AgentNameFile} {
+		if err := os.Remove(filepath.Join(o.CommonOpts.HubKubeconfigDir, file)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/ocm/pkg/registration/register"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent/clusterclaims"
+)
+
+func TestRemoveAppliedManifestWorks(t *testing.T) {
+	appliedManifestWorks := []runtime.Object{
+		&workv1.AppliedManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "work1",
+				Finalizers: []string{workv1.AppliedManifestWorkFinalizer},
+			},
+		},
+		&workv1.AppliedManifestWork{ObjectMeta: metav1.ObjectMeta{Name: "work2"}},
+	}
+
+	tests := []struct {
+		name                 string
+		appliedManifestWorks []runtime.Object
+		appliedResources     string
+		wantPropagation      metav1.DeletionPropagation
+		wantPatchedWorks     []string
+		wantDeletedWorks     []string
+	}{
+		{
+			name:             "no applied manifest works",
+			appliedResources: AppliedResourcesOrphan,
+			wantPatchedWorks: []string{},
+			wantDeletedWorks: []string{},
+		},
+		{
+			name:                 "orphan the applied resources",
+			appliedResources:     AppliedResourcesOrphan,
+			appliedManifestWorks: appliedManifestWorks,
+			wantPropagation:      metav1.DeletePropagationOrphan,
+			wantPatchedWorks:     []string{"work1"},
+			wantDeletedWorks:     []string{"work1", "work2"},
+		},
+		{
+			name:                 "delete the applied resources",
+			appliedResources:     AppliedResourcesDelete,
+			appliedManifestWorks: appliedManifestWorks,
+			wantPropagation:      metav1.DeletePropagationBackground,
+			wantPatchedWorks:     []string{"work1"},
+			wantDeletedWorks:     []string{"work1", "work2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []runtime.Object{}
+			for _, obj := range tt.appliedManifestWorks {
+				objs = append(objs, obj.DeepCopyObject())
+			}
+			workClient := workfake.NewSimpleClientset(objs...)
+
+			o := NewUninstallOptions()
+			o.AppliedResources = tt.appliedResources
+			if err := o.removeAppliedManifestWorks(context.TODO(), workClient); err != nil {
+				t.Fatalf("removeAppliedManifestWorks() error = %v", err)
+			}
+
+			patched, deleted := []string{}, []string{}
+			for _, action := range workClient.Actions() {
+				switch action := action.(type) {
+				case clienttesting.PatchActionImpl:
+					patched = append(patched, action.GetName())
+				case clienttesting.DeleteActionImpl:
+					deleted = append(deleted, action.GetName())
+					if policy := action.DeleteOptions.PropagationPolicy; policy == nil || *policy != tt.wantPropagation {
+						t.Errorf("the AppliedManifestWork %s is deleted with the propagation policy %v, want %v",
+							action.GetName(), policy, tt.wantPropagation)
+					}
+				}
+			}
+			if !reflect.DeepEqual(patched, tt.wantPatchedWorks) {
+				t.Errorf("patched AppliedManifestWorks = %v, want %v", patched, tt.wantPatchedWorks)
+			}
+			if !reflect.DeepEqual(deleted, tt.wantDeletedWorks) {
+				t.Errorf("deleted AppliedManifestWorks = %v, want %v", deleted, tt.wantDeletedWorks)
+			}
+		})
+	}
+}
+
+func TestLoadHubKubeConfig(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["hub"] = &clientcmdapi.Cluster{Server: "https://hub:6443"}
+	config.AuthInfos["default-auth"] = &clientcmdapi.AuthInfo{
+		ClientCertificate: corev1.TLSCertKey,
+		ClientKey:         corev1.TLSPrivateKeyKey,
+	}
+	config.Contexts["default-context"] = &clientcmdapi.Context{Cluster: "hub", AuthInfo: "default-auth"}
+	config.CurrentContext = "default-context"
+	kubeconfigData, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newSecret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "open-cluster-management-agent", Name: "hub-kubeconfig-secret"},
+			Data:       data,
+		}
+	}
+
+	tests := []struct {
+		name            string
+		clusterName     string
+		secrets         []runtime.Object
+		wantConfig      bool
+		wantClusterName string
+	}{
+		{
+			name: "the cluster is not registered",
+		},
+		{
+			name:    "the hub kubeconfig is not issued",
+			secrets: []runtime.Object{newSecret(map[string][]byte{register.ClusterNameFile: []byte("cluster1")})},
+		},
+		{
+			name: "the hub kubeconfig is loaded from the secret",
+			secrets: []runtime.Object{newSecret(map[string][]byte{
+				register.KubeconfigFile:  kubeconfigData,
+				register.ClusterNameFile: []byte("cluster1"),
+				corev1.TLSCertKey:        []byte("cert"),
+				corev1.TLSPrivateKeyKey:  []byte("key"),
+			})},
+			wantConfig:      true,
+			wantClusterName: "cluster1",
+		},
+		{
+			name:        "the cluster name is set",
+			clusterName: "cluster2",
+			secrets: []runtime.Object{newSecret(map[string][]byte{
+				register.KubeconfigFile:  kubeconfigData,
+				register.ClusterNameFile: []byte("cluster1"),
+				corev1.TLSCertKey:        []byte("cert"),
+				corev1.TLSPrivateKeyKey:  []byte("key"),
+			})},
+			wantConfig:      true,
+			wantClusterName: "cluster2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewUninstallOptions()
+			o.WithHubKubeconfigDir(t.TempDir())
+			o.CommonOpts.ComponentNamespace = "open-cluster-management-agent"
+			o.RegistrationAgentOpts.HubKubeconfigSecret = "hub-kubeconfig-secret"
+			o.CommonOpts.SpokeClusterName = tt.clusterName
+
+			hubKubeConfig, clusterName, err := o.loadHubKubeConfig(context.TODO(), kubefake.NewSimpleClientset(tt.secrets...))
+			if err != nil {
+				t.Fatalf("loadHubKubeConfig() error = %v", err)
+			}
+			if !tt.wantConfig {
+				if hubKubeConfig != nil {
+					t.Errorf("expected no hub kubeconfig, but got %v", hubKubeConfig)
+				}
+				return
+			}
+
+			if hubKubeConfig == nil {
+				t.Fatalf("expected the hub kubeconfig, but got nil")
+			}
+			if hubKubeConfig.Host != "https://hub:6443" {
+				t.Errorf("the hub host = %q, want %q", hubKubeConfig.Host, "https://hub:6443")
+			}
+			if len(hubKubeConfig.CertFile) != 0 || len(hubKubeConfig.KeyFile) != 0 {
+				t.Errorf("expected the client certificate from the secret, but got the files %q and %q",
+					hubKubeConfig.CertFile, hubKubeConfig.KeyFile)
+			}
+			if string(hubKubeConfig.CertData) != "cert" || string(hubKubeConfig.KeyData) != "key" {
+				t.Errorf("the client certificate is not loaded from the secret")
+			}
+			if clusterName != tt.wantClusterName {
+				t.Errorf("loadHubKubeConfig() cluster name = %q, want %q", clusterName, tt.wantClusterName)
+			}
+		})
+	}
+}
+
+func TestRemoveCRDs(t *testing.T) {
+	requiredCRDs, err := loadCRDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newClaim := func(name, collector string) *clusterv1alpha1.ClusterClaim {
+		claim := &clusterv1alpha1.ClusterClaim{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if len(collector) != 0 {
+			claim.Labels = map[string]string{clusterclaims.CollectorLabel: collector}
+		}
+		return claim
+	}
+
+	tests := []struct {
+		name              string
+		claims            []runtime.Object
+		wantClusterClaims bool
+	}{
+		{
+			name: "no cluster claims",
+		},
+		{
+			name:   "the cluster claims are collected by the agent",
+			claims: []runtime.Object{newClaim("platform.open-cluster-management.io", "platform")},
+		},
+		{
+			name: "the cluster claims are not collected by the agent",
+			claims: []runtime.Object{
+				newClaim("platform.open-cluster-management.io", "platform"),
+				newClaim("id.k8s.io", ""),
+			},
+			wantClusterClaims: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crds := []runtime.Object{}
+			for _, crd := range requiredCRDs {
+				crds = append(crds, crd)
+			}
+			apiExtensionsClient := apiextensionsfake.NewSimpleClientset(crds...)
+			clusterClient := clusterfake.NewSimpleClientset(tt.claims...)
+
+			if err := clusterclaims.RemoveClaims(context.TODO(), clusterClient); err != nil {
+				t.Fatal(err)
+			}
+			if err := removeCRDs(context.TODO(), apiExtensionsClient, clusterClient); err != nil {
+				t.Fatalf("removeCRDs() error = %v", err)
+			}
+
+			existing, err := apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().List(
+				context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, crd := range existing.Items {
+				names = append(names, crd.Name)
+			}
+			want := []string{}
+			if tt.wantClusterClaims {
+				want = append(want, clusterClaimCRDName)
+			}
+			if !reflect.DeepEqual(names, want) {
+				t.Errorf("the remaining crds = %v, want %v", names, want)
+			}
+		})
+	}
+}
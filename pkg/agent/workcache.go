@@ -0,0 +1,151 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/sdk-go/pkg/cloudevents/generic"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent/workcache"
+)
+
+const (
+	// WorkPersistenceNone does not persist the ManifestWorks
+	WorkPersistenceNone = "none"
+	// WorkPersistenceDisk persists the ManifestWorks to the files of the work persistence dir
+	WorkPersistenceDisk = "disk"
+	// WorkPersistenceSecret persists the ManifestWorks to the secrets of the component namespace
+	WorkPersistenceSecret = "secret"
+
+	defaultWorkPersistenceDir          = "/var/lib/multicluster-controlplane-agent/manifestworks"
+	defaultWorkPersistenceSyncInterval = 30 * time.Second
+	hubRequestTimeout                  = 10 * time.Second
+)
+
+func validateWorkPersistence(persistence string, syncInterval time.Duration) error {
+	switch persistence {
+	case WorkPersistenceNone, WorkPersistenceDisk, WorkPersistenceSecret:
+	default:
+		return fmt.Errorf("unsupported work persistence %q, it should be one of %s, %s and %s",
+			persistence, WorkPersistenceNone, WorkPersistenceDisk, WorkPersistenceSecret)
+	}
+	if syncInterval <= 0 {
+		return fmt.Errorf("the work persistence sync interval must be positive")
+	}
+	return nil
+}
+
+// runWorkCache waits for the cluster is registered, and then persists the ManifestWorks of the cluster, the
+// persisted ManifestWorks are enforced by the agent while the hub is unreachable.
+func (o *AgentOptions) runWorkCache(ctx context.Context, managementKubeConfig, spokeKubeConfig *rest.Config) {
+	var clusterName, hubHash string
+	if err := wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		var err error
+		if clusterName, err = o.registeredClusterName(); err != nil {
+			klog.V(4).Infof("the cluster name is not found, %v", err)
+			return false, nil
+		}
+		if hubHash, err = o.workHubHash(); err != nil {
+			klog.V(4).Infof("the work source config is not found, %v", err)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return
+	}
+
+	store, err := o.newWorkStore(managementKubeConfig, clusterName)
+	if err != nil {
+		klog.Errorf("failed to create the ManifestWork store, %v", err)
+		return
+	}
+
+	hubWorkClient := func() (workv1client.ManifestWorkInterface, error) {
+		hubKubeConfig, err := o.hubKubeConfig()
+		if err != nil {
+			return nil, err
+		}
+		hubKubeConfig.Timeout = hubRequestTimeout
+		client, err := workclientset.NewForConfig(hubKubeConfig)
+		if err != nil {
+			return nil, err
+		}
+		return client.WorkV1().ManifestWorks(clusterName), nil
+	}
+
+	cache, err := workcache.NewWorkCache(store, hubHash, hubWorkClient, spokeKubeConfig, o.eventRecorder,
+		o.WorkPersistenceSyncInterval)
+	if err != nil {
+		klog.Errorf("failed to create the ManifestWork cache, %v", err)
+		return
+	}
+
+	klog.Infof("persisting the ManifestWorks of the cluster %q to %s", clusterName, o.WorkPersistence)
+	cache.Run(ctx)
+}
+
+func (o *AgentOptions) newWorkStore(managementKubeConfig *rest.Config, clusterName string) (workcache.Store, error) {
+	if o.WorkPersistence == WorkPersistenceDisk {
+		return workcache.NewDiskStore(o.WorkPersistenceDir, clusterName)
+	}
+
+	managementKubeClient, err := kubernetes.NewForConfig(managementKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return workcache.NewSecretStore(managementKubeClient, o.CommonOpts.ComponentNamespace, clusterName), nil
+}
+
+// workHubHash returns the hub hash of the work agent, it is the hash of the hub host that the work agent
+// connects to, the AppliedManifestWorks are named with it.
+func (o *AgentOptions) workHubHash() (string, error) {
+	if o.WorkAgentOpts.WorkloadSourceDriver == "kube" {
+		config, err := clientcmd.BuildConfigFromFlags("", o.WorkAgentOpts.WorkloadSourceConfig)
+		if err != nil {
+			return "", err
+		}
+		return helper.HubHash(config.Host), nil
+	}
+
+	host, _, err := generic.NewConfigLoader(o.WorkAgentOpts.WorkloadSourceDriver, o.WorkAgentOpts.WorkloadSourceConfig).
+		LoadConfig()
+	if err != nil {
+		return "", err
+	}
+	return helper.HubHash(host), nil
+}
+
+// removeWorkCache removes the persisted ManifestWorks of the cluster
+func (o *AgentOptions) removeWorkCache(ctx context.Context, managementKubeClient kubernetes.Interface,
+	clusterName string) error {
+	secrets, err := managementKubeClient.CoreV1().Secrets(o.CommonOpts.ComponentNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", workcache.CacheLabel, clusterName),
+	})
+	if err != nil {
+		return err
+	}
+	for _, secret := range secrets.Items {
+		if err := managementKubeClient.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name,
+			metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if len(o.WorkPersistenceDir) != 0 && len(clusterName) != 0 {
+		return os.RemoveAll(filepath.Join(o.WorkPersistenceDir, clusterName))
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package workcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	workv1client "open-cluster-management.io/api/client/work/clientset/versioned/typed/work/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/ocm/pkg/work/helper"
+	"open-cluster-management.io/ocm/pkg/work/spoke/apply"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// ReasonAppliedOffline and ReasonAppliedFailedOffline are the reasons of the applied conditions that are set
+	// while the hub is unreachable
+	ReasonAppliedOffline       = "AppliedWhileHubUnreachable"
+	ReasonAppliedFailedOffline = "AppliedFailedWhileHubUnreachable"
+)
+
+// HubWorkClientFunc returns the ManifestWork client of the cluster namespace on the hub, the client is recreated in
+// each sync, since the hub kubeconfig is rotated by the registration agent
+type HubWorkClientFunc func() (workv1client.ManifestWorkInterface, error)
+
+// WorkCache persists the ManifestWorks of the cluster while the hub is reachable, and keeps enforcing the persisted
+// ManifestWorks on the managed cluster while the hub is unreachable, the status that is updated while the hub is
+// unreachable is flushed to the hub once the hub is reachable.
+//
+// Only the manifests that were applied by the work agent are enforced. The manifests are applied with the
+// credentials of the agent, so the ManifestWorks that have an executor are not enforced, otherwise the executor
+// permissions of the ManifestWorks would be bypassed.
+type WorkCache struct {
+	store         Store
+	hubHash       string
+	hubWorkClient HubWorkClientFunc
+
+	spokeWorkClient workclientset.Interface
+	appliers        *apply.Appliers
+	restMapper      meta.RESTMapper
+	recorder        events.Recorder
+
+	interval    time.Duration
+	unreachable bool
+}
+
+// NewWorkCache returns the cache of the ManifestWorks, the hub hash is the hash of the hub that the work agent
+// connects to, it is used to find the AppliedManifestWorks of the persisted ManifestWorks
+func NewWorkCache(store Store, hubHash string, hubWorkClient HubWorkClientFunc, spokeKubeConfig *rest.Config,
+	recorder events.Recorder, interval time.Duration) (*WorkCache, error) {
+	spokeDynamicClient, err := dynamic.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	spokeKubeClient, err := kubernetes.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	spokeAPIExtensionClient, err := apiextensionsclient.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	spokeWorkClient, err := workclientset.NewForConfig(spokeKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := rest.HTTPClientFor(spokeKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	restMapper, err := apiutil.NewDynamicRESTMapper(spokeKubeConfig, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkCache{
+		store:           store,
+		hubHash:         hubHash,
+		hubWorkClient:   hubWorkClient,
+		spokeWorkClient: spokeWorkClient,
+		appliers:        apply.NewAppliers(spokeDynamicClient, spokeKubeClient, spokeAPIExtensionClient),
+		restMapper:      restMapper,
+		recorder:        recorder.WithComponentSuffix("manifestwork-cache"),
+		interval:        interval,
+	}, nil
+}
+
+// Run syncs the ManifestWorks with the interval until the context is done
+func (c *WorkCache) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, c.sync, c.interval)
+}
+
+func (c *WorkCache) sync(ctx context.Context) {
+	hubWorks, hubWorkClient, err := c.listHubWorks(ctx)
+	if err != nil && !isUnreachable(err) {
+		klog.Errorf("failed to list the ManifestWorks on the hub, %v", err)
+		return
+	}
+
+	if err != nil {
+		if !c.unreachable {
+			klog.Warningf("the hub is unreachable, enforcing the persisted ManifestWorks, %v", err)
+			c.unreachable = true
+		}
+		if err := c.enforce(ctx); err != nil {
+			klog.Errorf("failed to enforce the persisted ManifestWorks, %v", err)
+		}
+		return
+	}
+
+	if c.unreachable {
+		klog.Info("the hub is reachable, flushing the status of the persisted ManifestWorks")
+		c.unreachable = false
+	}
+	if err := c.persist(ctx, hubWorkClient, hubWorks); err != nil {
+		klog.Errorf("failed to persist the ManifestWorks, %v", err)
+	}
+}
+
+func (c *WorkCache) listHubWorks(ctx context.Context) ([]workv1.ManifestWork, workv1client.ManifestWorkInterface, error) {
+	hubWorkClient, err := c.hubWorkClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	works, err := hubWorkClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return works.Items, hubWorkClient, nil
+}
+
+// persist flushes the pending status to the hub, and then persists the ManifestWorks on the hub and removes the
+// persisted ManifestWorks that are removed from the hub
+func (c *WorkCache) persist(ctx context.Context, hubWorkClient workv1client.ManifestWorkInterface,
+	hubWorks []workv1.ManifestWork) error {
+	cachedWorks, err := c.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	cached := map[string]*CachedWork{}
+	for _, cachedWork := range cachedWorks {
+		cached[cachedWork.Work.Name] = cachedWork
+	}
+
+	errs := []error{}
+	hubWorkNames := sets.New[string]()
+	for i := range hubWorks {
+		work := &hubWorks[i]
+		hubWorkNames.Insert(work.Name)
+
+		cachedWork, ok := cached[work.Name]
+		if ok && cachedWork.PendingStatus != nil {
+			updated, err := c.flush(ctx, hubWorkClient, work, cachedWork)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			work = updated
+		} else if ok && cachedWork.Work.ResourceVersion == work.ResourceVersion {
+			continue
+		}
+
+		work = work.DeepCopy()
+		work.ManagedFields = nil
+		if err := c.store.Save(ctx, &CachedWork{Work: work}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for name := range cached {
+		if hubWorkNames.Has(name) {
+			continue
+		}
+		if err := c.store.Delete(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// flush updates the applied conditions of the pending status to the ManifestWork on the hub, the pending status
+// is dropped if the ManifestWork is changed on the hub, e.g. its spec is changed or its status is already updated
+// by the work agent, since the pending status is stale
+func (c *WorkCache) flush(ctx context.Context, hubWorkClient workv1client.ManifestWorkInterface,
+	work *workv1.ManifestWork, cachedWork *CachedWork) (*workv1.ManifestWork, error) {
+	if work.ResourceVersion != cachedWork.Work.ResourceVersion {
+		klog.Infof("the ManifestWork %q is changed on the hub, the pending status is dropped", work.Name)
+		return work, nil
+	}
+
+	updated := work.DeepCopy()
+	if condition := meta.FindStatusCondition(cachedWork.PendingStatus.Conditions, workv1.WorkApplied); condition != nil {
+		meta.SetStatusCondition(&updated.Status.Conditions, *condition)
+	}
+	for _, pending := range cachedWork.PendingStatus.ResourceStatus.Manifests {
+		condition := meta.FindStatusCondition(pending.Conditions, workv1.ManifestApplied)
+		if condition == nil {
+			continue
+		}
+		for i, manifest := range updated.Status.ResourceStatus.Manifests {
+			if manifest.ResourceMeta == pending.ResourceMeta {
+				meta.SetStatusCondition(&updated.Status.ResourceStatus.Manifests[i].Conditions, *condition)
+			}
+		}
+	}
+
+	if equality.Semantic.DeepEqual(work.Status, updated.Status) {
+		return work, nil
+	}
+	updated, err := hubWorkClient.UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to flush the status of the ManifestWork %q, %v", work.Name, err)
+	}
+	klog.Infof("the pending status of the ManifestWork %q is flushed", work.Name)
+	return updated, nil
+}
+
+// enforce applies the persisted ManifestWorks on the managed cluster, and records the results to the pending status
+func (c *WorkCache) enforce(ctx context.Context) error {
+	cachedWorks, err := c.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, cachedWork := range cachedWorks {
+		status := cachedWork.PendingStatus
+		if status == nil {
+			status = &cachedWork.Work.Status
+		}
+
+		pendingStatus, err := c.enforceWork(ctx, cachedWork.Work, status)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if pendingStatus == nil || equality.Semantic.DeepEqual(cachedWork.PendingStatus, pendingStatus) {
+			continue
+		}
+
+		cachedWork.PendingStatus = pendingStatus
+		if err := c.store.Save(ctx, cachedWork); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enforceWork applies the manifests of the ManifestWork that were applied, and returns the status with the apply
+// results, nil is returned if the ManifestWork is not applied on the managed cluster
+func (c *WorkCache) enforceWork(ctx context.Context, work *workv1.ManifestWork,
+	status *workv1.ManifestWorkStatus) (*workv1.ManifestWorkStatus, error) {
+	if !work.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	// the permissions of the executor cannot be checked while the hub is unreachable
+	if work.Spec.Executor != nil {
+		return nil, nil
+	}
+
+	appliedWork, err := c.spokeWorkClient.WorkV1().AppliedManifestWorks().Get(
+		ctx, fmt.Sprintf("%s-%s", c.hubHash, work.Name), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	owner := helper.NewAppliedManifestWorkOwner(appliedWork)
+
+	pendingStatus := status.DeepCopy()
+	allApplied := true
+	for i, manifest := range pendingStatus.ResourceStatus.Manifests {
+		ordinal := int(manifest.ResourceMeta.Ordinal)
+		if !appliedBefore(work.Status, manifest.ResourceMeta) || ordinal >= len(work.Spec.Workload.Manifests) {
+			continue
+		}
+
+		condition := metav1.Condition{
+			Type:               workv1.ManifestApplied,
+			Status:             metav1.ConditionTrue,
+			Reason:             ReasonAppliedOffline,
+			Message:            "Apply manifest complete while the hub is unreachable",
+			ObservedGeneration: work.Generation,
+		}
+		if err := c.applyManifest(ctx, work, ordinal, *owner); err != nil {
+			allApplied = false
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = ReasonAppliedFailedOffline
+			condition.Message = fmt.Sprintf("Failed to apply manifest while the hub is unreachable: %v", err)
+		}
+		meta.SetStatusCondition(&pendingStatus.ResourceStatus.Manifests[i].Conditions, condition)
+	}
+
+	condition := metav1.Condition{
+		Type:               workv1.WorkApplied,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonAppliedOffline,
+		Message:            "Apply manifest work complete while the hub is unreachable",
+		ObservedGeneration: work.Generation,
+	}
+	if !allApplied {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ReasonAppliedFailedOffline
+		condition.Message = "Failed to apply manifest work while the hub is unreachable"
+	}
+	meta.SetStatusCondition(&pendingStatus.Conditions, condition)
+	return pendingStatus, nil
+}
+
+// applyManifest applies the manifest in the same way of the work agent
+func (c *WorkCache) applyManifest(ctx context.Context, work *workv1.ManifestWork, ordinal int,
+	owner metav1.OwnerReference) error {
+	required := &unstructured.Unstructured{}
+	if err := required.UnmarshalJSON(work.Spec.Workload.Manifests[ordinal].Raw); err != nil {
+		return err
+	}
+	required.SetUID("")
+
+	resourceMeta, gvr, err := helper.BuildResourceMeta(ordinal, required, c.restMapper)
+	if err != nil {
+		return err
+	}
+
+	// the owner is removed from the resource if the resource is orphaned by the delete option
+	if !helper.OwnedByTheWork(gvr, resourceMeta.Namespace, resourceMeta.Name, work.Spec.DeleteOption) {
+		owner = *owner.DeepCopy()
+		owner.UID = types.UID(fmt.Sprintf("%s-", owner.UID))
+	}
+
+	option := helper.FindManifestConfiguration(resourceMeta, work.Spec.ManifestConfigs)
+	strategy := workv1.UpdateStrategy{Type: workv1.UpdateStrategyTypeUpdate}
+	if option != nil && option.UpdateStrategy != nil {
+		strategy = *option.UpdateStrategy
+	}
+
+	_, err = c.appliers.GetApplier(strategy.Type).Apply(ctx, gvr, required, owner, option, c.recorder)
+	return err
+}
+
+// appliedBefore returns true if the manifest was applied by the work agent in the last known status of the hub
+func appliedBefore(status workv1.ManifestWorkStatus, resourceMeta workv1.ManifestResourceMeta) bool {
+	for _, manifest := range status.ResourceStatus.Manifests {
+		if manifest.ResourceMeta == resourceMeta {
+			return meta.IsStatusConditionTrue(manifest.Conditions, workv1.ManifestApplied)
+		}
+	}
+	return false
+}
+
+// isUnreachable returns true if the hub cannot be reached, e.g. the connection is refused or timed out, the other
+// errors, e.g. the hub kubeconfig is missing or corrupt, are not treated as the hub is unreachable
+func isUnreachable(err error) bool {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return apierrors.IsServerTimeout(err) || apierrors.IsServiceUnavailable(err) || apierrors.IsTimeout(err)
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || utilnet.IsConnectionRefused(err) ||
+		utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)
+}
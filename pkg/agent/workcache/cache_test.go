@@ -0,0 +1,379 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package workcache
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workv1 "open-cluster-management.io/api/work/v1"
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/work/spoke/apply"
+)
+
+// memoryStore persists the ManifestWorks in memory
+type memoryStore struct {
+	works map[string]*CachedWork
+	saved int
+}
+
+func newMemoryStore(works ...*CachedWork) *memoryStore {
+	s := &memoryStore{works: map[string]*CachedWork{}}
+	for _, work := range works {
+		s.works[work.Work.Name] = work
+	}
+	return s
+}
+
+func (s *memoryStore) List(_ context.Context) ([]*CachedWork, error) {
+	works := []*CachedWork{}
+	for _, work := range s.works {
+		works = append(works, work)
+	}
+	return works, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, work *CachedWork) error {
+	s.works[work.Work.Name] = work
+	s.saved++
+	return nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, name string) error {
+	delete(s.works, name)
+	return nil
+}
+
+var configMapMeta = workv1.ManifestResourceMeta{
+	Ordinal:   0,
+	Version:   "v1",
+	Kind:      "ConfigMap",
+	Resource:  "configmaps",
+	Name:      "cm1",
+	Namespace: "default",
+}
+
+func newManifestWork(name, resourceVersion string, applied metav1.ConditionStatus) *workv1.ManifestWork {
+	return &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "cluster1", ResourceVersion: resourceVersion},
+		Spec: workv1.ManifestWorkSpec{
+			Workload: workv1.ManifestsTemplate{Manifests: []workv1.Manifest{{RawExtension: runtime.RawExtension{
+				Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm1","namespace":"default"}}`),
+			}}}},
+		},
+		Status: workv1.ManifestWorkStatus{
+			Conditions: []metav1.Condition{{Type: workv1.WorkApplied, Status: applied, Reason: "Applied"}},
+			ResourceStatus: workv1.ManifestResourceStatus{Manifests: []workv1.ManifestCondition{{
+				ResourceMeta: configMapMeta,
+				Conditions:   []metav1.Condition{{Type: workv1.ManifestApplied, Status: applied, Reason: "Applied"}},
+			}}},
+		},
+	}
+}
+
+func newPendingStatus(applied metav1.ConditionStatus) *workv1.ManifestWorkStatus {
+	return &workv1.ManifestWorkStatus{
+		Conditions: []metav1.Condition{{Type: workv1.WorkApplied, Status: applied, Reason: ReasonAppliedOffline}},
+		ResourceStatus: workv1.ManifestResourceStatus{Manifests: []workv1.ManifestCondition{{
+			ResourceMeta: configMapMeta,
+			Conditions:   []metav1.Condition{{Type: workv1.ManifestApplied, Status: applied, Reason: ReasonAppliedOffline}},
+		}}},
+	}
+}
+
+func TestPersist(t *testing.T) {
+	tests := []struct {
+		name        string
+		hubWorks    []*workv1.ManifestWork
+		cached      []*CachedWork
+		wantVerbs   []string
+		wantSaved   int
+		wantCached  string
+		wantFlushed bool
+	}{
+		{
+			name:       "new ManifestWork",
+			hubWorks:   []*workv1.ManifestWork{newManifestWork("work1", "1", metav1.ConditionTrue)},
+			wantSaved:  1,
+			wantCached: "work1",
+		},
+		{
+			name:       "ManifestWork is not changed",
+			hubWorks:   []*workv1.ManifestWork{newManifestWork("work1", "1", metav1.ConditionTrue)},
+			cached:     []*CachedWork{{Work: newManifestWork("work1", "1", metav1.ConditionTrue)}},
+			wantCached: "work1",
+		},
+		{
+			name:       "ManifestWork is changed",
+			hubWorks:   []*workv1.ManifestWork{newManifestWork("work1", "2", metav1.ConditionTrue)},
+			cached:     []*CachedWork{{Work: newManifestWork("work1", "1", metav1.ConditionTrue)}},
+			wantSaved:  1,
+			wantCached: "work1",
+		},
+		{
+			name:   "ManifestWork is removed from the hub",
+			cached: []*CachedWork{{Work: newManifestWork("work1", "1", metav1.ConditionTrue)}},
+		},
+		{
+			name:     "pending status is flushed",
+			hubWorks: []*workv1.ManifestWork{newManifestWork("work1", "1", metav1.ConditionTrue)},
+			cached: []*CachedWork{{
+				Work:          newManifestWork("work1", "1", metav1.ConditionTrue),
+				PendingStatus: newPendingStatus(metav1.ConditionFalse),
+			}},
+			wantVerbs:   []string{"update"},
+			wantSaved:   1,
+			wantCached:  "work1",
+			wantFlushed: true,
+		},
+		{
+			name:     "pending status is dropped if the ManifestWork is changed",
+			hubWorks: []*workv1.ManifestWork{newManifestWork("work1", "2", metav1.ConditionTrue)},
+			cached: []*CachedWork{{
+				Work:          newManifestWork("work1", "1", metav1.ConditionTrue),
+				PendingStatus: newPendingStatus(metav1.ConditionFalse),
+			}},
+			wantSaved:  1,
+			wantCached: "work1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []runtime.Object{}
+			hubWorks := []workv1.ManifestWork{}
+			for _, work := range tt.hubWorks {
+				objs = append(objs, work)
+				hubWorks = append(hubWorks, *work)
+			}
+			hubWorkClient := workfake.NewSimpleClientset(objs...)
+			store := newMemoryStore(tt.cached...)
+			c := &WorkCache{store: store}
+
+			if err := c.persist(context.TODO(), hubWorkClient.WorkV1().ManifestWorks("cluster1"), hubWorks); err != nil {
+				t.Fatalf("persist() error = %v", err)
+			}
+
+			testingcommon.AssertActions(t, hubWorkClient.Actions(), tt.wantVerbs...)
+			if store.saved != tt.wantSaved {
+				t.Errorf("persist() saved %d ManifestWorks, want %d", store.saved, tt.wantSaved)
+			}
+			works, _ := store.List(context.TODO())
+			if names := cachedWorkNames(works); names != tt.wantCached {
+				t.Fatalf("the persisted ManifestWorks = %v, want %v", names, tt.wantCached)
+			}
+			for _, work := range works {
+				if work.PendingStatus != nil {
+					t.Errorf("the pending status of %s is not removed", work.Work.Name)
+				}
+			}
+
+			if tt.wantFlushed {
+				work, err := hubWorkClient.WorkV1().ManifestWorks("cluster1").Get(context.TODO(), "work1", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !meta.IsStatusConditionFalse(work.Status.Conditions, workv1.WorkApplied) {
+					t.Errorf("the pending status is not flushed, %v", work.Status.Conditions)
+				}
+			}
+		})
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	const hubHash = "hubhash"
+	appliedWork := &workv1.AppliedManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-work1", hubHash), UID: "uid1"},
+	}
+	deleting := newManifestWork("work1", "1", metav1.ConditionTrue)
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	withExecutor := newManifestWork("work1", "1", metav1.ConditionTrue)
+	withExecutor.Spec.Executor = &workv1.ManifestWorkExecutor{
+		Subject: workv1.ManifestWorkExecutorSubject{
+			Type: workv1.ExecutorSubjectTypeServiceAccount,
+			ServiceAccount: &workv1.ManifestWorkSubjectServiceAccount{
+				Namespace: "default",
+				Name:      "executor",
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		cached         *CachedWork
+		appliedWorks   []runtime.Object
+		existing       []runtime.Object
+		wantApplied    bool
+		wantPending    bool
+		wantWorkStatus metav1.ConditionStatus
+	}{
+		{
+			name:           "applied ManifestWork",
+			cached:         &CachedWork{Work: newManifestWork("work1", "1", metav1.ConditionTrue)},
+			appliedWorks:   []runtime.Object{appliedWork},
+			wantApplied:    true,
+			wantPending:    true,
+			wantWorkStatus: metav1.ConditionTrue,
+		},
+		{
+			name:         "the drift of the applied resource is corrected",
+			cached:       &CachedWork{Work: newManifestWork("work1", "1", metav1.ConditionTrue)},
+			appliedWorks: []runtime.Object{appliedWork},
+			existing: []runtime.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "cm1", Namespace: "default"},
+				Data:       map[string]string{"a": "b"},
+			}},
+			wantApplied:    true,
+			wantPending:    true,
+			wantWorkStatus: metav1.ConditionTrue,
+		},
+		{
+			name:   "ManifestWork is not applied on the managed cluster",
+			cached: &CachedWork{Work: newManifestWork("work1", "1", metav1.ConditionTrue)},
+		},
+		{
+			name:           "the manifest that was not applied is not enforced",
+			cached:         &CachedWork{Work: newManifestWork("work1", "1", metav1.ConditionFalse)},
+			appliedWorks:   []runtime.Object{appliedWork},
+			wantPending:    true,
+			wantWorkStatus: metav1.ConditionTrue,
+		},
+		{
+			name:         "deleting ManifestWork",
+			cached:       &CachedWork{Work: deleting},
+			appliedWorks: []runtime.Object{appliedWork},
+		},
+		{
+			name:         "ManifestWork with the executor",
+			cached:       &CachedWork{Work: withExecutor},
+			appliedWorks: []runtime.Object{appliedWork},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restMapper := meta.NewDefaultRESTMapper(nil)
+			restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+			kubeClient := kubefake.NewSimpleClientset(tt.existing...)
+			dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme, tt.existing...)
+
+			store := newMemoryStore(tt.cached)
+			c := &WorkCache{
+				store:           store,
+				hubHash:         hubHash,
+				spokeWorkClient: workfake.NewSimpleClientset(tt.appliedWorks...),
+				appliers:        apply.NewAppliers(dynamicClient, kubeClient, apiextensionsfake.NewSimpleClientset()),
+				restMapper:      restMapper,
+				recorder:        eventstesting.NewTestingEventRecorder(t),
+			}
+
+			if err := c.enforce(context.TODO()); err != nil {
+				t.Fatalf("enforce() error = %v", err)
+			}
+
+			configMap, err := kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), "cm1", metav1.GetOptions{})
+			switch {
+			case err != nil && !apierrors.IsNotFound(err):
+				t.Fatal(err)
+			case tt.wantApplied && (err != nil || len(configMap.Data) != 0):
+				t.Errorf("the manifest is not enforced, %v, %v", configMap, err)
+			case !tt.wantApplied && len(tt.existing) == 0 && err == nil:
+				t.Errorf("the manifest is enforced, %v", configMap)
+			}
+
+			works, _ := store.List(context.TODO())
+			pendingStatus := works[0].PendingStatus
+			if (pendingStatus != nil) != tt.wantPending {
+				t.Fatalf("enforce() pending status = %v, want %v", pendingStatus, tt.wantPending)
+			}
+			if pendingStatus == nil {
+				return
+			}
+			condition := meta.FindStatusCondition(pendingStatus.Conditions, workv1.WorkApplied)
+			if condition == nil || condition.Status != tt.wantWorkStatus || condition.Reason != ReasonAppliedOffline {
+				t.Errorf("enforce() applied condition = %v, want %v", condition, tt.wantWorkStatus)
+			}
+		})
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	gr := schema.GroupResource{Group: workv1.GroupName, Resource: "manifestworks"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "connection refused",
+			err: &url.Error{Op: "Get", URL: "https://hub:6443", Err: &net.OpError{
+				Op:  "dial",
+				Net: "tcp",
+				Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED},
+			}},
+			want: true,
+		},
+		{
+			name: "dns error",
+			err:  &url.Error{Op: "Get", URL: "https://hub:6443", Err: &net.DNSError{Err: "no such host", Name: "hub"}},
+			want: true,
+		},
+		{
+			name: "request timeout",
+			err:  &url.Error{Op: "Get", URL: "https://hub:6443", Err: context.DeadlineExceeded},
+			want: true,
+		},
+		{
+			name: "the hub kubeconfig is missing",
+			err: fmt.Errorf("failed to load the hub kubeconfig, %w",
+				&os.PathError{Op: "open", Path: "/spoke/hub-kubeconfig/kubeconfig", Err: syscall.ENOENT}),
+		},
+		{
+			name: "the hub kubeconfig is corrupt",
+			err:  fmt.Errorf("error loading config file: yaml: line 1: did not find expected key"),
+		},
+		{
+			name: "the certificate is not trusted",
+			err:  &url.Error{Op: "Get", URL: "https://hub:6443", Err: x509.UnknownAuthorityError{}},
+		},
+		{
+			name: "service unavailable",
+			err:  apierrors.NewServiceUnavailable("unavailable"),
+			want: true,
+		},
+		{
+			name: "server timeout",
+			err:  apierrors.NewServerTimeout(gr, "list", 1),
+			want: true,
+		},
+		{
+			name: "forbidden",
+			err:  apierrors.NewForbidden(gr, "", fmt.Errorf("forbidden")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnreachable(tt.err); got != tt.want {
+				t.Errorf("isUnreachable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package workcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+const (
+	// CacheLabel is the label of the secrets that persist the ManifestWorks, its value is the cluster name
+	CacheLabel = "multicluster-controlplane.open-cluster-management.io/manifestwork-cache"
+	// cacheDataKey is the key of the persisted ManifestWork in the secret
+	cacheDataKey = "manifestwork.json"
+	cacheFileExt = ".json"
+)
+
+// CachedWork is a ManifestWork that is persisted on the managed cluster
+type CachedWork struct {
+	// Work is the last known ManifestWork on the hub
+	Work *workv1.ManifestWork `json:"work"`
+	// PendingStatus is the status that is updated while the hub is unreachable, it is flushed to the hub once
+	// the hub is reachable
+	PendingStatus *workv1.ManifestWorkStatus `json:"pendingStatus,omitempty"`
+}
+
+// Store persists the ManifestWorks of a managed cluster
+type Store interface {
+	List(ctx context.Context) ([]*CachedWork, error)
+	Save(ctx context.Context, work *CachedWork) error
+	Delete(ctx context.Context, name string) error
+}
+
+// diskStore persists each ManifestWork to a file of the cluster dir
+type diskStore struct {
+	dir string
+}
+
+// NewDiskStore returns a store that persists the ManifestWorks of the cluster to the files of the <dir>/<cluster>
+func NewDiskStore(dir, clusterName string) (Store, error) {
+	clusterDir := filepath.Join(dir, clusterName)
+	if err := os.MkdirAll(clusterDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create dir %q, %v", clusterDir, err)
+	}
+	return &diskStore{dir: clusterDir}, nil
+}
+
+func (s *diskStore) List(_ context.Context) ([]*CachedWork, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	works := []*CachedWork{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), cacheFileExt) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		// a broken file does not block the other ManifestWorks, it is overwritten once the ManifestWork is
+		// persisted again
+		work, err := decode(data)
+		if err != nil {
+			klog.Warningf("skip the file %q that cannot be decoded, %v", entry.Name(), err)
+			continue
+		}
+		works = append(works, work)
+	}
+	return works, nil
+}
+
+func (s *diskStore) Save(_ context.Context, work *CachedWork) error {
+	data, err := json.Marshal(work)
+	if err != nil {
+		return err
+	}
+
+	// write a temp file and rename it, so the file is not broken if the agent is stopped during the writing
+	file := filepath.Join(s.dir, work.Work.Name+cacheFileExt)
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write file %q, %v", tmpFile, err)
+	}
+	return os.Rename(tmpFile, file)
+}
+
+func (s *diskStore) Delete(_ context.Context, name string) error {
+	if err := os.Remove(filepath.Join(s.dir, name+cacheFileExt)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// secretStore persists each ManifestWork to a secret, the manifests of the ManifestWork may contain secrets, so
+// the ManifestWorks are not persisted to the configmaps
+type secretStore struct {
+	kubeClient  kubernetes.Interface
+	namespace   string
+	clusterName string
+}
+
+// NewSecretStore returns a store that persists the ManifestWorks of the cluster to the secrets of the namespace
+func NewSecretStore(kubeClient kubernetes.Interface, namespace, clusterName string) Store {
+	return &secretStore{
+		kubeClient:  kubeClient,
+		namespace:   namespace,
+		clusterName: clusterName,
+	}
+}
+
+func (s *secretStore) List(ctx context.Context) ([]*CachedWork, error) {
+	secrets, err := s.kubeClient.CoreV1().Secrets(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", CacheLabel, s.clusterName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	works := []*CachedWork{}
+	for _, secret := range secrets.Items {
+		work, err := decode(secret.Data[cacheDataKey])
+		if err != nil {
+			klog.Warningf("skip the secret %s/%s that cannot be decoded, %v", secret.Namespace, secret.Name, err)
+			continue
+		}
+		works = append(works, work)
+	}
+	return works, nil
+}
+
+func (s *secretStore) Save(ctx context.Context, work *CachedWork) error {
+	data, err := json.Marshal(work)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.secretName(work.Work.Name),
+			Namespace: s.namespace,
+			Labels:    map[string]string{CacheLabel: s.clusterName},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{cacheDataKey: data},
+	}
+
+	existing, err := s.kubeClient.CoreV1().Secrets(s.namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := s.kubeClient.CoreV1().Secrets(s.namespace).Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	existing = existing.DeepCopy()
+	existing.Labels = secret.Labels
+	existing.Data = secret.Data
+	_, err = s.kubeClient.CoreV1().Secrets(s.namespace).Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *secretStore) Delete(ctx context.Context, name string) error {
+	err := s.kubeClient.CoreV1().Secrets(s.namespace).Delete(ctx, s.secretName(name), metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// secretName returns the secret name of the ManifestWork, the name of the ManifestWork is hashed, since the
+// secret name with the cluster name may be longer than the max length of the name
+func (s *secretStore) secretName(workName string) string {
+	return fmt.Sprintf("%s-manifestwork-%x", s.clusterName, sha256.Sum256([]byte(workName)))
+}
+
+func decode(data []byte) (*CachedWork, error) {
+	work := &CachedWork{}
+	if err := json.Unmarshal(data, work); err != nil {
+		return nil, err
+	}
+	if work.Work == nil {
+		return nil, fmt.Errorf("the ManifestWork is not found")
+	}
+	return work, nil
+}
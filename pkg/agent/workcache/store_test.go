@@ -0,0 +1,141 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package workcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func newCachedWork(name, resourceVersion string) *CachedWork {
+	return &CachedWork{Work: &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "cluster1", ResourceVersion: resourceVersion},
+	}}
+}
+
+func cachedWorkNames(works []*CachedWork) string {
+	names := []string{}
+	for _, work := range works {
+		names = append(names, work.Work.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name string
+		// newStore returns the store and a func to add a broken entry to the store
+		newStore func(t *testing.T) (Store, func())
+	}{
+		{
+			name: "disk",
+			newStore: func(t *testing.T) (Store, func()) {
+				dir := t.TempDir()
+				store, err := NewDiskStore(dir, "cluster1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store, func() {
+					if err := os.WriteFile(filepath.Join(dir, "cluster1", "broken.json"), []byte("{"), 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
+		{
+			name: "secret",
+			newStore: func(t *testing.T) (Store, func()) {
+				kubeClient := kubefake.NewSimpleClientset()
+				return NewSecretStore(kubeClient, "open-cluster-management-agent", "cluster1"), func() {
+					if _, err := kubeClient.CoreV1().Secrets("open-cluster-management-agent").Create(context.TODO(),
+						&corev1.Secret{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      "broken",
+								Namespace: "open-cluster-management-agent",
+								Labels:    map[string]string{CacheLabel: "cluster1"},
+							},
+							Data: map[string][]byte{cacheDataKey: []byte(`{"work":null}`)},
+						}, metav1.CreateOptions{}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			store, addBrokenEntry := tt.newStore(t)
+
+			for _, work := range []*CachedWork{newCachedWork("work1", "1"), newCachedWork("work2", "1")} {
+				if err := store.Save(ctx, work); err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+			}
+			// the saved ManifestWork is updated
+			if err := store.Save(ctx, newCachedWork("work1", "2")); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if err := store.Delete(ctx, "work2"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			// the ManifestWork is already deleted
+			if err := store.Delete(ctx, "work3"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			addBrokenEntry()
+
+			works, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if names := cachedWorkNames(works); names != "work1" {
+				t.Fatalf("List() = %v, want work1", names)
+			}
+			if works[0].Work.ResourceVersion != "2" {
+				t.Errorf("List() resource version = %v, want 2", works[0].Work.ResourceVersion)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			data: `{"work":{"metadata":{"name":"work1"}}}`,
+		},
+		{
+			name:    "invalid json",
+			data:    `{"work":`,
+			wantErr: true,
+		},
+		{
+			name:    "no ManifestWork",
+			data:    `{"pendingStatus":{}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -87,6 +87,19 @@ func certSetup(cfg *configs.ControlplaneRunConfig) (*certchains.CertificateChain
 				"10.0.0.1",
 			},
 		},
+		&certchains.ServingCertificateSigningRequestInfo{
+			CSRMeta: certchains.CSRMeta{
+				Name:         GRPCServerCertDirName,
+				ValidityDays: ShortLivedCertificateValidityDays,
+			},
+			Hostnames: []string{
+				cfg.Apiserver.ExternalHostname,
+				fmt.Sprintf("multicluster-controlplane.%s", util.GetComponentNamespace()),
+				fmt.Sprintf("multicluster-controlplane.%s.svc", util.GetComponentNamespace()),
+				"localhost",
+				"127.0.0.1",
+			},
+		},
 		&certchains.ServingCertificateSigningRequestInfo{
 			CSRMeta: certchains.CSRMeta{
 				Name:         KubeAggregatorCertDirName,
@@ -0,0 +1,80 @@
+// Copyright Contributors to the Open Cluster Management project
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"testing"
+
+	certutil "k8s.io/client-go/util/cert"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/configs"
+	"open-cluster-management.io/multicluster-controlplane/pkg/util"
+)
+
+func TestGRPCServingCertificate(t *testing.T) {
+	cfg := &configs.ControlplaneRunConfig{
+		DataDirectory: t.TempDir(),
+		Apiserver:     configs.ApiserverConfig{ExternalHostname: "controlplane.example.com"},
+	}
+	certChains, err := certSetup(cfg)
+	if err != nil {
+		t.Fatalf("certSetup() error = %v", err)
+	}
+	certsDir := CertsDirectory(cfg.DataDirectory)
+
+	certPEM, err := os.ReadFile(GRPCServingCertFile(certsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(GRPCServingKeyFile(certsDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the grpc serving cert is the one in the server CA chain
+	chainCertPEM, _, err := certChains.GetCertKey(RootCACertDirName, ServerCACertDirName, GRPCServerCertDirName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(certPEM, chainCertPEM) {
+		t.Errorf("the grpc serving cert file is not the cert of the server CA chain")
+	}
+
+	certs, err := certutil.ParseCertsPEM(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundlePEM, err := os.ReadFile(TotalServerCABundlePath(certsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundlePEM) {
+		t.Fatalf("failed to load the server CA bundle")
+	}
+
+	tests := []struct {
+		hostname string
+		wantErr  bool
+	}{
+		{hostname: "controlplane.example.com"},
+		{hostname: fmt.Sprintf("multicluster-controlplane.%s.svc", util.GetComponentNamespace())},
+		{hostname: "localhost"},
+		{hostname: "127.0.0.1"},
+		{hostname: "api.kube-public.svc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			_, err := certs[0].Verify(x509.VerifyOptions{
+				DNSName:   tt.hostname,
+				Roots:     roots,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
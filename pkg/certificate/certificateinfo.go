@@ -37,6 +37,7 @@ const (
 	AdminCertDirName          = "admin"
 	KubeApiserverCertDirName  = "kube-apiserver"
 	KubeAggregatorCertDirName = "kube-aggregator"
+	GRPCServerCertDirName     = "grpc-server"
 	AuthProxyCertDirName      = "auth-proxy"
 	PeerCertDirName           = "peer"
 	ClientCertDirName         = "client"
@@ -88,6 +89,12 @@ func ServingCertFile(certsDir string) string {
 func ServingKeyFile(certsDir string) string {
 	return filepath.Join(ServerCACertDir(certsDir), KubeApiserverCertDirName, certchains.ServerKeyFileName)
 }
+func GRPCServingCertFile(certsDir string) string {
+	return filepath.Join(ServerCACertDir(certsDir), GRPCServerCertDirName, certchains.ServerCertFileName)
+}
+func GRPCServingKeyFile(certsDir string) string {
+	return filepath.Join(ServerCACertDir(certsDir), GRPCServerCertDirName, certchains.ServerKeyFileName)
+}
 func ClientCACertFile(certsDir string) string {
 	return filepath.Join(ClientCACertDir(certsDir), certchains.CACertFileName)
 }
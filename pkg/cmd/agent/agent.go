@@ -41,6 +41,16 @@ func NewAgent() *cobra.Command {
 			ctx, terminate := context.WithCancel(shutdownCtx)
 			defer terminate()
 
+			if err := agentOptions.PrepareBootstrapKubeconfig(ctx); err != nil {
+				return err
+			}
+
+			go func() {
+				if err := agentOptions.RunServer(ctx); err != nil {
+					klog.Fatalf("failed to run agent server, %v", err)
+				}
+			}()
+
 			go func() {
 				klog.Info("starting the controlplane agent")
 				if err := agentOptions.RunAgent(ctx); err != nil {
@@ -60,5 +70,9 @@ func NewAgent() *cobra.Command {
 	flags := cmd.Flags()
 	features.SpokeMutableFeatureGate.AddFlag(flags)
 	agentOptions.AddFlags(flags)
+
+	cmd.AddCommand(NewHostedAgent())
+	cmd.AddCommand(NewPreflight())
+	cmd.AddCommand(NewUninstall())
 	return cmd
 }
@@ -0,0 +1,60 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apiserver/pkg/server"
+	"k8s.io/klog/v2"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent"
+)
+
+func NewHostedAgent() *cobra.Command {
+	hostedOptions := agent.NewHostedAgentOptions()
+
+	cmd := &cobra.Command{
+		Use:   "hosted",
+		Short: "Start a Multicluster Controlplane Agent that manages many spoke clusters",
+		Long: "Start a Multicluster Controlplane Agent in the hosted mode, the agent reads the spoke kubeconfigs " +
+			"from a directory or the secrets of the hosting cluster, and runs the registration and work agents " +
+			"and the addons for each spoke cluster.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := hostedOptions.Validate(); err != nil {
+				return err
+			}
+
+			shutdownCtx, cancel := context.WithCancel(context.TODO())
+
+			shutdownHandler := server.SetupSignalHandler()
+			go func() {
+				defer cancel()
+				<-shutdownHandler
+				klog.Infof("Received SIGTERM or SIGINT signal, shutting down agent.")
+			}()
+
+			ctx, terminate := context.WithCancel(shutdownCtx)
+			defer terminate()
+
+			if err := hostedOptions.PrepareBootstrapKubeconfig(ctx); err != nil {
+				return err
+			}
+
+			go func() {
+				if err := hostedOptions.RunServer(ctx); err != nil {
+					klog.Fatalf("failed to run agent server, %v", err)
+				}
+			}()
+
+			klog.Info("starting the hosted controlplane agent")
+			return hostedOptions.RunHostedAgents(ctx)
+		},
+	}
+
+	flags := cmd.Flags()
+	features.SpokeMutableFeatureGate.AddFlag(flags)
+	hostedOptions.AddFlags(flags)
+	return cmd
+}
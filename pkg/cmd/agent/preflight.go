@@ -0,0 +1,47 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent"
+)
+
+func NewPreflight() *cobra.Command {
+	agentOptions := agent.NewAgentOptions()
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check the Multicluster Controlplane Agent can run on the managed cluster",
+		Long: "Check the permissions of the agent on the managed cluster, the connectivity and the permissions of " +
+			"the agent on the controlplane, and the TokenRequest API of the managed cluster before the agent starts.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			failed := 0
+			for _, result := range agentOptions.RunPreflightChecks(context.TODO()) {
+				switch {
+				case result.Skipped:
+					fmt.Fprintf(cmd.OutOrStdout(), "[SKIP] %s\n", result.Name)
+				case result.Err != nil:
+					failed++
+					fmt.Fprintf(cmd.OutOrStdout(), "[FAIL] %s: %v\n", result.Name, result.Err)
+				default:
+					fmt.Fprintf(cmd.OutOrStdout(), "[PASS] %s\n", result.Name)
+				}
+			}
+
+			if failed != 0 {
+				return fmt.Errorf("%d preflight check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	features.SpokeMutableFeatureGate.AddFlag(flags)
+	agentOptions.AddFlags(flags)
+	return cmd
+}
@@ -0,0 +1,33 @@
+// Copyright Contributors to the Open Cluster Management project
+package agent
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent"
+)
+
+func NewUninstall() *cobra.Command {
+	uninstallOptions := agent.NewUninstallOptions()
+
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Uninstall the Multicluster Controlplane Agent from the managed cluster",
+		Long: "Uninstall the Multicluster Controlplane Agent from the managed cluster, the AppliedManifestWorks, " +
+			"the agent CRDs and the hub kubeconfig secret are removed, the resources that are applied by the " +
+			"ManifestWorks are orphaned or deleted, and the ManagedCluster is marked as leaving on the " +
+			"controlplane if the agent still has the hub credentials. The agent should be stopped before it is " +
+			"uninstalled.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return uninstallOptions.Uninstall(context.TODO())
+		},
+	}
+
+	flags := cmd.Flags()
+	features.SpokeMutableFeatureGate.AddFlag(flags)
+	uninstallOptions.AddFlags(flags)
+	return cmd
+}
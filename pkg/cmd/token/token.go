@@ -0,0 +1,164 @@
+// Copyright Contributors to the Open Cluster Management project
+package token
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/bootstrap"
+)
+
+type tokenOptions struct {
+	kubeconfig string
+}
+
+func (o *tokenOptions) kubeClient() (kubernetes.Interface, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the controlplane kubeconfig: %v", err)
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// NewToken returns the command to manage the bootstrap tokens of the controlplane
+func NewToken() *cobra.Command {
+	o := &tokenOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage the bootstrap tokens that are used by the managed clusters to join the controlplane",
+	}
+
+	cmd.PersistentFlags().StringVar(&o.kubeconfig, "kubeconfig", o.kubeconfig,
+		"Path to the kubeconfig file of the controlplane, the KUBECONFIG environment variable is used if it is not set.")
+
+	cmd.AddCommand(newCreateCommand(o))
+	cmd.AddCommand(newListCommand(o))
+	cmd.AddCommand(newRevokeCommand(o))
+	return cmd
+}
+
+func newCreateCommand(o *tokenOptions) *cobra.Command {
+	tokenOpts := bootstrap.BootstrapTokenOptions{
+		TTL: bootstrap.DefaultBootstrapTokenTTL,
+	}
+	printJoinCommand := false
+	hubAPIServer := ""
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a bootstrap token",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			kubeClient, err := o.kubeClient()
+			if err != nil {
+				return err
+			}
+
+			token, err := bootstrap.CreateBootstrapToken(ctx, kubeClient, tokenOpts)
+			if err != nil {
+				return fmt.Errorf("failed to create the bootstrap token: %v", err)
+			}
+
+			if !printJoinCommand {
+				fmt.Fprintln(cmd.OutOrStdout(), token.Token)
+				return nil
+			}
+
+			server, caCertHashes, err := bootstrap.GetClusterInfo(ctx, kubeClient)
+			if err != nil {
+				return fmt.Errorf("failed to get the cluster-info: %v", err)
+			}
+			if len(hubAPIServer) != 0 {
+				server = hubAPIServer
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), bootstrap.JoinCommand(server, token.Token, caCertHashes))
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.DurationVar(&tokenOpts.TTL, "ttl", tokenOpts.TTL,
+		"The duration before the token is expired, the token never expires if it is 0.")
+	flags.StringVar(&tokenOpts.Description, "description", tokenOpts.Description,
+		"A human friendly description of how the token is used.")
+	flags.StringSliceVar(&tokenOpts.ExtraGroups, "groups", tokenOpts.ExtraGroups,
+		"Extra groups that the token authenticates as, the group must start with \"system:bootstrappers:\".")
+	flags.BoolVar(&printJoinCommand, "print-join-command", printJoinCommand,
+		"Print the agent command to join a cluster to the controlplane with the token.")
+	flags.StringVar(&hubAPIServer, "hub-apiserver", hubAPIServer,
+		"The controlplane apiserver address in the join command, the address of the cluster-info is used if it is not set.")
+	return cmd
+}
+
+func newListCommand(o *tokenOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the bootstrap tokens",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := o.kubeClient()
+			if err != nil {
+				return err
+			}
+
+			tokens, err := bootstrap.ListBootstrapTokens(context.Background(), kubeClient)
+			if err != nil {
+				return fmt.Errorf("failed to list the bootstrap tokens: %v", err)
+			}
+
+			now := time.Now()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "TOKEN\tTTL\tEXPIRES\tGROUPS\tDESCRIPTION")
+			for _, token := range tokens {
+				ttl, expires := "<forever>", "<never>"
+				if token.Expiration != nil {
+					ttl = "<expired>"
+					if !token.Expired(now) {
+						ttl = token.Expiration.Sub(now).Round(time.Second).String()
+					}
+					expires = token.Expiration.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					token.Token, ttl, expires, strings.Join(token.Groups, ","), token.Description)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newRevokeCommand(o *tokenOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke [token-id]...",
+		Short: "Revoke the bootstrap tokens, the token can be the token id or the whole token",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := o.kubeClient()
+			if err != nil {
+				return err
+			}
+
+			for _, token := range args {
+				if err := bootstrap.RevokeBootstrapToken(context.Background(), kubeClient, token); err != nil {
+					return fmt.Errorf("failed to revoke the bootstrap token: %v", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "bootstrap token %q revoked\n", strings.Split(token, ".")[0])
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package addons
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/component-base/featuregate"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
+)
+
+const (
+	// ManagedServiceAccountAgentClusterRole is the hub clusterrole of the managed serviceaccount addon agents, it is
+	// bound to the managed clusters in their namespaces.
+	ManagedServiceAccountAgentClusterRole = "open-cluster-management:managedcluster:managedserviceaccount"
+	// ResourceUsageScoreAgentClusterRole is the hub clusterrole of the resource usage score addon agents, it is
+	// bound to the managed clusters in their namespaces.
+	ResourceUsageScoreAgentClusterRole = "open-cluster-management:managedcluster:resourceusagescore"
+)
+
+// addOnAgentClusterRoles are the hub clusterroles of the addon agents, a clusterrole is only bound to the managed
+// clusters if the feature of its addon is enabled on the controlplane
+var addOnAgentClusterRoles = []struct {
+	addOnName   string
+	clusterRole string
+	feature     featuregate.Feature
+}{
+	{
+		addOnName:   "managedserviceaccount",
+		clusterRole: ManagedServiceAccountAgentClusterRole,
+		feature:     mcfeature.ManagedServiceAccount,
+	},
+	{
+		addOnName:   "resourceusagescore",
+		clusterRole: ResourceUsageScoreAgentClusterRole,
+		feature:     mcfeature.ResourceUsageScore,
+	},
+}
+
+// EnabledAddOnAgentClusterRoles returns the hub clusterroles of the addon agents that are enabled by the feature
+// gates, they are keyed by the addon names
+func EnabledAddOnAgentClusterRoles() map[string]string {
+	clusterRoles := map[string]string{}
+	for _, addOn := range addOnAgentClusterRoles {
+		if features.HubMutableFeatureGate.Enabled(addOn.feature) {
+			clusterRoles[addOn.addOnName] = addOn.clusterRole
+		}
+	}
+	return clusterRoles
+}
+
+// addOnRBACController binds the addon agent clusterroles to the accepted managed clusters in their namespaces, so
+// the addon agents can run with the hub kubeconfig of the clusters rather than the bootstrap kubeconfig.
+type addOnRBACController struct {
+	clusterRoles  map[string]string
+	kubeClient    kubernetes.Interface
+	clusterLister clusterlisterv1.ManagedClusterLister
+	eventRecorder events.Recorder
+}
+
+// NewAddOnRBACController returns a controller to grant the addon agent permissions to the managed clusters, the
+// clusterRoles are keyed by the addon names
+func NewAddOnRBACController(
+	clusterRoles map[string]string,
+	kubeClient kubernetes.Interface,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	recorder events.Recorder) factory.Controller {
+	c := &addOnRBACController{
+		clusterRoles:  clusterRoles,
+		kubeClient:    kubeClient,
+		clusterLister: clusterInformer.Lister(),
+		eventRecorder: recorder.WithComponentSuffix("addon-rbac-controller"),
+	}
+
+	return factory.New().
+		WithInformersQueueKeysFunc(
+			func(obj runtime.Object) []string {
+				accessor, _ := meta.Accessor(obj)
+				return []string{accessor.GetName()}
+			},
+			clusterInformer.Informer()).
+		WithSync(c.sync).
+		ToController("AddOnRBACController", recorder)
+}
+
+func (c *addOnRBACController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if len(clusterName) == 0 || clusterName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// the cluster namespace is created after the cluster is accepted, and the rolebinding is removed with
+	// the namespace
+	if !cluster.Spec.HubAcceptsClient || !cluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	errs := []error{}
+	for addOnName, clusterRole := range c.clusterRoles {
+		_, _, err = resourceapply.ApplyRoleBinding(ctx, c.kubeClient.RbacV1(), c.eventRecorder, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("open-cluster-management:managedcluster:%s:%s", clusterName, addOnName),
+				Namespace: clusterName,
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabelKey: clusterName,
+				},
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     clusterRole,
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					APIGroup: rbacv1.GroupName,
+					Kind:     rbacv1.GroupKind,
+					Name:     fmt.Sprintf("system:open-cluster-management:%s", clusterName),
+				},
+			},
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
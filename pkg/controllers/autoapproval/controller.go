@@ -0,0 +1,408 @@
+// Copyright Contributors to the Open Cluster Management project
+package autoapproval
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	certificatesinformerv1 "k8s.io/client-go/informers/certificates/v1"
+	"k8s.io/client-go/kubernetes"
+	certificateslisterv1 "k8s.io/client-go/listers/certificates/v1"
+	"k8s.io/client-go/tools/cache"
+	tokenapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	"k8s.io/klog/v2"
+	clusterclientset "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	"open-cluster-management.io/ocm/pkg/registration/helpers"
+	"open-cluster-management.io/ocm/pkg/registration/hub/managedclusterset"
+	"open-cluster-management.io/ocm/pkg/registration/hub/user"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/configs"
+)
+
+// rule is the compiled auto approval rule
+type rule struct {
+	configs.AutoApprovalRule
+	clusterNamePattern      *regexp.Regexp
+	tokenDescriptionPattern *regexp.Regexp
+}
+
+// requester is the bootstrap user who requests to join a managed cluster
+type requester struct {
+	username         string
+	groups           sets.Set[string]
+	tokenDescription string
+}
+
+// autoApprovalController approves the bootstrap CSRs of the joining managed clusters, accepts the managed
+// clusters and adds them to the ManagedClusterSets by the configured rules. The rules are evaluated in order,
+// the first matched rule is used.
+type autoApprovalController struct {
+	kubeClient    kubernetes.Interface
+	clusterClient clusterclientset.Interface
+	csrLister     certificateslisterv1.CertificateSigningRequestLister
+	clusterLister clusterlisterv1.ManagedClusterLister
+	rules         []rule
+	eventRecorder events.Recorder
+}
+
+// NewAutoApprovalController returns a controller to approve the managed clusters with the rules
+func NewAutoApprovalController(
+	kubeClient kubernetes.Interface,
+	clusterClient clusterclientset.Interface,
+	csrInformer certificatesinformerv1.CertificateSigningRequestInformer,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	approvalRules []configs.AutoApprovalRule,
+	recorder events.Recorder) (factory.Controller, error) {
+	rules, err := compileRules(approvalRules)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &autoApprovalController{
+		kubeClient:    kubeClient,
+		clusterClient: clusterClient,
+		csrLister:     csrInformer.Lister(),
+		clusterLister: clusterInformer.Lister(),
+		rules:         rules,
+		eventRecorder: recorder.WithComponentSuffix("auto-approval-controller"),
+	}
+
+	return factory.New().
+		WithFilteredEventsInformersQueueKeysFunc(
+			func(obj runtime.Object) []string {
+				accessor, ok := meta(obj)
+				if !ok {
+					return []string{}
+				}
+				return []string{accessor.GetLabels()[clusterv1.ClusterNameLabelKey]}
+			},
+			func(obj interface{}) bool {
+				accessor, ok := meta(obj)
+				if !ok {
+					return false
+				}
+				_, ok = accessor.GetLabels()[clusterv1.ClusterNameLabelKey]
+				return ok
+			},
+			csrInformer.Informer()).
+		WithInformersQueueKeysFunc(
+			func(obj runtime.Object) []string {
+				accessor, ok := meta(obj)
+				if !ok {
+					return []string{}
+				}
+				return []string{accessor.GetName()}
+			},
+			clusterInformer.Informer()).
+		WithSync(c.sync).
+		ToController("AutoApprovalController", recorder), nil
+}
+
+func (c *autoApprovalController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	clusterName := syncCtx.QueueKey()
+	if len(clusterName) == 0 || clusterName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	csrs, err := c.csrLister.List(labels.SelectorFromSet(labels.Set{clusterv1.ClusterNameLabelKey: clusterName}))
+	if err != nil {
+		return err
+	}
+
+	cluster, err := c.clusterLister.Get(clusterName)
+	if errors.IsNotFound(err) {
+		cluster = nil
+	} else if err != nil {
+		return err
+	}
+
+	// the CSRs of a cluster may be requested with different bootstrap tokens, each CSR is matched with its own
+	// requester, so a CSR is never approved by the rule that is matched by another CSR. The cluster is updated by
+	// the rule that is matched by the latest matched CSR.
+	sort.Slice(csrs, func(i, j int) bool {
+		return csrs[j].CreationTimestamp.Before(&csrs[i].CreationTimestamp)
+	})
+	var clusterRule *rule
+	for _, csr := range csrs {
+		if !isBootstrapCSR(csr) {
+			continue
+		}
+
+		req, err := c.getRequester(ctx, csr)
+		if err != nil {
+			return err
+		}
+		matched := c.match(clusterName, cluster, req)
+		if matched == nil {
+			continue
+		}
+		if clusterRule == nil {
+			clusterRule = matched
+		}
+
+		if matched.ApproveCSR {
+			if err := c.approveCSR(ctx, csr, clusterName, matched.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the rules without the token conditions match the cluster without the CSRs
+	if clusterRule == nil {
+		clusterRule = c.match(clusterName, cluster, nil)
+	}
+
+	if clusterRule == nil || cluster == nil || !cluster.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	return c.applyCluster(ctx, cluster, clusterRule)
+}
+
+// getRequester returns the bootstrap user of the CSR, the token groups are recorded on the CSR when it is created,
+// so the token can be expired or revoked after the CSR is created.
+func (c *autoApprovalController) getRequester(ctx context.Context,
+	csr *certificatesv1.CertificateSigningRequest) (*requester, error) {
+	req := &requester{
+		username: csr.Spec.Username,
+		groups:   sets.New(csr.Spec.Groups...),
+	}
+
+	tokenID := strings.TrimPrefix(csr.Spec.Username, tokenapi.BootstrapUserPrefix)
+	secret, err := c.kubeClient.CoreV1().Secrets(metav1.NamespaceSystem).Get(
+		ctx, bootstraputil.BootstrapTokenSecretName(tokenID), metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		klog.V(4).Infof("the bootstrap token %s is not found", tokenID)
+	case err != nil:
+		return nil, err
+	case secret.Type == corev1.SecretTypeBootstrapToken:
+		req.tokenDescription = string(secret.Data[tokenapi.BootstrapTokenDescriptionKey])
+	}
+
+	return req, nil
+}
+
+func (c *autoApprovalController) match(clusterName string, cluster *clusterv1.ManagedCluster, req *requester) *rule {
+	for i := range c.rules {
+		if c.rules[i].matches(clusterName, cluster, req) {
+			return &c.rules[i]
+		}
+	}
+	return nil
+}
+
+func (c *autoApprovalController) approveCSR(ctx context.Context,
+	csr *certificatesv1.CertificateSigningRequest, clusterName, ruleName string) error {
+	if helpers.IsCSRInTerminalState(&csr.Status) {
+		return nil
+	}
+
+	if !isManagedClusterCSR(csr, clusterName) {
+		klog.V(4).Infof("the CSR %s is not a managed cluster CSR", csr.Name)
+		return nil
+	}
+
+	approved := csr.DeepCopy()
+	approved.Status.Conditions = append(approved.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  "AutoApprovedByRule",
+		Message: fmt.Sprintf("Auto approved by the rule %q", ruleName),
+	})
+	if _, err := c.kubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(
+		ctx, approved.Name, approved, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	c.eventRecorder.Eventf("ManagedClusterCSRAutoApproved",
+		"the CSR %q of managed cluster %q is approved by the rule %q", csr.Name, clusterName, ruleName)
+	return nil
+}
+
+func (c *autoApprovalController) applyCluster(ctx context.Context, cluster *clusterv1.ManagedCluster, matched *rule) error {
+	updated := cluster.DeepCopy()
+	if matched.AcceptCluster {
+		updated.Spec.HubAcceptsClient = true
+	}
+
+	// the cluster set that is changed by the admin is kept, so only the clusters that are not in a cluster set or
+	// in the default cluster set are added to the cluster set of the rule
+	if len(matched.ClusterSet) != 0 {
+		clusterSet := updated.Labels[clusterv1beta2.ClusterSetLabel]
+		if len(clusterSet) == 0 || clusterSet == managedclusterset.DefaultManagedClusterSetName {
+			if updated.Labels == nil {
+				updated.Labels = map[string]string{}
+			}
+			updated.Labels[clusterv1beta2.ClusterSetLabel] = matched.ClusterSet
+		}
+	}
+
+	if cluster.Spec.HubAcceptsClient == updated.Spec.HubAcceptsClient &&
+		cluster.Labels[clusterv1beta2.ClusterSetLabel] == updated.Labels[clusterv1beta2.ClusterSetLabel] {
+		return nil
+	}
+
+	if _, err := c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	c.eventRecorder.Eventf("ManagedClusterAutoApproved",
+		"the managed cluster %q is updated by the rule %q", cluster.Name, matched.Name)
+	return nil
+}
+
+// isBootstrapCSR returns true if the CSR is requested by a bootstrap token, the renewal CSRs are approved by the
+// registration controller
+func isBootstrapCSR(csr *certificatesv1.CertificateSigningRequest) bool {
+	return strings.HasPrefix(csr.Spec.Username, tokenapi.BootstrapUserPrefix)
+}
+
+func (r *rule) matches(clusterName string, cluster *clusterv1.ManagedCluster, req *requester) bool {
+	if r.clusterNamePattern != nil && !r.clusterNamePattern.MatchString(clusterName) {
+		return false
+	}
+
+	if len(r.Labels) != 0 {
+		if cluster == nil || !labels.SelectorFromSet(r.Labels).Matches(labels.Set(cluster.Labels)) {
+			return false
+		}
+	}
+
+	if len(r.ClusterClaims) != 0 {
+		if cluster == nil {
+			return false
+		}
+
+		claims := map[string]string{}
+		for _, claim := range cluster.Status.ClusterClaims {
+			claims[claim.Name] = claim.Value
+		}
+		for name, value := range r.ClusterClaims {
+			if claimValue, ok := claims[name]; !ok || claimValue != value {
+				return false
+			}
+		}
+	}
+
+	if r.tokenDescriptionPattern != nil {
+		if req == nil || !r.tokenDescriptionPattern.MatchString(req.tokenDescription) {
+			return false
+		}
+	}
+
+	if len(r.TokenGroups) != 0 {
+		if req == nil || !req.groups.HasAll(r.TokenGroups...) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateRules validates the auto approval rules
+func ValidateRules(approvalRules []configs.AutoApprovalRule) error {
+	_, err := compileRules(approvalRules)
+	return err
+}
+
+func compileRules(approvalRules []configs.AutoApprovalRule) ([]rule, error) {
+	rules := []rule{}
+	for i, approvalRule := range approvalRules {
+		r := rule{AutoApprovalRule: approvalRule}
+		if len(r.Name) == 0 {
+			r.Name = fmt.Sprintf("rule-%d", i)
+		}
+
+		// a rule without conditions matches every cluster
+		if len(r.ClusterNamePattern) == 0 && len(r.ClusterClaims) == 0 && len(r.Labels) == 0 &&
+			len(r.TokenDescriptionPattern) == 0 && len(r.TokenGroups) == 0 {
+			return nil, fmt.Errorf("the auto approval rule %q has no conditions", r.Name)
+		}
+
+		// the cluster name and labels are chosen by the registering agent, so only the bootstrap token can decide
+		// whether the CSRs are approved and the cluster is accepted
+		if (r.ApproveCSR || r.AcceptCluster) && len(r.TokenDescriptionPattern) == 0 && len(r.TokenGroups) == 0 {
+			return nil, fmt.Errorf("the auto approval rule %q must have the tokenDescriptionPattern or tokenGroups "+
+				"to approve the CSRs or accept the cluster", r.Name)
+		}
+
+		// the cluster claims are reported to the ManagedCluster status after the CSR is approved
+		if r.ApproveCSR && len(r.ClusterClaims) != 0 {
+			return nil, fmt.Errorf("the auto approval rule %q cannot approve the CSRs with the cluster claims", r.Name)
+		}
+
+		if len(approvalRule.ClusterNamePattern) != 0 {
+			pattern, err := regexp.Compile(approvalRule.ClusterNamePattern)
+			if err != nil {
+				return nil, fmt.Errorf("the cluster name pattern of the auto approval rule %q is invalid, %v", r.Name, err)
+			}
+			r.clusterNamePattern = pattern
+		}
+
+		if len(approvalRule.TokenDescriptionPattern) != 0 {
+			pattern, err := regexp.Compile(approvalRule.TokenDescriptionPattern)
+			if err != nil {
+				return nil, fmt.Errorf("the token description pattern of the auto approval rule %q is invalid, %v", r.Name, err)
+			}
+			r.tokenDescriptionPattern = pattern
+		}
+
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// isManagedClusterCSR checks the signer, the organization and the common name of the CSR, it is same
+// as the validation of the registration hub controller.
+func isManagedClusterCSR(csr *certificatesv1.CertificateSigningRequest, clusterName string) bool {
+	if csr.Spec.SignerName != certificatesv1.KubeAPIServerClientSignerName {
+		return false
+	}
+
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return false
+	}
+
+	x509cr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	requestingOrgs := sets.New(x509cr.Subject.Organization...)
+	requestingOrgs.Delete(user.ManagedClustersGroup)
+	expectedPerClusterOrg := fmt.Sprintf("%s%s", user.SubjectPrefix, clusterName)
+	if requestingOrgs.Len() != 1 || !requestingOrgs.Has(expectedPerClusterOrg) {
+		return false
+	}
+
+	return strings.HasPrefix(x509cr.Subject.CommonName, expectedPerClusterOrg)
+}
+
+func meta(obj interface{}) (metav1.Object, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, ok := obj.(metav1.Object)
+	return accessor, ok
+}
@@ -0,0 +1,428 @@
+// Copyright Contributors to the Open Cluster Management project
+package autoapproval
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	tokenapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+	"open-cluster-management.io/ocm/pkg/registration/hub/user"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/configs"
+)
+
+func newCSRRequest(t *testing.T, clusterName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	org := user.SubjectPrefix + clusterName
+	request, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			Organization: []string{org, user.ManagedClustersGroup},
+			CommonName:   org + ":agent1",
+		},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request})
+}
+
+func newCSR(t *testing.T, name, tokenID string, created time.Time, groups ...string) *certificatesv1.CertificateSigningRequest {
+	return &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Labels:            map[string]string{clusterv1.ClusterNameLabelKey: "cluster1"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:    newCSRRequest(t, "cluster1"),
+			SignerName: certificatesv1.KubeAPIServerClientSignerName,
+			Username:   tokenapi.BootstrapUserPrefix + tokenID,
+			Groups:     groups,
+		},
+	}
+}
+
+func newTokenSecret(tokenID, description string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootstraputil.BootstrapTokenSecretName(tokenID),
+			Namespace: metav1.NamespaceSystem,
+		},
+		Type: corev1.SecretTypeBootstrapToken,
+		Data: map[string][]byte{tokenapi.BootstrapTokenDescriptionKey: []byte(description)},
+	}
+}
+
+func TestCompileRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []configs.AutoApprovalRule
+		wantErr bool
+	}{
+		{
+			name: "valid rules",
+			rules: []configs.AutoApprovalRule{
+				{Name: "dev", ClusterNamePattern: "^dev-.*", TokenGroups: []string{"system:bootstrappers:dev"}, ApproveCSR: true},
+				{
+					Name:                    "prod",
+					ClusterClaims:           map[string]string{"region": "us"},
+					TokenDescriptionPattern: "^prod",
+					AcceptCluster:           true,
+				},
+				{Name: "staging", ClusterNamePattern: "^staging-.*", ClusterSet: "staging"},
+			},
+		},
+		{
+			name:    "no conditions",
+			rules:   []configs.AutoApprovalRule{{Name: "all", ApproveCSR: true, AcceptCluster: true}},
+			wantErr: true,
+		},
+		{
+			name: "approve the CSRs without the token conditions",
+			rules: []configs.AutoApprovalRule{
+				{Name: "dev", ClusterNamePattern: "^dev-.*", Labels: map[string]string{"env": "dev"}, ApproveCSR: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "accept the cluster without the token conditions",
+			rules: []configs.AutoApprovalRule{
+				{Name: "dev", ClusterNamePattern: "^dev-.*", AcceptCluster: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "approve the CSRs with the cluster claims",
+			rules: []configs.AutoApprovalRule{
+				{
+					Name:          "claims",
+					ClusterClaims: map[string]string{"region": "us"},
+					TokenGroups:   []string{"system:bootstrappers"},
+					ApproveCSR:    true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid cluster name pattern",
+			rules:   []configs.AutoApprovalRule{{Name: "invalid", ClusterNamePattern: "(", ClusterSet: "dev"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid token description pattern",
+			rules:   []configs.AutoApprovalRule{{Name: "invalid", TokenDescriptionPattern: "[", AcceptCluster: true}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateRules(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "dev-cluster1", Labels: map[string]string{"env": "dev"}},
+		Status: clusterv1.ManagedClusterStatus{
+			ClusterClaims: []clusterv1.ManagedClusterClaim{{Name: "region", Value: "us"}},
+		},
+	}
+	req := &requester{
+		username:         tokenapi.BootstrapUserPrefix + "abcdef",
+		groups:           sets.New("system:bootstrappers", "system:bootstrappers:dev"),
+		tokenDescription: "the token of the dev clusters",
+	}
+
+	tests := []struct {
+		name    string
+		rule    configs.AutoApprovalRule
+		cluster *clusterv1.ManagedCluster
+		req     *requester
+		want    bool
+	}{
+		{
+			name:    "cluster name",
+			rule:    configs.AutoApprovalRule{ClusterNamePattern: "^dev-.*"},
+			cluster: cluster,
+			want:    true,
+		},
+		{
+			name:    "cluster name is not matched",
+			rule:    configs.AutoApprovalRule{ClusterNamePattern: "^prod-.*"},
+			cluster: cluster,
+		},
+		{
+			name:    "labels",
+			rule:    configs.AutoApprovalRule{Labels: map[string]string{"env": "dev"}},
+			cluster: cluster,
+			want:    true,
+		},
+		{
+			name: "labels without the cluster",
+			rule: configs.AutoApprovalRule{Labels: map[string]string{"env": "dev"}},
+		},
+		{
+			name:    "cluster claims",
+			rule:    configs.AutoApprovalRule{ClusterClaims: map[string]string{"region": "us"}},
+			cluster: cluster,
+			want:    true,
+		},
+		{
+			name:    "cluster claims are not matched",
+			rule:    configs.AutoApprovalRule{ClusterClaims: map[string]string{"region": "eu"}},
+			cluster: cluster,
+		},
+		{
+			name: "token description",
+			rule: configs.AutoApprovalRule{TokenDescriptionPattern: "dev"},
+			req:  req,
+			want: true,
+		},
+		{
+			name:    "token description without the requester",
+			rule:    configs.AutoApprovalRule{TokenDescriptionPattern: "dev"},
+			cluster: cluster,
+		},
+		{
+			name: "token groups",
+			rule: configs.AutoApprovalRule{TokenGroups: []string{"system:bootstrappers:dev"}},
+			req:  req,
+			want: true,
+		},
+		{
+			name: "token groups are not matched",
+			rule: configs.AutoApprovalRule{TokenGroups: []string{"system:bootstrappers:prod"}},
+			req:  req,
+		},
+		{
+			name: "all of the conditions must be matched",
+			rule: configs.AutoApprovalRule{
+				ClusterNamePattern: "^dev-.*",
+				TokenGroups:        []string{"system:bootstrappers:prod"},
+			},
+			cluster: cluster,
+			req:     req,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := compileRules([]configs.AutoApprovalRule{tt.rule})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rules[0].matches("dev-cluster1", tt.cluster, tt.req); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	now := time.Now()
+	devRule := configs.AutoApprovalRule{
+		Name:          "dev",
+		TokenGroups:   []string{"system:bootstrappers:dev"},
+		ApproveCSR:    true,
+		AcceptCluster: true,
+		ClusterSet:    "dev",
+	}
+	setRule := configs.AutoApprovalRule{Name: "dev", ClusterNamePattern: "^cluster", ClusterSet: "dev"}
+	newCluster := func(clusterSet string) *clusterv1.ManagedCluster {
+		cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+		if len(clusterSet) != 0 {
+			cluster.Labels = map[string]string{clusterv1beta2.ClusterSetLabel: clusterSet}
+		}
+		return cluster
+	}
+	cluster := newCluster("")
+
+	tests := []struct {
+		name           string
+		rules          []configs.AutoApprovalRule
+		csrs           []runtime.Object
+		cluster        *clusterv1.ManagedCluster
+		wantApproved   []string
+		wantUpdated    bool
+		wantAccepted   bool
+		wantClusterSet string
+	}{
+		{
+			name:  "CSR is matched",
+			rules: []configs.AutoApprovalRule{devRule},
+			csrs: []runtime.Object{
+				newCSR(t, "csr1", "abcdef", now, "system:bootstrappers", "system:bootstrappers:dev"),
+			},
+			cluster:        cluster,
+			wantApproved:   []string{"csr1"},
+			wantUpdated:    true,
+			wantAccepted:   true,
+			wantClusterSet: "dev",
+		},
+		{
+			name:  "only the matched CSR is approved",
+			rules: []configs.AutoApprovalRule{devRule},
+			csrs: []runtime.Object{
+				newCSR(t, "csr1", "abcdef", now.Add(-time.Minute), "system:bootstrappers", "system:bootstrappers:dev"),
+				newCSR(t, "csr2", "ghijkl", now, "system:bootstrappers"),
+			},
+			cluster:        cluster,
+			wantApproved:   []string{"csr1"},
+			wantUpdated:    true,
+			wantAccepted:   true,
+			wantClusterSet: "dev",
+		},
+		{
+			name:  "CSR is not matched",
+			rules: []configs.AutoApprovalRule{devRule},
+			csrs: []runtime.Object{
+				newCSR(t, "csr1", "abcdef", now, "system:bootstrappers"),
+			},
+			cluster: cluster,
+		},
+		{
+			name: "CSR is matched by the token description",
+			rules: []configs.AutoApprovalRule{
+				{Name: "dev", TokenDescriptionPattern: "^dev", ApproveCSR: true},
+			},
+			csrs: []runtime.Object{
+				newCSR(t, "csr1", "abcdef", now, "system:bootstrappers"),
+				newCSR(t, "csr2", "ghijkl", now, "system:bootstrappers"),
+				newTokenSecret("abcdef", "dev clusters"),
+				newTokenSecret("ghijkl", "prod clusters"),
+			},
+			wantApproved: []string{"csr1"},
+		},
+		{
+			name:    "the cluster is not accepted without the CSRs",
+			rules:   []configs.AutoApprovalRule{devRule},
+			cluster: newCluster(""),
+		},
+		{
+			name:           "the cluster is added to the cluster set without the CSRs",
+			rules:          []configs.AutoApprovalRule{setRule},
+			cluster:        newCluster(""),
+			wantUpdated:    true,
+			wantClusterSet: "dev",
+		},
+		{
+			name:           "the cluster in the default cluster set is moved",
+			rules:          []configs.AutoApprovalRule{setRule},
+			cluster:        newCluster("default"),
+			wantUpdated:    true,
+			wantClusterSet: "dev",
+		},
+		{
+			name:    "the cluster set that is changed by the admin is kept",
+			rules:   []configs.AutoApprovalRule{setRule},
+			cluster: newCluster("prod"),
+		},
+		{
+			name:  "the cluster is accepted in the cluster set that is changed by the admin",
+			rules: []configs.AutoApprovalRule{devRule},
+			csrs: []runtime.Object{
+				newCSR(t, "csr1", "abcdef", now, "system:bootstrappers", "system:bootstrappers:dev"),
+			},
+			cluster:        newCluster("prod"),
+			wantApproved:   []string{"csr1"},
+			wantUpdated:    true,
+			wantAccepted:   true,
+			wantClusterSet: "prod",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(tt.csrs...)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+			for _, obj := range tt.csrs {
+				if csr, ok := obj.(*certificatesv1.CertificateSigningRequest); ok {
+					if err := kubeInformers.Certificates().V1().CertificateSigningRequests().Informer().GetStore().Add(csr); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			clusterObjs := []runtime.Object{}
+			if tt.cluster != nil {
+				clusterObjs = append(clusterObjs, tt.cluster)
+			}
+			clusterClient := clusterfake.NewSimpleClientset(clusterObjs...)
+			clusterInformers := clusterinformers.NewSharedInformerFactory(clusterClient, 0)
+			if tt.cluster != nil {
+				if err := clusterInformers.Cluster().V1().ManagedClusters().Informer().GetStore().Add(tt.cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rules, err := compileRules(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &autoApprovalController{
+				kubeClient:    kubeClient,
+				clusterClient: clusterClient,
+				csrLister:     kubeInformers.Certificates().V1().CertificateSigningRequests().Lister(),
+				clusterLister: clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+				rules:         rules,
+				eventRecorder: eventstesting.NewTestingEventRecorder(t),
+			}
+
+			if err := c.sync(context.TODO(), testingcommon.NewFakeSyncContext(t, "cluster1")); err != nil {
+				t.Fatalf("sync() error = %v", err)
+			}
+
+			approved := []string{}
+			for _, action := range kubeClient.Actions() {
+				if update, ok := action.(clienttesting.UpdateAction); ok && action.GetSubresource() == "approval" {
+					approved = append(approved, update.GetObject().(*certificatesv1.CertificateSigningRequest).Name)
+				}
+			}
+			sort.Strings(approved)
+			if strings.Join(approved, ",") != strings.Join(tt.wantApproved, ",") {
+				t.Errorf("sync() approved %v, want %v", approved, tt.wantApproved)
+			}
+
+			if !tt.wantUpdated {
+				testingcommon.AssertNoActions(t, clusterClient.Actions())
+				return
+			}
+			testingcommon.AssertActions(t, clusterClient.Actions(), "update")
+			updated := clusterClient.Actions()[0].(clienttesting.UpdateAction).GetObject().(*clusterv1.ManagedCluster)
+			if updated.Spec.HubAcceptsClient != tt.wantAccepted {
+				t.Errorf("the cluster is accepted = %v, want %v", updated.Spec.HubAcceptsClient, tt.wantAccepted)
+			}
+			if clusterSet := updated.Labels[clusterv1beta2.ClusterSetLabel]; clusterSet != tt.wantClusterSet {
+				t.Errorf("the cluster set = %q, want %q", clusterSet, tt.wantClusterSet)
+			}
+		})
+	}
+}
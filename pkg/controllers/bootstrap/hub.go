@@ -3,8 +3,6 @@ package bootstrap
 
 import (
 	"context"
-	"fmt"
-	"math/rand"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -21,11 +19,19 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/cmd/kubeadm/app/phases/bootstraptoken/clusterinfo"
 	"k8s.io/kubernetes/cmd/kubeadm/app/util/apiclient"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
 )
 
-var letterRunes_az09 = []rune("abcdefghijklmnopqrstuvwxyz0123456789")
+// defaultBootstrapTokenResyncInterval is the max interval to check whether the default bootstrap token is expiring
+const defaultBootstrapTokenResyncInterval = 10 * time.Minute
 
-func BuildKubeSystemResources(ctx context.Context, config server.Config, kubeClient kubernetes.Interface) error {
+// BuildKubeSystemResources prepares the resources that are required by the managed clusters to join the
+// controlplane, the default bootstrap token is kept with the given TTL, it is not created if the TTL is 0.
+// The default ValidatingAdmissionPolicies are applied except the disabled ones.
+func BuildKubeSystemResources(ctx context.Context, config server.Config, kubeClient kubernetes.Interface,
+	defaultBootstrapTokenTTL time.Duration, disabledAdmissionPolicies []string) error {
 	// prepare default namespace
 	if _, err := kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
@@ -51,20 +57,32 @@ func BuildKubeSystemResources(ctx context.Context, config server.Config, kubeCli
 		klog.Errorf("failed to prepare cluster-info configmap in kube-public namespace: %v", err)
 	}
 
-	// prepare bootstrap token secret
-	if err := wait.PollUntilContextCancel(ctx, 1*time.Second, true, func(ctx context.Context) (bool, error) {
-		if _, err := kubeClient.CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{}); err != nil {
-			// waiting the kube-system namespace
-			return false, nil
-		}
-
-		if err := prepareBootstrapTokenSecret(ctx, kubeClient); err != nil {
-			return false, err
+	// prepare the default bootstrap token secret, the token is checked at least 4 times in its TTL, and a new token
+	// is created when the previous one will be expired before the next two checks, so the default token is always
+	// valid.
+	if defaultBootstrapTokenTTL > 0 {
+		resyncInterval := defaultBootstrapTokenResyncInterval
+		if defaultBootstrapTokenTTL/4 < resyncInterval {
+			resyncInterval = defaultBootstrapTokenTTL / 4
 		}
 
-		return true, nil
-	}); err != nil {
-		klog.Errorf("failed to prepare bootstrap token secret in kube-system namespace: %v", err)
+		go wait.UntilWithContext(ctx, func(ctx context.Context) {
+			if err := wait.PollUntilContextCancel(ctx, 1*time.Second, true, func(ctx context.Context) (bool, error) {
+				if _, err := kubeClient.CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{}); err != nil {
+					// waiting the kube-system namespace
+					return false, nil
+				}
+
+				if err := ensureDefaultBootstrapToken(ctx, kubeClient,
+					defaultBootstrapTokenTTL, 2*resyncInterval); err != nil {
+					return false, err
+				}
+
+				return true, nil
+			}); err != nil {
+				klog.Errorf("failed to prepare bootstrap token secret in kube-system namespace: %v", err)
+			}
+		}, resyncInterval)
 	}
 
 	// prepare clusterroles
@@ -99,6 +117,58 @@ func BuildKubeSystemResources(ctx context.Context, config server.Config, kubeCli
 			},
 		}
 
+		// the addon agents run with the hub kubeconfig of the managed clusters, the clusterroles are bound to the
+		// clusters in their namespaces if the addons are enabled
+		if features.HubMutableFeatureGate.Enabled(mcfeature.ManagedServiceAccount) {
+			clusterRoles = append(clusterRoles, &rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "open-cluster-management:managedcluster:managedserviceaccount",
+				},
+				Rules: []rbacv1.PolicyRule{
+					{
+						APIGroups: []string{"authentication.open-cluster-management.io"},
+						Resources: []string{"managedserviceaccounts"},
+						Verbs:     []string{"get", "list", "watch"},
+					},
+					{
+						APIGroups: []string{"authentication.open-cluster-management.io"},
+						Resources: []string{"managedserviceaccounts/status"},
+						Verbs:     []string{"get", "update", "patch"},
+					},
+					{
+						APIGroups: []string{""},
+						Resources: []string{"secrets"},
+						Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+					},
+					{
+						APIGroups: []string{"", "events.k8s.io"},
+						Resources: []string{"events"},
+						Verbs:     []string{"create", "patch", "update"},
+					},
+				},
+			})
+		}
+		// the resource usage score addon agents publish the AddOnPlacementScores to the cluster namespaces
+		if features.HubMutableFeatureGate.Enabled(mcfeature.ResourceUsageScore) {
+			clusterRoles = append(clusterRoles, &rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "open-cluster-management:managedcluster:resourceusagescore",
+				},
+				Rules: []rbacv1.PolicyRule{
+					{
+						APIGroups: []string{"cluster.open-cluster-management.io"},
+						Resources: []string{"addonplacementscores"},
+						Verbs:     []string{"get", "create"},
+					},
+					{
+						APIGroups: []string{"cluster.open-cluster-management.io"},
+						Resources: []string{"addonplacementscores/status"},
+						Verbs:     []string{"update", "patch"},
+					},
+				},
+			})
+		}
+
 		for _, clusterRole := range clusterRoles {
 			if err := prepareClusterRole(ctx, kubeClient, clusterRole); err != nil {
 				return false, err
@@ -126,7 +196,7 @@ func BuildKubeSystemResources(ctx context.Context, config server.Config, kubeCli
 					{
 						APIGroup: "rbac.authorization.k8s.io",
 						Kind:     "Group",
-						Name:     "system:bootstrappers:managedcluster",
+						Name:     ManagedClusterBootstrapGroup,
 					},
 				},
 			},
@@ -161,6 +231,18 @@ func BuildKubeSystemResources(ctx context.Context, config server.Config, kubeCli
 		klog.Errorf("failed to prepare clusterrolebindings: %v", err)
 	}
 
+	// prepare validatingadmissionpolicies
+	if err := wait.PollUntilContextCancel(ctx, 1*time.Second, true, func(ctx context.Context) (bool, error) {
+		if err := prepareAdmissionPolicies(ctx, kubeClient, disabledAdmissionPolicies); err != nil {
+			klog.Warningf("failed to prepare validatingadmissionpolicies, retrying: %v", err)
+			return false, nil
+		}
+
+		return true, nil
+	}); err != nil {
+		klog.Errorf("failed to prepare validatingadmissionpolicies: %v", err)
+	}
+
 	return nil
 }
 
@@ -199,31 +281,6 @@ func prepareClusterInfoConfigmap(config server.Config, kubeClient kubernetes.Int
 	return nil
 }
 
-func prepareBootstrapTokenSecret(ctx context.Context, kubeClient kubernetes.Interface) error {
-	tokenID := randStringRunes(6, letterRunes_az09)
-	tokenSecret := randStringRunes(16, letterRunes_az09)
-
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   fmt.Sprintf("bootstrap-token-%s", tokenID),
-			Labels: map[string]string{"app": "cluster-manager"},
-		},
-		Type: corev1.SecretTypeBootstrapToken,
-		StringData: map[string]string{
-			"token-id":                       tokenID,
-			"token-secret":                   tokenSecret,
-			"usage-bootstrap-authentication": "true",
-			"auth-extra-groups":              "system:bootstrappers:managedcluster",
-		},
-	}
-
-	if _, err := kubeClient.CoreV1().Secrets(metav1.NamespaceSystem).Create(
-		ctx, secret, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
-		return err
-	}
-	return nil
-}
-
 func prepareClusterRole(ctx context.Context, kubeClient kubernetes.Interface, required *rbacv1.ClusterRole) error {
 	existing, err := kubeClient.RbacV1().ClusterRoles().Get(ctx, required.Name, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
@@ -264,11 +321,3 @@ func prepareClusterRoleBinding(ctx context.Context, kubeClient kubernetes.Interf
 	_, err = kubeClient.RbacV1().ClusterRoleBindings().Update(ctx, required, metav1.UpdateOptions{})
 	return err
 }
-
-func randStringRunes(n int, runes []rune) string {
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = runes[rand.Intn(len(runes))]
-	}
-	return string(b)
-}
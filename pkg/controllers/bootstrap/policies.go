@@ -0,0 +1,188 @@
+// Copyright Contributors to the Open Cluster Management project
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// ManifestWorkNamespacePolicy denies the ManifestWorks that are created in the namespaces of the controlplane
+	ManifestWorkNamespacePolicy = "ocm-manifestwork-namespace"
+	// ManagedClusterNamePolicy denies the ManagedClusters whose names are not DNS-1123 labels
+	ManagedClusterNamePolicy = "ocm-managedcluster-name"
+	// ManagedClusterAcceptPolicy denies the requests that set the hubAcceptsClient of the ManagedClusters if
+	// the requester is not allowed to accept the clusters
+	ManagedClusterAcceptPolicy = "ocm-managedcluster-accept"
+)
+
+// controlplaneNamespaces are the namespaces that are used by the controlplane self, they are not cluster namespaces
+var controlplaneNamespaces = []string{
+	metav1.NamespaceDefault,
+	metav1.NamespaceSystem,
+	metav1.NamespacePublic,
+	"kube-node-lease",
+	"open-cluster-management-hub",
+}
+
+// DefaultAdmissionPolicies returns the names of the ValidatingAdmissionPolicies that are bootstrapped by default
+func DefaultAdmissionPolicies() []string {
+	return sets.List(sets.KeySet(defaultAdmissionPolicies()))
+}
+
+func defaultAdmissionPolicies() map[string]*admissionregistrationv1.ValidatingAdmissionPolicy {
+	quotedNamespaces := []string{}
+	for _, ns := range controlplaneNamespaces {
+		quotedNamespaces = append(quotedNamespaces, fmt.Sprintf("'%s'", ns))
+	}
+
+	return map[string]*admissionregistrationv1.ValidatingAdmissionPolicy{
+		ManifestWorkNamespacePolicy: newAdmissionPolicy(ManifestWorkNamespacePolicy,
+			"work.open-cluster-management.io", "manifestworks",
+			[]admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			admissionregistrationv1.Validation{
+				Expression: fmt.Sprintf("!(request.namespace in [%s])", strings.Join(quotedNamespaces, ", ")),
+				Message:    "ManifestWorks cannot be created in the namespaces of the controlplane",
+				Reason:     ptr.To(metav1.StatusReasonForbidden),
+			}),
+		ManagedClusterNamePolicy: newAdmissionPolicy(ManagedClusterNamePolicy,
+			"cluster.open-cluster-management.io", "managedclusters",
+			[]admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			admissionregistrationv1.Validation{
+				Expression: "size(object.metadata.name) <= 63 && object.metadata.name.matches('^[a-z0-9]([-a-z0-9]*[a-z0-9])?$')",
+				Message:    "the ManagedCluster name must be a DNS-1123 label",
+				Reason:     ptr.To(metav1.StatusReasonInvalid),
+			}),
+		// it is same as the check of the ManagedClusterValidating plugin, the policy keeps the check when the
+		// plugin is not in the enforce mode.
+		ManagedClusterAcceptPolicy: newAdmissionPolicy(ManagedClusterAcceptPolicy,
+			"cluster.open-cluster-management.io", "managedclusters",
+			[]admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			admissionregistrationv1.Validation{
+				Expression: "!has(object.spec) || !has(object.spec.hubAcceptsClient) || !object.spec.hubAcceptsClient || " +
+					"(oldObject != null && has(oldObject.spec) && has(oldObject.spec.hubAcceptsClient) && oldObject.spec.hubAcceptsClient) || " +
+					"authorizer.group('register.open-cluster-management.io').resource('managedclusters')." +
+					"subresource('accept').name(object.metadata.name).check('update').allowed()",
+				Message: "the user is not allowed to set the hubAcceptsClient of the ManagedCluster",
+				Reason:  ptr.To(metav1.StatusReasonForbidden),
+			}),
+	}
+}
+
+func newAdmissionPolicy(name, group, resource string, operations []admissionregistrationv1.OperationType,
+	validation admissionregistrationv1.Validation) *admissionregistrationv1.ValidatingAdmissionPolicy {
+	return &admissionregistrationv1.ValidatingAdmissionPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicySpec{
+			FailurePolicy: ptr.To(admissionregistrationv1.Fail),
+			MatchConstraints: &admissionregistrationv1.MatchResources{
+				ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{
+					{
+						RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+							Operations: operations,
+							Rule: admissionregistrationv1.Rule{
+								APIGroups:   []string{group},
+								APIVersions: []string{"*"},
+								Resources:   []string{resource},
+							},
+						},
+					},
+				},
+			},
+			Validations: []admissionregistrationv1.Validation{validation},
+		},
+	}
+}
+
+// prepareAdmissionPolicies applies the default ValidatingAdmissionPolicies and their bindings, the disabled
+// policies are removed, so a policy can be switched off after it is bootstrapped.
+func prepareAdmissionPolicies(ctx context.Context, kubeClient kubernetes.Interface, disabledPolicies []string) error {
+	disabled := sets.New(disabledPolicies...)
+	for name, policy := range defaultAdmissionPolicies() {
+		if disabled.Has(name) {
+			if err := removeAdmissionPolicy(ctx, kubeClient, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := prepareAdmissionPolicy(ctx, kubeClient, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func prepareAdmissionPolicy(ctx context.Context, kubeClient kubernetes.Interface,
+	required *admissionregistrationv1.ValidatingAdmissionPolicy) error {
+	policies := kubeClient.AdmissionregistrationV1().ValidatingAdmissionPolicies()
+	existing, err := policies.Get(ctx, required.Name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		if _, err := policies.Create(ctx, required, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !equality.Semantic.DeepEqual(existing.Spec.Validations, required.Spec.Validations) ||
+		!equality.Semantic.DeepEqual(existing.Spec.MatchConstraints.ResourceRules, required.Spec.MatchConstraints.ResourceRules):
+		// if the policy exists, update it to the latest one
+		required.ResourceVersion = existing.ResourceVersion
+		if _, err := policies.Update(ctx, required, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	requiredBinding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: required.Name,
+		},
+		Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName:        required.Name,
+			ValidationActions: []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny},
+		},
+	}
+	bindings := kubeClient.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings()
+	existingBinding, err := bindings.Get(ctx, requiredBinding.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := bindings.Create(ctx, requiredBinding, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if existingBinding.Spec.PolicyName == requiredBinding.Spec.PolicyName &&
+		equality.Semantic.DeepEqual(existingBinding.Spec.ValidationActions, requiredBinding.Spec.ValidationActions) {
+		return nil
+	}
+
+	// if the binding exists, update it to the latest one
+	requiredBinding.ResourceVersion = existingBinding.ResourceVersion
+	_, err = bindings.Update(ctx, requiredBinding, metav1.UpdateOptions{})
+	return err
+}
+
+func removeAdmissionPolicy(ctx context.Context, kubeClient kubernetes.Interface, name string) error {
+	err := kubeClient.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	err = kubeClient.AdmissionregistrationV1().ValidatingAdmissionPolicies().Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright Contributors to the Open Cluster Management project
+package bootstrap
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	plugincel "k8s.io/apiserver/pkg/admission/plugin/cel"
+	"k8s.io/apiserver/pkg/admission/plugin/policy/validating"
+	"k8s.io/apiserver/pkg/cel/environment"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAdmissionPoliciesCompile(t *testing.T) {
+	compiler := plugincel.NewCompiler(environment.MustBaseEnvSet(environment.DefaultCompatibilityVersion(), true))
+	options := plugincel.OptionalVariableDeclarations{HasAuthorizer: true, StrictCost: true}
+
+	for name, policy := range defaultAdmissionPolicies() {
+		for _, v := range policy.Spec.Validations {
+			result := compiler.CompileCELExpression(&validating.ValidationCondition{Expression: v.Expression},
+				options, environment.StoredExpressions)
+			if result.Error != nil {
+				t.Errorf("failed to compile the validation of the policy %q: %v", name, result.Error)
+			}
+		}
+	}
+}
+
+func TestPrepareAdmissionPolicies(t *testing.T) {
+	allPolicies := DefaultAdmissionPolicies()
+
+	tests := []struct {
+		name             string
+		existingObjects  []runtime.Object
+		disabledPolicies []string
+		wantPolicies     []string
+	}{
+		{
+			name:         "all of the policies are enabled",
+			wantPolicies: allPolicies,
+		},
+		{
+			name:             "the disabled policy is not created",
+			disabledPolicies: []string{ManifestWorkNamespacePolicy},
+			wantPolicies:     []string{ManagedClusterAcceptPolicy, ManagedClusterNamePolicy},
+		},
+		{
+			name: "the disabled policy is removed",
+			existingObjects: []runtime.Object{
+				defaultAdmissionPolicies()[ManagedClusterNamePolicy],
+				&admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: ManagedClusterNamePolicy},
+				},
+			},
+			disabledPolicies: []string{ManagedClusterNamePolicy, ManagedClusterAcceptPolicy},
+			wantPolicies:     []string{ManifestWorkNamespacePolicy},
+		},
+		{
+			name:             "all of the policies are disabled",
+			disabledPolicies: allPolicies,
+			wantPolicies:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			kubeClient := kubefake.NewSimpleClientset(tt.existingObjects...)
+			if err := prepareAdmissionPolicies(ctx, kubeClient, tt.disabledPolicies); err != nil {
+				t.Fatalf("prepareAdmissionPolicies() error = %v", err)
+			}
+
+			policies, err := kubeClient.AdmissionregistrationV1().ValidatingAdmissionPolicies().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			policyNames := sets.New[string]()
+			for _, policy := range policies.Items {
+				policyNames.Insert(policy.Name)
+			}
+			if !reflect.DeepEqual(sets.List(policyNames), tt.wantPolicies) {
+				t.Errorf("the policies = %v, want %v", sets.List(policyNames), tt.wantPolicies)
+			}
+
+			bindings, err := kubeClient.AdmissionregistrationV1().ValidatingAdmissionPolicyBindings().List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			bindingNames := sets.New[string]()
+			for _, binding := range bindings.Items {
+				if binding.Spec.PolicyName != binding.Name {
+					t.Errorf("the binding %q binds the policy %q", binding.Name, binding.Spec.PolicyName)
+				}
+				bindingNames.Insert(binding.Name)
+			}
+			if !bindingNames.Equal(policyNames) {
+				t.Errorf("the bindings = %v, want %v", sets.List(bindingNames), tt.wantPolicies)
+			}
+		})
+	}
+}
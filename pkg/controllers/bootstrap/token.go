@@ -0,0 +1,306 @@
+// Copyright Contributors to the Open Cluster Management project
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+	tokenapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/cmd/kubeadm/app/util/pubkeypin"
+)
+
+const (
+	// ManagedClusterBootstrapGroup is the group of the bootstrap tokens, the group is bound to the cluster role
+	// that allows the managed clusters to register themselves.
+	ManagedClusterBootstrapGroup = "system:bootstrappers:managedcluster"
+
+	// DefaultBootstrapTokenTTL is the default TTL of the bootstrap tokens
+	DefaultBootstrapTokenTTL = 24 * time.Hour
+
+	// the label is also used by clusteradm to find the bootstrap token
+	bootstrapTokenLabelKey   = "app"
+	bootstrapTokenLabelValue = "cluster-manager"
+	// the label marks the tokens that are created by CreateBootstrapToken, so the never-expiring default tokens of
+	// the previous controlplane versions, which do not have it, can be told apart
+	bootstrapTokenCreatorLabelKey   = "multicluster-controlplane.open-cluster-management.io/bootstrap-token"
+	bootstrapTokenCreatorLabelValue = "true"
+
+	defaultBootstrapTokenDescription = "The default bootstrap token created by the multicluster controlplane."
+)
+
+// BootstrapTokenOptions is the options to create a bootstrap token
+type BootstrapTokenOptions struct {
+	// TTL is the duration before the token is expired, the token never expires if the TTL is 0
+	TTL time.Duration
+	// Description is the human friendly description of how the token is used
+	Description string
+	// ExtraGroups are the groups that the token authenticates as in addition to the managed cluster bootstrap group,
+	// the group must start with system:bootstrappers:
+	ExtraGroups []string
+}
+
+// BootstrapToken is the bootstrap token that can be used by the managed clusters to join the controlplane
+type BootstrapToken struct {
+	ID          string
+	Token       string
+	Description string
+	Expiration  *time.Time
+	Groups      []string
+
+	// legacy is true for the never-expiring default tokens that are created by the previous controlplane versions
+	legacy bool
+}
+
+// Expired returns true if the token is expired at the given time
+func (t BootstrapToken) Expired(now time.Time) bool {
+	return t.Expiration != nil && !now.Before(*t.Expiration)
+}
+
+// CreateBootstrapToken creates a new bootstrap token in the kube-system namespace
+func CreateBootstrapToken(ctx context.Context, kubeClient kubernetes.Interface, opts BootstrapTokenOptions) (*BootstrapToken, error) {
+	if opts.TTL < 0 {
+		return nil, fmt.Errorf("the token TTL %v is invalid", opts.TTL)
+	}
+
+	groups := []string{ManagedClusterBootstrapGroup}
+	for _, group := range opts.ExtraGroups {
+		if err := bootstraputil.ValidateBootstrapGroupName(group); err != nil {
+			return nil, err
+		}
+		if group == ManagedClusterBootstrapGroup {
+			continue
+		}
+		groups = append(groups, group)
+	}
+
+	token, err := bootstraputil.GenerateBootstrapToken()
+	if err != nil {
+		return nil, err
+	}
+	// the token format is validated by the GenerateBootstrapToken
+	parts := strings.Split(token, ".")
+	tokenID, tokenSecret := parts[0], parts[1]
+
+	data := map[string]string{
+		tokenapi.BootstrapTokenIDKey:               tokenID,
+		tokenapi.BootstrapTokenSecretKey:           tokenSecret,
+		tokenapi.BootstrapTokenUsageAuthentication: "true",
+		tokenapi.BootstrapTokenExtraGroupsKey:      strings.Join(groups, ","),
+	}
+	if len(opts.Description) != 0 {
+		data[tokenapi.BootstrapTokenDescriptionKey] = opts.Description
+	}
+
+	var expiration *time.Time
+	if opts.TTL > 0 {
+		expiredAt := time.Now().Add(opts.TTL).UTC()
+		expiration = &expiredAt
+		data[tokenapi.BootstrapTokenExpirationKey] = expiredAt.Format(time.RFC3339)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: bootstraputil.BootstrapTokenSecretName(tokenID),
+			Labels: map[string]string{
+				bootstrapTokenLabelKey:        bootstrapTokenLabelValue,
+				bootstrapTokenCreatorLabelKey: bootstrapTokenCreatorLabelValue,
+			},
+		},
+		Type:       corev1.SecretTypeBootstrapToken,
+		StringData: data,
+	}
+
+	if _, err := kubeClient.CoreV1().Secrets(metav1.NamespaceSystem).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return nil, err
+	}
+
+	return &BootstrapToken{
+		ID:          tokenID,
+		Token:       token,
+		Description: opts.Description,
+		Expiration:  expiration,
+		Groups:      groups,
+	}, nil
+}
+
+// ListBootstrapTokens lists the bootstrap tokens that are created by the controlplane
+func ListBootstrapTokens(ctx context.Context, kubeClient kubernetes.Interface) ([]BootstrapToken, error) {
+	secrets, err := kubeClient.CoreV1().Secrets(metav1.NamespaceSystem).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{bootstrapTokenLabelKey: bootstrapTokenLabelValue}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []BootstrapToken{}
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeBootstrapToken {
+			continue
+		}
+
+		token, err := toBootstrapToken(&secret)
+		if err != nil {
+			klog.Warningf("ignore the invalid bootstrap token secret %s, %v", secret.Name, err)
+			continue
+		}
+		tokens = append(tokens, *token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+// RevokeBootstrapToken deletes the bootstrap token with the given token id, the token can be either the
+// token id or the whole token.
+func RevokeBootstrapToken(ctx context.Context, kubeClient kubernetes.Interface, token string) error {
+	tokenID := strings.Split(token, ".")[0]
+	if !bootstraputil.IsValidBootstrapTokenID(tokenID) {
+		return fmt.Errorf("the token id %q is invalid", tokenID)
+	}
+
+	err := kubeClient.CoreV1().Secrets(metav1.NamespaceSystem).Delete(
+		ctx, bootstraputil.BootstrapTokenSecretName(tokenID), metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("the bootstrap token %q is not found", tokenID)
+	}
+	return err
+}
+
+// GetClusterInfo returns the apiserver address and the CA cert hashes of the controlplane from the
+// cluster-info configmap, the CA cert hashes are used by the managed clusters to validate the
+// controlplane when they join the controlplane with a bootstrap token.
+func GetClusterInfo(ctx context.Context, kubeClient kubernetes.Interface) (string, []string, error) {
+	cm, err := kubeClient.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(ctx, tokenapi.ConfigMapClusterInfo, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return ParseClusterInfo(cm)
+}
+
+// ParseClusterInfo parses the apiserver address and the CA cert hashes from the cluster-info configmap
+func ParseClusterInfo(cm *corev1.ConfigMap) (string, []string, error) {
+	config, err := clientcmd.Load([]byte(cm.Data[tokenapi.KubeConfigKey]))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load the kubeconfig of the cluster-info, %v", err)
+	}
+
+	for _, cluster := range config.Clusters {
+		certs, err := certutil.ParseCertsPEM(cluster.CertificateAuthorityData)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse the CA of the cluster-info, %v", err)
+		}
+
+		hashes := []string{}
+		for _, cert := range certs {
+			hashes = append(hashes, pubkeypin.Hash(cert))
+		}
+		return cluster.Server, hashes, nil
+	}
+
+	return "", nil, fmt.Errorf("no cluster is found in the cluster-info")
+}
+
+// JoinCommand returns the command that joins a cluster to the controlplane with the bootstrap token, the agent
+// builds its bootstrap kubeconfig from the hub apiserver, the token and the CA cert hashes.
+func JoinCommand(server, token string, caCertHashes []string) string {
+	args := []string{
+		"controlplane agent",
+		"--cluster-name=<cluster name>",
+		"--kubeconfig=<managed cluster kubeconfig file>",
+		fmt.Sprintf("--hub-apiserver=%s", server),
+		fmt.Sprintf("--bootstrap-token=%s", token),
+	}
+	for _, hash := range caCertHashes {
+		args = append(args, fmt.Sprintf("--discovery-token-ca-cert-hash=%s", hash))
+	}
+	return strings.Join(args, " ")
+}
+
+// ensureDefaultBootstrapToken creates the default bootstrap token if there is no default token that is valid for
+// longer than the renewBefore, so the new token is created before the previous one is expired and there is always
+// a valid default token. The never-expiring default tokens of the previous controlplane versions are kept, they
+// may be used by the clusters that are joining, so they are only reported and should be revoked by the admin.
+func ensureDefaultBootstrapToken(ctx context.Context, kubeClient kubernetes.Interface,
+	ttl, renewBefore time.Duration) error {
+	tokens, err := ListBootstrapTokens(ctx, kubeClient)
+	if err != nil {
+		return err
+	}
+
+	renewAt := time.Now().Add(renewBefore)
+	created := false
+	for _, token := range tokens {
+		if token.Description == defaultBootstrapTokenDescription && !token.Expired(renewAt) {
+			created = true
+			break
+		}
+	}
+
+	if !created {
+		token, err := CreateBootstrapToken(ctx, kubeClient, BootstrapTokenOptions{
+			TTL:         ttl,
+			Description: defaultBootstrapTokenDescription,
+		})
+		if err != nil {
+			return err
+		}
+		klog.Infof("the default bootstrap token %s is created", token.ID)
+	}
+
+	for _, token := range tokens {
+		if !token.legacy {
+			continue
+		}
+
+		klog.Warningf("the never-expiring default bootstrap token %s is created by a previous controlplane version, "+
+			"revoke it with the controlplane token command once it is not used to join the clusters", token.ID)
+	}
+	return nil
+}
+
+func toBootstrapToken(secret *corev1.Secret) (*BootstrapToken, error) {
+	tokenID := string(secret.Data[tokenapi.BootstrapTokenIDKey])
+	tokenSecret := string(secret.Data[tokenapi.BootstrapTokenSecretKey])
+	token := bootstraputil.TokenFromIDAndSecret(tokenID, tokenSecret)
+	if !bootstraputil.IsValidBootstrapToken(token) {
+		return nil, fmt.Errorf("the token is invalid")
+	}
+
+	bootstrapToken := &BootstrapToken{
+		ID:          tokenID,
+		Token:       token,
+		Description: string(secret.Data[tokenapi.BootstrapTokenDescriptionKey]),
+	}
+
+	if expiration := string(secret.Data[tokenapi.BootstrapTokenExpirationKey]); len(expiration) != 0 {
+		expiredAt, err := time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			return nil, fmt.Errorf("the token expiration %q is invalid, %v", expiration, err)
+		}
+		bootstrapToken.Expiration = &expiredAt
+	}
+
+	if groups := string(secret.Data[tokenapi.BootstrapTokenExtraGroupsKey]); len(groups) != 0 {
+		bootstrapToken.Groups = strings.Split(groups, ",")
+	}
+
+	// the previous versions created the default token without a description and an expiration
+	bootstrapToken.legacy = secret.Labels[bootstrapTokenCreatorLabelKey] != bootstrapTokenCreatorLabelValue &&
+		len(bootstrapToken.Description) == 0 && bootstrapToken.Expiration == nil &&
+		len(bootstrapToken.Groups) == 1 && bootstrapToken.Groups[0] == ManagedClusterBootstrapGroup
+
+	return bootstrapToken, nil
+}
@@ -0,0 +1,217 @@
+// Copyright Contributors to the Open Cluster Management project
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	tokenapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/agent"
+)
+
+func newTokenSecret(tokenID string, labels map[string]string, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bootstraputil.BootstrapTokenSecretName(tokenID),
+			Namespace: metav1.NamespaceSystem,
+			Labels:    labels,
+		},
+		Type: corev1.SecretTypeBootstrapToken,
+		Data: map[string][]byte{
+			tokenapi.BootstrapTokenIDKey:               []byte(tokenID),
+			tokenapi.BootstrapTokenSecretKey:           []byte("0123456789abcdef"),
+			tokenapi.BootstrapTokenUsageAuthentication: []byte("true"),
+			tokenapi.BootstrapTokenExtraGroupsKey:      []byte(ManagedClusterBootstrapGroup),
+		},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func TestEnsureDefaultBootstrapToken(t *testing.T) {
+	legacyLabels := map[string]string{bootstrapTokenLabelKey: bootstrapTokenLabelValue}
+	labels := map[string]string{
+		bootstrapTokenLabelKey:        bootstrapTokenLabelValue,
+		bootstrapTokenCreatorLabelKey: bootstrapTokenCreatorLabelValue,
+	}
+	validUntil := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	expiringAt := time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)
+	expiredAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name        string
+		secrets     []runtime.Object
+		wantCreated bool
+	}{
+		{
+			name:        "no tokens",
+			wantCreated: true,
+		},
+		{
+			name: "valid default token",
+			secrets: []runtime.Object{
+				newTokenSecret("abcdef", labels, map[string]string{
+					tokenapi.BootstrapTokenDescriptionKey: defaultBootstrapTokenDescription,
+					tokenapi.BootstrapTokenExpirationKey:  validUntil,
+				}),
+			},
+		},
+		{
+			name: "default token is expiring",
+			secrets: []runtime.Object{
+				newTokenSecret("abcdef", labels, map[string]string{
+					tokenapi.BootstrapTokenDescriptionKey: defaultBootstrapTokenDescription,
+					tokenapi.BootstrapTokenExpirationKey:  expiringAt,
+				}),
+			},
+			wantCreated: true,
+		},
+		{
+			name: "expired default token",
+			secrets: []runtime.Object{
+				newTokenSecret("abcdef", labels, map[string]string{
+					tokenapi.BootstrapTokenDescriptionKey: defaultBootstrapTokenDescription,
+					tokenapi.BootstrapTokenExpirationKey:  expiredAt,
+				}),
+			},
+			wantCreated: true,
+		},
+		{
+			name: "legacy default token",
+			secrets: []runtime.Object{
+				newTokenSecret("legacy", legacyLabels, nil),
+			},
+			wantCreated: true,
+		},
+		{
+			name: "legacy default token with a valid default token",
+			secrets: []runtime.Object{
+				newTokenSecret("abcdef", labels, map[string]string{
+					tokenapi.BootstrapTokenDescriptionKey: defaultBootstrapTokenDescription,
+					tokenapi.BootstrapTokenExpirationKey:  validUntil,
+				}),
+				newTokenSecret("legacy", legacyLabels, nil),
+			},
+		},
+		{
+			name: "never-expiring token that is created by the token command",
+			secrets: []runtime.Object{
+				newTokenSecret("abcdef", labels, map[string]string{
+					tokenapi.BootstrapTokenDescriptionKey: defaultBootstrapTokenDescription,
+					tokenapi.BootstrapTokenExpirationKey:  validUntil,
+				}),
+				newTokenSecret("ghijkl", labels, nil),
+			},
+		},
+		{
+			name: "never-expiring token with extra groups",
+			secrets: []runtime.Object{
+				newTokenSecret("abcdef", labels, map[string]string{
+					tokenapi.BootstrapTokenDescriptionKey: defaultBootstrapTokenDescription,
+					tokenapi.BootstrapTokenExpirationKey:  validUntil,
+				}),
+				newTokenSecret("ghijkl", legacyLabels, map[string]string{
+					tokenapi.BootstrapTokenExtraGroupsKey: ManagedClusterBootstrapGroup + ",system:bootstrappers:dev",
+				}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(tt.secrets...)
+
+			if err := ensureDefaultBootstrapToken(context.TODO(), kubeClient,
+				DefaultBootstrapTokenTTL, 20*time.Minute); err != nil {
+				t.Fatalf("ensureDefaultBootstrapToken() error = %v", err)
+			}
+
+			created := false
+			for _, action := range kubeClient.Actions() {
+				switch action := action.(type) {
+				case clienttesting.CreateAction:
+					secret := action.GetObject().(*corev1.Secret)
+					if secret.StringData[tokenapi.BootstrapTokenDescriptionKey] != defaultBootstrapTokenDescription ||
+						len(secret.StringData[tokenapi.BootstrapTokenExpirationKey]) == 0 {
+						t.Errorf("the created token %v is not the default token with the TTL", secret.StringData)
+					}
+					created = true
+				case clienttesting.DeleteAction:
+					t.Errorf("the token %s is revoked", action.GetName())
+				}
+			}
+			if created != tt.wantCreated {
+				t.Errorf("ensureDefaultBootstrapToken() created = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestJoinCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		server       string
+		token        string
+		caCertHashes []string
+	}{
+		{
+			name:         "one CA",
+			server:       "https://controlplane.example.com:443",
+			token:        "abcdef.0123456789abcdef",
+			caCertHashes: []string{"sha256:1111"},
+		},
+		{
+			name:         "rotated CA",
+			server:       "https://127.0.0.1:9443",
+			token:        "ghijkl.0123456789abcdef",
+			caCertHashes: []string{"sha256:1111", "sha256:2222"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := strings.NewReplacer(
+				"<cluster name>", "cluster1",
+				"<managed cluster kubeconfig file>", "/tmp/kubeconfig",
+			).Replace(JoinCommand(tt.server, tt.token, tt.caCertHashes))
+
+			if !strings.HasPrefix(command, "controlplane agent ") {
+				t.Fatalf("JoinCommand() = %q, want the agent command", command)
+			}
+
+			// the join command is parsed with the flags of the agent
+			agentOptions := agent.NewAgentOptions()
+			fs := pflag.NewFlagSet("agent", pflag.ContinueOnError)
+			agentOptions.AddFlags(fs)
+			if err := fs.Parse(strings.Fields(strings.TrimPrefix(command, "controlplane agent "))); err != nil {
+				t.Fatalf("JoinCommand() = %q, error = %v", command, err)
+			}
+
+			if agentOptions.CommonOpts.SpokeClusterName != "cluster1" {
+				t.Errorf("the cluster name = %q, want %q", agentOptions.CommonOpts.SpokeClusterName, "cluster1")
+			}
+			if agentOptions.KubeConfig != "/tmp/kubeconfig" {
+				t.Errorf("the kubeconfig = %q, want %q", agentOptions.KubeConfig, "/tmp/kubeconfig")
+			}
+			if agentOptions.HubAPIServer != tt.server {
+				t.Errorf("the hub apiserver = %q, want %q", agentOptions.HubAPIServer, tt.server)
+			}
+			if agentOptions.BootstrapToken != tt.token {
+				t.Errorf("the bootstrap token = %q, want %q", agentOptions.BootstrapToken, tt.token)
+			}
+			if strings.Join(agentOptions.DiscoveryTokenCACertHashes, ",") != strings.Join(tt.caCertHashes, ",") {
+				t.Errorf("the CA cert hashes = %v, want %v", agentOptions.DiscoveryTokenCACertHashes, tt.caCertHashes)
+			}
+		})
+	}
+}
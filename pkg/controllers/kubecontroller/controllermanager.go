@@ -186,7 +186,6 @@ var _ ControllerInitializersFunc = NewControllerInitializers
 // ControllersDisabledByDefault is the set of controllers which is disabled by default
 var ControllersDisabledByDefault = sets.NewString(
 	"bootstrapsigner",
-	"tokencleaner",
 )
 
 const (
@@ -200,6 +199,7 @@ func NewControllerInitializers() map[string]InitFunc {
 	controllers["namespace"] = startNamespaceController
 	controllers["serviceaccount"] = startServiceAccountController
 	controllers["garbagecollector"] = startGarbageCollectorController
+	controllers["resourcequota"] = startResourceQuotaController
 	controllers["csrsigning"] = startCSRSigningController
 	controllers["csrapproving"] = startCSRApprovingController
 	controllers["csrcleaner"] = startCSRCleanerController
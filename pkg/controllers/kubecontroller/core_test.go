@@ -0,0 +1,154 @@
+// Copyright Contributors to the Open Cluster Management project
+package kubecontroller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/metadata/metadatainformer"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/controller-manager/pkg/informerfactory"
+	kubectrlmgrconfig "k8s.io/kubernetes/pkg/controller/apis/config"
+	resourcequotaconfig "k8s.io/kubernetes/pkg/controller/resourcequota/config"
+)
+
+const manifestWorkCountResource = "count/manifestworks.work.open-cluster-management.io"
+
+// fakeDiscovery returns the preferred resources, which are not returned by the FakeDiscovery
+type fakeDiscovery struct {
+	*fakediscovery.FakeDiscovery
+}
+
+func (d *fakeDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return d.Resources, nil
+}
+
+func (d *fakeDiscovery) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	return discovery.ServerPreferredNamespacedResources(d)
+}
+
+// fakeClientBuilder returns the fake clients for the controllers
+type fakeClientBuilder struct {
+	client    clientset.Interface
+	discovery discovery.DiscoveryInterface
+}
+
+func (b *fakeClientBuilder) Config(_ string) (*restclient.Config, error) {
+	return &restclient.Config{}, nil
+}
+
+func (b *fakeClientBuilder) ConfigOrDie(_ string) *restclient.Config {
+	return &restclient.Config{}
+}
+
+func (b *fakeClientBuilder) Client(_ string) (clientset.Interface, error) {
+	return b.client, nil
+}
+
+func (b *fakeClientBuilder) ClientOrDie(_ string) clientset.Interface {
+	return b.client
+}
+
+func (b *fakeClientBuilder) DiscoveryClient(_ string) (discovery.DiscoveryInterface, error) {
+	return b.discovery, nil
+}
+
+func (b *fakeClientBuilder) DiscoveryClientOrDie(_ string) discovery.DiscoveryInterface {
+	return b.discovery
+}
+
+func newManifestWorkMetadata(namespace, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "work.open-cluster-management.io/v1", Kind: "ManifestWork"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}
+}
+
+func TestResourceQuotaController(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	kubeClient := kubefake.NewSimpleClientset(&v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cluster1", Name: "manifestworks"},
+		Spec: v1.ResourceQuotaSpec{
+			Hard: v1.ResourceList{manifestWorkCountResource: resource.MustParse("10")},
+		},
+	})
+	kubeClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "work.open-cluster-management.io/v1",
+			APIResources: []metav1.APIResource{{
+				Name:       "manifestworks",
+				Namespaced: true,
+				Kind:       "ManifestWork",
+				Verbs:      metav1.Verbs{"create", "delete", "get", "list", "watch"},
+			}},
+		},
+	}
+
+	scheme := fake.NewTestScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	metadataClient := fake.NewSimpleMetadataClient(scheme,
+		newManifestWorkMetadata("cluster1", "work1"),
+		newManifestWorkMetadata("cluster1", "work2"),
+		newManifestWorkMetadata("cluster2", "work1"),
+	)
+
+	resyncPeriod := func() time.Duration { return time.Minute }
+	kubeInformers := informers.NewSharedInformerFactory(kubeClient, 0)
+	metadataInformers := metadatainformer.NewSharedInformerFactory(metadataClient, 0)
+	controllerContext := ControllerContext{
+		ClientBuilder: &fakeClientBuilder{
+			client:    kubeClient,
+			discovery: &fakeDiscovery{FakeDiscovery: kubeClient.Discovery().(*fakediscovery.FakeDiscovery)},
+		},
+		InformerFactory:                 kubeInformers,
+		ObjectOrMetadataInformerFactory: informerfactory.NewInformerFactory(kubeInformers, metadataInformers),
+		ComponentConfig: kubectrlmgrconfig.KubeControllerManagerConfiguration{
+			ResourceQuotaController: resourcequotaconfig.ResourceQuotaControllerConfiguration{
+				ResourceQuotaSyncPeriod:      metav1.Duration{Duration: time.Minute},
+				ConcurrentResourceQuotaSyncs: 1,
+			},
+		},
+		InformersStarted: make(chan struct{}),
+		ResyncPeriod:     resyncPeriod,
+	}
+
+	if _, _, err := startResourceQuotaController(ctx, controllerContext); err != nil {
+		t.Fatalf("startResourceQuotaController() error = %v", err)
+	}
+	kubeInformers.Start(ctx.Done())
+	metadataInformers.Start(ctx.Done())
+	close(controllerContext.InformersStarted)
+
+	// the ManifestWorks in the namespace of the quota are counted
+	var used resource.Quantity
+	if err := wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, 30*time.Second, true,
+		func(ctx context.Context) (bool, error) {
+			quota, err := kubeClient.CoreV1().ResourceQuotas("cluster1").Get(ctx, "manifestworks", metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			var ok bool
+			used, ok = quota.Status.Used[manifestWorkCountResource]
+			return ok, nil
+		}); err != nil {
+		t.Fatalf("the usage of %s is not computed, %v", manifestWorkCountResource, err)
+	}
+	if used.Value() != 2 {
+		t.Errorf("the usage of %s = %s, want 2", manifestWorkCountResource, used.String())
+	}
+}
@@ -28,6 +28,7 @@ type KubeControllerManagerOptions struct {
 	CSRSigningController       *CSRSigningControllerOptions
 	GarbageCollectorController *GarbageCollectorControllerOptions
 	NamespaceController        *NamespaceControllerOptions
+	ResourceQuotaController    *ResourceQuotaControllerOptions
 	SAController               *SAControllerOptions
 
 	Metrics *metrics.Options
@@ -55,6 +56,9 @@ func NewKubeControllerManagerOptions() (*KubeControllerManagerOptions, error) {
 		NamespaceController: &NamespaceControllerOptions{
 			&componentConfig.NamespaceController,
 		},
+		ResourceQuotaController: &ResourceQuotaControllerOptions{
+			&componentConfig.ResourceQuotaController,
+		},
 		SAController: &SAControllerOptions{
 			&componentConfig.SAController,
 		},
@@ -91,6 +95,7 @@ func (s *KubeControllerManagerOptions) Flags() cliflag.NamedFlagSets {
 	s.CSRSigningController.AddFlags(fss.FlagSet("csrsigning controller"))
 	s.GarbageCollectorController.AddFlags(fss.FlagSet("garbagecollector controller"))
 	s.NamespaceController.AddFlags(fss.FlagSet("namespace controller"))
+	s.ResourceQuotaController.AddFlags(fss.FlagSet("resourcequota controller"))
 	s.SAController.AddFlags(fss.FlagSet("serviceaccount controller"))
 
 	return fss
@@ -111,6 +116,9 @@ func (s *KubeControllerManagerOptions) ApplyTo(c *kubecontrollerconfig.Config) e
 	if err := s.NamespaceController.ApplyTo(&c.ComponentConfig.NamespaceController); err != nil {
 		return err
 	}
+	if err := s.ResourceQuotaController.ApplyTo(&c.ComponentConfig.ResourceQuotaController); err != nil {
+		return err
+	}
 	if err := s.SAController.ApplyTo(&c.ComponentConfig.SAController); err != nil {
 		return err
 	}
@@ -125,6 +133,7 @@ func (s *KubeControllerManagerOptions) Validate() error {
 	errs = append(errs, s.CSRSigningController.Validate()...)
 	errs = append(errs, s.GarbageCollectorController.Validate()...)
 	errs = append(errs, s.NamespaceController.Validate()...)
+	errs = append(errs, s.ResourceQuotaController.Validate()...)
 	errs = append(errs, s.SAController.Validate()...)
 
 	return utilerrors.NewAggregate(errs)
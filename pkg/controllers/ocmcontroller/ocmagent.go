@@ -7,22 +7,27 @@ import (
 	"path"
 	"time"
 
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/meta"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	aggregatorapiserver "k8s.io/kube-aggregator/pkg/apiserver"
 
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
-	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
+	"open-cluster-management.io/ocm/pkg/registration/register"
 	"open-cluster-management.io/sdk-go/pkg/helpers"
 
 	"open-cluster-management.io/multicluster-controlplane/pkg/agent"
 	"open-cluster-management.io/multicluster-controlplane/pkg/certificate"
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/bootstrap"
 	"open-cluster-management.io/multicluster-controlplane/pkg/servers/options"
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 )
@@ -37,6 +42,11 @@ type ClusterInfo struct {
 
 func InstallSelfManagementCluster(options options.ServerRunOptions) func(<-chan struct{}, *aggregatorapiserver.Config) error {
 	return func(stopCh <-chan struct{}, aggregatorConfig *aggregatorapiserver.Config) error {
+		ctx := util.GoContext(stopCh)
+		hubRestConfig := aggregatorConfig.GenericConfig.LoopbackClientConfig
+		hubRestConfig.ContentType = "application/json"
+
+		// the self managed cluster can only be created in a cluster, so there is nothing to clean up either
 		inClusterConfig, err := rest.InClusterConfig()
 		if err != nil {
 			klog.Warning("Current runtime environment is not in a cluster, ignore --self-management flag.")
@@ -44,14 +54,10 @@ func InstallSelfManagementCluster(options options.ServerRunOptions) func(<-chan
 		}
 
 		if !options.EnableSelfManagement {
-			// TODO if there is a self management cluster, cleanup its resources
+			go DisableSelfManagement(ctx, hubRestConfig, inClusterConfig, options.ControlplaneDataDir)
 			return nil
 		}
 
-		ctx := util.GoContext(stopCh)
-		hubRestConfig := aggregatorConfig.GenericConfig.LoopbackClientConfig
-		hubRestConfig.ContentType = "application/json"
-
 		clusterName := options.SelfManagementClusterName
 		if len(clusterName) == 0 {
 			clusterName, err = util.GenerateSelfManagedClusterName(ctx, inClusterConfig)
@@ -79,111 +85,186 @@ func InstallSelfManagementCluster(options options.ServerRunOptions) func(<-chan
 			CABundle:    caBundle,
 		}
 
-		go EnableSelfManagement(ctx, hubRestConfig, options.ControlplaneDataDir, &selfClusterInfo)
+		go EnableSelfManagement(ctx, hubRestConfig, inClusterConfig, options.ControlplaneDataDir, &selfClusterInfo)
 
 		return nil
 	}
 }
 
-func EnableSelfManagement(ctx context.Context, hubRestConfig *rest.Config, controlplaneCertDir string, selfClusterInfo *ClusterInfo) {
-	kubeClient, err := kubernetes.NewForConfig(hubRestConfig)
+// EnableSelfManagement runs a controller to maintain the self managed cluster, and starts the agent on the
+// current cluster after the self managed cluster is accepted.
+func EnableSelfManagement(ctx context.Context, hubRestConfig, inClusterConfig *rest.Config,
+	controlplaneDataDir string, selfClusterInfo *ClusterInfo) {
+	clients, err := newSelfManagementClients(ctx, hubRestConfig, inClusterConfig)
 	if err != nil {
-		klog.Fatalf("Failed to kube client, %v", err)
+		klog.Fatalf("Failed to build self management clients, %v", err)
 	}
 
-	clusterClient, err := clusterclient.NewForConfig(hubRestConfig)
-	if err != nil {
-		klog.Fatalf("Failed to cluster client, %v", err)
+	bootstrapKubeConfig := path.Join(controlplaneDataDir, "cert", certificate.InclusterKubeconfigFileName)
+	agentDir := path.Join(controlplaneDataDir, "agent")
+	agentHubKubeconfigDir := path.Join(agentDir, "hub-kubeconfig")
+
+	// the agent options are built for each run, since the agent is restarted if the self managed cluster is
+	// recreated
+	newKlusterletAgent := func() *agent.AgentOptions {
+		// TODO also need provide feature gates
+		return agent.NewAgentOptions().
+			WithClusterName(selfClusterInfo.ClusterName).
+			WithBootstrapKubeconfig(bootstrapKubeConfig).
+			WithHubKubeconfigDir(agentHubKubeconfigDir).
+			WithWorkloadSourceDriverConfig(agentHubKubeconfigDir + "/kubeconfig")
 	}
+	klusterletAgent := newKlusterletAgent()
 
-	if err := createNamespace(ctx, kubeClient, selfClusterInfo.ClusterName); err != nil {
-		klog.Fatalf("Failed to create self managed cluster namespace, %v", err)
+	// the agent dir is owned by the self management, so it can be cleaned up once the self management is disabled
+	if err := markAgentDir(agentDir); err != nil {
+		klog.Fatalf("Failed to mark the agent dir %s, %v", agentDir, err)
 	}
 
-	// TODO need a controller to maintain the self managed cluster
-	if err := waitForSelfManagedCluster(ctx, clusterClient, selfClusterInfo); err != nil {
-		klog.Fatalf("Failed to create self managed cluster, %v", err)
+	// the agent state belongs to a previous self managed cluster, clean it up to register the cluster again
+	lastClusterName, err := os.ReadFile(path.Join(agentHubKubeconfigDir, register.ClusterNameFile))
+	if err != nil && !os.IsNotExist(err) {
+		klog.Fatalf("Failed to read the cluster name of the agent, %v", err)
+	}
+	if len(lastClusterName) != 0 && string(lastClusterName) != selfClusterInfo.ClusterName {
+		klog.Infof("The self managed cluster name is changed from %s to %s, cleanup the agent state",
+			string(lastClusterName), selfClusterInfo.ClusterName)
+		if err := cleanupAgentState(ctx, clients.inClusterKubeClient, agentDir,
+			klusterletAgent.CommonOpts.ComponentNamespace,
+			klusterletAgent.RegistrationAgentOpts.HubKubeconfigSecret); err != nil {
+			klog.Fatalf("Failed to cleanup the agent state, %v", err)
+		}
+		if err := markAgentDir(agentDir); err != nil {
+			klog.Fatalf("Failed to mark the agent dir %s, %v", agentDir, err)
+		}
 	}
 
-	bootstrapKubeConfig := path.Join(controlplaneCertDir, "cert", certificate.InclusterKubeconfigFileName)
-	agentHubKubeconfigDir := path.Join(controlplaneCertDir, "agent", "hub-kubeconfig")
 	if err := os.MkdirAll(agentHubKubeconfigDir, os.ModePerm); err != nil {
 		klog.Fatalf("Failed to create dir %s, %v", agentHubKubeconfigDir, err)
 	}
 
-	// TODO also need provide feature gates
-	klusterletAgent := agent.NewAgentOptions().
-		WithClusterName(selfClusterInfo.ClusterName).
-		WithBootstrapKubeconfig(bootstrapKubeConfig).
-		WithHubKubeconfigDir(agentHubKubeconfigDir).
-		WithWorkloadSourceDriverConfig(agentHubKubeconfigDir + "/kubeconfig")
+	controller := newSelfManagementController(
+		clients.kubeClient,
+		clients.clusterClient,
+		clients.workClient,
+		clients.clusterInformers.Cluster().V1().ManagedClusters().Informer(),
+		clients.clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+		selfClusterInfo,
+		func(agentCtx context.Context) {
+			go func() {
+				// the agent is stopped by the controller if the self managed cluster is recreated
+				if err := newKlusterletAgent().RunAgent(agentCtx); err != nil && agentCtx.Err() == nil {
+					klog.Fatalf("failed to start agents, %v", err)
+				}
+			}()
+		},
+		util.NewLoggingRecorder("self-management-controller"),
+	)
 
-	if err := klusterletAgent.RunAgent(ctx); err != nil {
-		klog.Fatalf("failed to start agents, %v", err)
-	}
+	go clients.clusterInformers.Start(ctx.Done())
+	controller.Run(ctx, 1)
 }
 
-func createNamespace(ctx context.Context, kubeClient kubernetes.Interface, ns string) error {
-	_, err := kubeClient.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		_, err := kubeClient.CoreV1().Namespaces().Create(
-			ctx,
-			&corev1.Namespace{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: ns,
-				},
-			},
-			metav1.CreateOptions{},
-		)
-		return err
+// DisableSelfManagement deregisters the self managed clusters from the controlplane, and cleans up the local
+// state of the self managed cluster agent if it is created by the self management. Nothing is cleaned up if there
+// is no self managed cluster.
+func DisableSelfManagement(ctx context.Context, hubRestConfig, inClusterConfig *rest.Config, controlplaneDataDir string) {
+	clients, err := newSelfManagementClients(ctx, hubRestConfig, inClusterConfig)
+	if err != nil {
+		klog.Fatalf("Failed to build self management clients, %v", err)
 	}
 
-	return err
+	clusterInformer := clients.clusterInformers.Cluster().V1().ManagedClusters()
+	clusterLister := clusterInformer.Lister()
+	go clusterInformer.Informer().Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), clusterInformer.Informer().HasSynced) {
+		return
+	}
+
+	if err := disableSelfManagement(ctx, clients, clusterLister, path.Join(controlplaneDataDir, "agent")); err != nil {
+		klog.Errorf("Failed to disable self management, %v", err)
+	}
 }
 
-func waitForSelfManagedCluster(ctx context.Context, clusterClient clusterclient.Interface, selfClusterInfo *ClusterInfo) error {
+func disableSelfManagement(ctx context.Context, clients *selfManagementClients,
+	clusterLister clusterlisterv1.ManagedClusterLister, agentDir string) error {
+	clusters, err := clusterLister.List(labels.SelectorFromSet(labels.Set{SelfManagementClusterLabel: ""}))
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		klog.V(4).Infof("There is no self managed cluster, skip disabling self management")
+		return nil
+	}
+
+	klusterletAgent := agent.NewAgentOptions()
 	return wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
-		klog.Info("Waiting for self managed cluster to be accepted")
-		selfCluster, err := clusterClient.ClusterV1().ManagedClusters().Get(ctx, selfClusterInfo.ClusterName, metav1.GetOptions{})
-		if errors.IsNotFound(err) {
-			selfManagedCluster, err := clusterClient.ClusterV1().ManagedClusters().Create(
-				ctx,
-				&clusterv1.ManagedCluster{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: selfClusterInfo.ClusterName,
-						Labels: map[string]string{
-							SelfManagementClusterLabel: "",
-						},
-					},
-					Spec: clusterv1.ManagedClusterSpec{
-						HubAcceptsClient: true,
-						ManagedClusterClientConfigs: []clusterv1.ClientConfig{
-							{
-								URL:      selfClusterInfo.URL,
-								CABundle: selfClusterInfo.CABundle,
-							},
-						},
-					},
-				},
-				metav1.CreateOptions{},
-			)
-
-			// The creation of the ManagedCluster CRD may take some time to complete.
-			// Therefore, we handle the "not found" error gracefully by ignoring it
-			// and allowing the operation to retry until the resource becomes available.
-			if err == nil {
-				return meta.IsStatusConditionTrue(selfManagedCluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted), nil
-			} else if err != nil && errors.IsNotFound(err) {
-				return false, nil
-			} else if err != nil {
-				return false, err
-			}
+		if err := deregisterSelfManagedClusters(ctx, clients.kubeClient, clients.clusterClient, clients.workClient,
+			clusterLister, sets.New[string]()); err != nil {
+			klog.Warningf("Failed to deregister the self managed clusters, %v", err)
+			return false, nil
 		}
 
-		if err != nil {
-			return false, err
+		if err := cleanupAgentState(ctx, clients.inClusterKubeClient, agentDir,
+			klusterletAgent.CommonOpts.ComponentNamespace,
+			klusterletAgent.RegistrationAgentOpts.HubKubeconfigSecret); err != nil {
+			klog.Warningf("Failed to cleanup the self managed cluster agent state, %v", err)
+			return false, nil
 		}
 
-		return meta.IsStatusConditionTrue(selfCluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted), nil
+		return true, nil
 	})
 }
+
+type selfManagementClients struct {
+	kubeClient          kubernetes.Interface
+	clusterClient       clusterclient.Interface
+	workClient          workclient.Interface
+	clusterInformers    clusterinformers.SharedInformerFactory
+	inClusterKubeClient kubernetes.Interface
+}
+
+func newSelfManagementClients(ctx context.Context, hubRestConfig, inClusterConfig *rest.Config) (*selfManagementClients, error) {
+	apiextensionsClient, err := apiextensionsclient.NewForConfig(hubRestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if bootstrap.WaitFOROCMCRDsReady(ctx, apiextensionsClient) {
+		klog.Infof("ocm crds are ready")
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(hubRestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterClient, err := clusterclient.NewForConfig(hubRestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	workClient, err := workclient.NewForConfig(hubRestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := &selfManagementClients{
+		kubeClient:    kubeClient,
+		clusterClient: clusterClient,
+		workClient:    workClient,
+		clusterInformers: clusterinformers.NewSharedInformerFactoryWithOptions(clusterClient, 10*time.Minute,
+			clusterinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = SelfManagementClusterLabel
+			})),
+	}
+
+	if inClusterConfig != nil {
+		clients.inClusterKubeClient, err = kubernetes.NewForConfig(inClusterConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return clients, nil
+}
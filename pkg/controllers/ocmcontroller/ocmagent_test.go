@@ -0,0 +1,101 @@
+// Copyright Contributors to the Open Cluster Management project
+package ocmcontroller
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+)
+
+func TestDisableSelfManagement(t *testing.T) {
+	selfManagedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "local-cluster",
+			Labels: map[string]string{SelfManagementClusterLabel: ""},
+		},
+	}
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	hubKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hub-kubeconfig-secret", Namespace: "open-cluster-management-agent"},
+	}
+
+	tests := []struct {
+		name         string
+		clusters     []*clusterv1.ManagedCluster
+		wantDisabled bool
+	}{
+		{
+			name: "no clusters",
+		},
+		{
+			name:     "no self managed cluster",
+			clusters: []*clusterv1.ManagedCluster{cluster},
+		},
+		{
+			name:         "self managed cluster exists",
+			clusters:     []*clusterv1.ManagedCluster{selfManagedCluster, cluster},
+			wantDisabled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentDir := path.Join(t.TempDir(), "agent")
+			if err := markAgentDir(agentDir); err != nil {
+				t.Fatal(err)
+			}
+
+			objs := []runtime.Object{}
+			for _, cluster := range tt.clusters {
+				objs = append(objs, cluster)
+			}
+			clients := &selfManagementClients{
+				kubeClient:          kubefake.NewSimpleClientset(),
+				clusterClient:       clusterfake.NewSimpleClientset(objs...),
+				workClient:          workfake.NewSimpleClientset(),
+				inClusterKubeClient: kubefake.NewSimpleClientset(hubKubeconfigSecret),
+			}
+			clusterInformers := clusterinformers.NewSharedInformerFactory(clients.clusterClient, 0)
+			for _, cluster := range tt.clusters {
+				if err := clusterInformers.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := disableSelfManagement(context.TODO(), clients,
+				clusterInformers.Cluster().V1().ManagedClusters().Lister(), agentDir); err != nil {
+				t.Fatalf("disableSelfManagement() error = %v", err)
+			}
+
+			_, err := os.Stat(agentDir)
+			if cleaned := os.IsNotExist(err); cleaned != tt.wantDisabled {
+				t.Errorf("disableSelfManagement() removed the agent dir = %v, want %v", cleaned, tt.wantDisabled)
+			}
+			if !tt.wantDisabled {
+				testingcommon.AssertNoActions(t, clients.clusterClient.(*clusterfake.Clientset).Actions())
+				testingcommon.AssertNoActions(t, clients.kubeClient.(*kubefake.Clientset).Actions())
+				testingcommon.AssertNoActions(t, clients.inClusterKubeClient.(*kubefake.Clientset).Actions())
+				return
+			}
+
+			if _, err := clients.clusterClient.ClusterV1().ManagedClusters().Get(
+				context.TODO(), selfManagedCluster.Name, metav1.GetOptions{}); err == nil {
+				t.Errorf("the self managed cluster is not deleted")
+			}
+			if _, err := clients.clusterClient.ClusterV1().ManagedClusters().Get(
+				context.TODO(), cluster.Name, metav1.GetOptions{}); err != nil {
+				t.Errorf("the cluster is deleted, %v", err)
+			}
+		})
+	}
+}
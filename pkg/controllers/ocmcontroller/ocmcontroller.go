@@ -9,6 +9,7 @@ import (
 
 	"github.com/openshift/library-go/pkg/controller/controllercmd"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/dynamic"
@@ -37,10 +38,14 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/addons"
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/autoapproval"
 	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/bootstrap"
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/workquota"
 	mcfeature "open-cluster-management.io/multicluster-controlplane/pkg/feature"
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/configs"
 	"open-cluster-management.io/multicluster-controlplane/pkg/servers/options"
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkquota"
 )
 
 var scheme = runtime.NewScheme()
@@ -74,6 +79,9 @@ func InstallControllers(opts options.ServerRunOptions) func(<-chan struct{}, *ag
 				restConfig,
 				aggregatorConfig.GenericConfig.SharedInformerFactory,
 				opts.RegistrationOpts,
+				opts.AutoApprovalRules,
+				opts.ManifestWorkQuota,
+				opts.WorkDriver,
 			); err != nil {
 				klog.Fatalf("failed to bootstrap ocm controllers: %v", err)
 			}
@@ -88,7 +96,10 @@ func InstallControllers(opts options.ServerRunOptions) func(<-chan struct{}, *ag
 func runControllers(ctx context.Context,
 	restConfig *rest.Config,
 	kubeInformers genericinformers.SharedInformerFactory,
-	opts *registrationhub.HubManagerOptions) error {
+	opts *registrationhub.HubManagerOptions,
+	autoApprovalRules []configs.AutoApprovalRule,
+	workQuota *manifestworkquota.Configuration,
+	workDriver string) error {
 	eventRecorder := util.NewLoggingRecorder("hub-controller")
 
 	kubeClient, err := kubernetes.NewForConfig(restConfig)
@@ -138,6 +149,13 @@ func runControllers(ctx context.Context,
 	addOnInformers := addoninformers.NewSharedInformerFactory(addOnClient, 10*time.Minute)
 	dynamicInformers := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 10*time.Minute)
 
+	registerFleetMetrics(
+		clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+		workInformers.Work().V1().ManifestWorks().Lister(),
+		addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+		clusterInformers.Cluster().V1beta1().PlacementDecisions().Lister(),
+	)
+
 	go func() {
 		if err := opts.RunControllerManagerWithInformers(
 			ctx,
@@ -157,6 +175,48 @@ func runControllers(ctx context.Context,
 		}
 	}()
 
+	if len(autoApprovalRules) != 0 {
+		autoApprovalController, err := autoapproval.NewAutoApprovalController(
+			kubeClient,
+			clusterClient,
+			kubeInformers.Certificates().V1().CertificateSigningRequests(),
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			autoApprovalRules,
+			eventRecorder,
+		)
+		if err != nil {
+			return err
+		}
+		go autoApprovalController.Run(ctx, 1)
+	}
+
+	if clusterRoles := addons.EnabledAddOnAgentClusterRoles(); len(clusterRoles) != 0 {
+		go addons.NewAddOnRBACController(
+			clusterRoles,
+			kubeClient,
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			eventRecorder,
+		).Run(ctx, 1)
+	}
+
+	if workQuota != nil {
+		// only the quota status configmaps are watched
+		quotaStatusInformers := genericinformers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute,
+			genericinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = workquota.QuotaStatusConfigMapLabelKey
+			}))
+		go workquota.NewManifestWorkQuotaController(
+			kubeClient,
+			kubeInformers.Core().V1().Namespaces(),
+			quotaStatusInformers.Core().V1().ConfigMaps(),
+			clusterInformers.Cluster().V1().ManagedClusters(),
+			workInformers.Work().V1().ManifestWorks(),
+			workQuota,
+			eventRecorder,
+		).Run(ctx, 1)
+		go quotaStatusInformers.Start(ctx.Done())
+	}
+
 	go func() {
 		if err := placementcontrollers.RunControllerManagerWithInformers(
 			ctx,
@@ -171,9 +231,8 @@ func runControllers(ctx context.Context,
 
 	if features.HubMutableFeatureGate.Enabled(ocmfeature.ManifestWorkReplicaSet) {
 		go func() {
-			// TODO(qiujian16), should expose as flags to support other types.
 			workOpts := workhub.NewWorkHubManagerOptions()
-			workOpts.WorkDriver = "kube"
+			workOpts.WorkDriver = hubWorkDriver(workDriver)
 
 			if err := workhub.NewWorkHubManagerConfig(workOpts).RunWorkHubManager(
 				ctx,
@@ -245,3 +304,15 @@ func runControllers(ctx context.Context,
 	<-ctx.Done()
 	return nil
 }
+
+// hubWorkDriver returns the driver of the work hub manager for the work driver of the agents, the ManifestWorks
+// are stored in the controlplane apiserver with both drivers, the gRPC server only delivers them to the agents,
+// so the ManifestWorkReplicaSets are always reconciled with the kube driver.
+func hubWorkDriver(workDriver string) string {
+	switch workDriver {
+	case configs.WorkDriverGRPC:
+		return configs.WorkDriverKube
+	default:
+		return workDriver
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright Contributors to the Open Cluster Management project
+package ocmcontroller
+
+import (
+	"testing"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/configs"
+)
+
+func TestHubWorkDriver(t *testing.T) {
+	tests := []struct {
+		name       string
+		workDriver string
+		want       string
+	}{
+		{
+			name:       "kube",
+			workDriver: configs.WorkDriverKube,
+			want:       configs.WorkDriverKube,
+		},
+		{
+			name:       "the ManifestWorks are stored in the controlplane for grpc",
+			workDriver: configs.WorkDriverGRPC,
+			want:       configs.WorkDriverKube,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hubWorkDriver(tt.workDriver); got != tt.want {
+				t.Errorf("hubWorkDriver() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright Contributors to the Open Cluster Management project
+package ocmcontroller
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonlisterv1alpha1 "open-cluster-management.io/api/client/addon/listers/addon/v1alpha1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	clusterlisterv1beta1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1beta1"
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+const ocmMetricsSubsystem = "ocm"
+
+var (
+	managedClusterConditionDesc = metrics.NewDesc(
+		metrics.BuildFQName("", ocmMetricsSubsystem, "managedclusters"),
+		"Number of ManagedClusters by condition type and status.",
+		[]string{"condition", "status"}, nil,
+		metrics.ALPHA, "",
+	)
+	manifestWorkConditionDesc = metrics.NewDesc(
+		metrics.BuildFQName("", ocmMetricsSubsystem, "manifestworks"),
+		"Number of ManifestWorks by cluster, condition type and status.",
+		[]string{"cluster", "condition", "status"}, nil,
+		metrics.ALPHA, "",
+	)
+	managedClusterAddOnHealthDesc = metrics.NewDesc(
+		metrics.BuildFQName("", ocmMetricsSubsystem, "managedclusteraddons"),
+		"Number of ManagedClusterAddOns by addon name and health (status of the Available condition).",
+		[]string{"addon", "status"}, nil,
+		metrics.ALPHA, "",
+	)
+	placementDecisionsDesc = metrics.NewDesc(
+		metrics.BuildFQName("", ocmMetricsSubsystem, "placement_decisions"),
+		"Number of clusters selected by a Placement.",
+		[]string{"namespace", "placement"}, nil,
+		metrics.ALPHA, "",
+	)
+)
+
+var (
+	managedClusterConditionTypes = []string{
+		clusterv1.ManagedClusterConditionAvailable,
+		clusterv1.ManagedClusterConditionHubAccepted,
+		clusterv1.ManagedClusterConditionJoined,
+	}
+	manifestWorkConditionTypes = []string{
+		workv1.WorkApplied,
+		workv1.WorkAvailable,
+		workv1.WorkDegraded,
+	}
+	conditionStatuses = []metav1.ConditionStatus{
+		metav1.ConditionTrue,
+		metav1.ConditionFalse,
+		metav1.ConditionUnknown,
+	}
+)
+
+var (
+	fleetMetrics             = &fleetCollector{}
+	registerFleetMetricsOnce sync.Once
+)
+
+// registerFleetMetrics exposes the fleet metrics with the apiserver metrics, the collector is registered only
+// once, if the controllers are started again, e.g. the leadership is acquired again, the collector reads from
+// the informer caches of the latest run.
+func registerFleetMetrics(
+	clusterLister clusterlisterv1.ManagedClusterLister,
+	workLister worklisterv1.ManifestWorkLister,
+	addOnLister addonlisterv1alpha1.ManagedClusterAddOnLister,
+	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister,
+) {
+	fleetMetrics.setListers(clusterLister, workLister, addOnLister, placementDecisionLister)
+	registerFleetMetricsOnce.Do(func() {
+		legacyregistry.CustomMustRegister(fleetMetrics)
+	})
+}
+
+// fleetCollector exposes the fleet level gauges of the controlplane, the values are computed from
+// the informer caches on each scrape, so no extra requests are sent to the apiserver.
+type fleetCollector struct {
+	metrics.BaseStableCollector
+
+	lock                    sync.RWMutex
+	clusterLister           clusterlisterv1.ManagedClusterLister
+	workLister              worklisterv1.ManifestWorkLister
+	addOnLister             addonlisterv1alpha1.ManagedClusterAddOnLister
+	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister
+}
+
+var _ metrics.StableCollector = &fleetCollector{}
+
+func (c *fleetCollector) setListers(
+	clusterLister clusterlisterv1.ManagedClusterLister,
+	workLister worklisterv1.ManifestWorkLister,
+	addOnLister addonlisterv1alpha1.ManagedClusterAddOnLister,
+	placementDecisionLister clusterlisterv1beta1.PlacementDecisionLister,
+) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.clusterLister = clusterLister
+	c.workLister = workLister
+	c.addOnLister = addOnLister
+	c.placementDecisionLister = placementDecisionLister
+}
+
+func (c *fleetCollector) DescribeWithStability(ch chan<- *metrics.Desc) {
+	ch <- managedClusterConditionDesc
+	ch <- manifestWorkConditionDesc
+	ch <- managedClusterAddOnHealthDesc
+	ch <- placementDecisionsDesc
+}
+
+func (c *fleetCollector) CollectWithStability(ch chan<- metrics.Metric) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	// the controllers are not started yet
+	if c.clusterLister == nil {
+		return
+	}
+
+	c.collectManagedClusters(ch)
+	c.collectManifestWorks(ch)
+	c.collectManagedClusterAddOns(ch)
+	c.collectPlacementDecisions(ch)
+}
+
+func (c *fleetCollector) collectManagedClusters(ch chan<- metrics.Metric) {
+	clusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list managed clusters for metrics, %v", err)
+		return
+	}
+
+	counts := newConditionCounts(managedClusterConditionTypes)
+	for _, cluster := range clusters {
+		counts.add(cluster.Status.Conditions)
+	}
+
+	for _, conditionType := range managedClusterConditionTypes {
+		for _, status := range conditionStatuses {
+			ch <- metrics.NewLazyConstMetric(managedClusterConditionDesc, metrics.GaugeValue,
+				float64(counts[conditionType][status]), conditionType, string(status))
+		}
+	}
+}
+
+func (c *fleetCollector) collectManifestWorks(ch chan<- metrics.Metric) {
+	works, err := c.workLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list manifestworks for metrics, %v", err)
+		return
+	}
+
+	// the ManifestWorks are counted by their cluster namespaces
+	countsByCluster := map[string]conditionCounts{}
+	for _, work := range works {
+		counts, ok := countsByCluster[work.Namespace]
+		if !ok {
+			counts = newConditionCounts(manifestWorkConditionTypes)
+			countsByCluster[work.Namespace] = counts
+		}
+		counts.add(work.Status.Conditions)
+	}
+
+	for cluster, counts := range countsByCluster {
+		for _, conditionType := range manifestWorkConditionTypes {
+			for _, status := range conditionStatuses {
+				ch <- metrics.NewLazyConstMetric(manifestWorkConditionDesc, metrics.GaugeValue,
+					float64(counts[conditionType][status]), cluster, conditionType, string(status))
+			}
+		}
+	}
+}
+
+func (c *fleetCollector) collectManagedClusterAddOns(ch chan<- metrics.Metric) {
+	addOns, err := c.addOnLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list managed cluster addons for metrics, %v", err)
+		return
+	}
+
+	countsByAddOn := map[string]conditionCounts{}
+	for _, addOn := range addOns {
+		counts, ok := countsByAddOn[addOn.Name]
+		if !ok {
+			counts = newConditionCounts([]string{addonv1alpha1.ManagedClusterAddOnConditionAvailable})
+			countsByAddOn[addOn.Name] = counts
+		}
+		counts.add(addOn.Status.Conditions)
+	}
+
+	for addOnName, counts := range countsByAddOn {
+		for _, status := range conditionStatuses {
+			ch <- metrics.NewLazyConstMetric(managedClusterAddOnHealthDesc, metrics.GaugeValue,
+				float64(counts[addonv1alpha1.ManagedClusterAddOnConditionAvailable][status]), addOnName, string(status))
+		}
+	}
+}
+
+func (c *fleetCollector) collectPlacementDecisions(ch chan<- metrics.Metric) {
+	decisions, err := c.placementDecisionLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list placement decisions for metrics, %v", err)
+		return
+	}
+
+	// a placement may have multiple decisions, sum them up by the placement label
+	type placementKey struct{ namespace, name string }
+	counts := map[placementKey]int{}
+	for _, decision := range decisions {
+		placementName, ok := decision.Labels[clusterv1beta1.PlacementLabel]
+		if !ok {
+			continue
+		}
+		counts[placementKey{decision.Namespace, placementName}] += len(decision.Status.Decisions)
+	}
+
+	for key, count := range counts {
+		ch <- metrics.NewLazyConstMetric(placementDecisionsDesc, metrics.GaugeValue, float64(count), key.namespace, key.name)
+	}
+}
+
+// conditionCounts counts the objects by condition type and condition status, an object
+// without the condition is counted as unknown.
+type conditionCounts map[string]map[metav1.ConditionStatus]int
+
+func newConditionCounts(conditionTypes []string) conditionCounts {
+	counts := conditionCounts{}
+	for _, conditionType := range conditionTypes {
+		counts[conditionType] = map[metav1.ConditionStatus]int{}
+	}
+	return counts
+}
+
+func (c conditionCounts) add(conditions []metav1.Condition) {
+	for conditionType, statuses := range c {
+		condition := meta.FindStatusCondition(conditions, conditionType)
+		if condition == nil {
+			statuses[metav1.ConditionUnknown]++
+			continue
+		}
+		statuses[condition.Status]++
+	}
+}
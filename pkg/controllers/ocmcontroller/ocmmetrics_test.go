@@ -0,0 +1,176 @@
+// Copyright Contributors to the Open Cluster Management project
+package ocmcontroller
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+	addonfake "open-cluster-management.io/api/client/addon/clientset/versioned/fake"
+	addoninformers "open-cluster-management.io/api/client/addon/informers/externalversions"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func newManifestWork(namespace, name string, conditions ...metav1.Condition) *workv1.ManifestWork {
+	return &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status:     workv1.ManifestWorkStatus{Conditions: conditions},
+	}
+}
+
+func TestFleetCollector(t *testing.T) {
+	applied := metav1.Condition{Type: workv1.WorkApplied, Status: metav1.ConditionTrue}
+	available := metav1.Condition{Type: workv1.WorkAvailable, Status: metav1.ConditionTrue}
+	degraded := metav1.Condition{Type: workv1.WorkDegraded, Status: metav1.ConditionFalse}
+
+	tests := []struct {
+		name     string
+		clusters []*clusterv1.ManagedCluster
+		works    []*workv1.ManifestWork
+		metric   string
+		want     string
+	}{
+		{
+			name:   "no ManifestWorks",
+			metric: "ocm_manifestworks",
+		},
+		{
+			name: "the ManifestWorks are counted by cluster",
+			works: []*workv1.ManifestWork{
+				newManifestWork("cluster1", "work1", applied, available, degraded),
+				newManifestWork("cluster1", "work2", applied),
+				newManifestWork("cluster2", "work1"),
+			},
+			metric: "ocm_manifestworks",
+			want: `
+# HELP ocm_manifestworks [ALPHA] Number of ManifestWorks by cluster, condition type and status.
+# TYPE ocm_manifestworks gauge
+ocm_manifestworks{cluster="cluster1",condition="Applied",status="False"} 0
+ocm_manifestworks{cluster="cluster1",condition="Applied",status="True"} 2
+ocm_manifestworks{cluster="cluster1",condition="Applied",status="Unknown"} 0
+ocm_manifestworks{cluster="cluster1",condition="Available",status="False"} 0
+ocm_manifestworks{cluster="cluster1",condition="Available",status="True"} 1
+ocm_manifestworks{cluster="cluster1",condition="Available",status="Unknown"} 1
+ocm_manifestworks{cluster="cluster1",condition="Degraded",status="False"} 1
+ocm_manifestworks{cluster="cluster1",condition="Degraded",status="True"} 0
+ocm_manifestworks{cluster="cluster1",condition="Degraded",status="Unknown"} 1
+ocm_manifestworks{cluster="cluster2",condition="Applied",status="False"} 0
+ocm_manifestworks{cluster="cluster2",condition="Applied",status="True"} 0
+ocm_manifestworks{cluster="cluster2",condition="Applied",status="Unknown"} 1
+ocm_manifestworks{cluster="cluster2",condition="Available",status="False"} 0
+ocm_manifestworks{cluster="cluster2",condition="Available",status="True"} 0
+ocm_manifestworks{cluster="cluster2",condition="Available",status="Unknown"} 1
+ocm_manifestworks{cluster="cluster2",condition="Degraded",status="False"} 0
+ocm_manifestworks{cluster="cluster2",condition="Degraded",status="True"} 0
+ocm_manifestworks{cluster="cluster2",condition="Degraded",status="Unknown"} 1
+`,
+		},
+		{
+			name: "ManagedClusters",
+			clusters: []*clusterv1.ManagedCluster{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+					Status: clusterv1.ManagedClusterStatus{Conditions: []metav1.Condition{
+						{Type: clusterv1.ManagedClusterConditionHubAccepted, Status: metav1.ConditionTrue},
+						{Type: clusterv1.ManagedClusterConditionJoined, Status: metav1.ConditionTrue},
+						{Type: clusterv1.ManagedClusterConditionAvailable, Status: metav1.ConditionFalse},
+					}},
+				},
+				{ObjectMeta: metav1.ObjectMeta{Name: "cluster2"}},
+			},
+			metric: "ocm_managedclusters",
+			want: `
+# HELP ocm_managedclusters [ALPHA] Number of ManagedClusters by condition type and status.
+# TYPE ocm_managedclusters gauge
+ocm_managedclusters{condition="HubAcceptedManagedCluster",status="False"} 0
+ocm_managedclusters{condition="HubAcceptedManagedCluster",status="True"} 1
+ocm_managedclusters{condition="HubAcceptedManagedCluster",status="Unknown"} 1
+ocm_managedclusters{condition="ManagedClusterConditionAvailable",status="False"} 1
+ocm_managedclusters{condition="ManagedClusterConditionAvailable",status="True"} 0
+ocm_managedclusters{condition="ManagedClusterConditionAvailable",status="Unknown"} 1
+ocm_managedclusters{condition="ManagedClusterJoined",status="False"} 0
+ocm_managedclusters{condition="ManagedClusterJoined",status="True"} 1
+ocm_managedclusters{condition="ManagedClusterJoined",status="Unknown"} 1
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterInformers := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), 0)
+			for _, cluster := range tt.clusters {
+				if err := clusterInformers.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+			}
+			workInformers := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 0)
+			for _, work := range tt.works {
+				if err := workInformers.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+					t.Fatal(err)
+				}
+			}
+			addOnInformers := addoninformers.NewSharedInformerFactory(addonfake.NewSimpleClientset(), 0)
+
+			collector := &fleetCollector{}
+			collector.setListers(
+				clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+				workInformers.Work().V1().ManifestWorks().Lister(),
+				addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+				clusterInformers.Cluster().V1beta1().PlacementDecisions().Lister(),
+			)
+
+			if err := testutil.CustomCollectAndCompare(collector, strings.NewReader(tt.want), tt.metric); err != nil {
+				t.Errorf("CollectWithStability() unexpected metrics, %v", err)
+			}
+		})
+	}
+}
+
+func TestRegisterFleetMetrics(t *testing.T) {
+	// the controllers may be started more than once, e.g. the leadership is acquired again, the collector
+	// reads from the informer caches of the latest run
+	for _, works := range [][]*workv1.ManifestWork{
+		{newManifestWork("cluster1", "work1")},
+		{newManifestWork("cluster2", "work1")},
+	} {
+		clusterInformers := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), 0)
+		workInformers := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 0)
+		for _, work := range works {
+			if err := workInformers.Work().V1().ManifestWorks().Informer().GetStore().Add(work); err != nil {
+				t.Fatal(err)
+			}
+		}
+		addOnInformers := addoninformers.NewSharedInformerFactory(addonfake.NewSimpleClientset(), 0)
+
+		registerFleetMetrics(
+			clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+			workInformers.Work().V1().ManifestWorks().Lister(),
+			addOnInformers.Addon().V1alpha1().ManagedClusterAddOns().Lister(),
+			clusterInformers.Cluster().V1beta1().PlacementDecisions().Lister(),
+		)
+	}
+
+	want := `
+# HELP ocm_manifestworks [ALPHA] Number of ManifestWorks by cluster, condition type and status.
+# TYPE ocm_manifestworks gauge
+ocm_manifestworks{cluster="cluster2",condition="Applied",status="False"} 0
+ocm_manifestworks{cluster="cluster2",condition="Applied",status="True"} 0
+ocm_manifestworks{cluster="cluster2",condition="Applied",status="Unknown"} 1
+ocm_manifestworks{cluster="cluster2",condition="Available",status="False"} 0
+ocm_manifestworks{cluster="cluster2",condition="Available",status="True"} 0
+ocm_manifestworks{cluster="cluster2",condition="Available",status="Unknown"} 1
+ocm_manifestworks{cluster="cluster2",condition="Degraded",status="False"} 0
+ocm_manifestworks{cluster="cluster2",condition="Degraded",status="True"} 0
+ocm_manifestworks{cluster="cluster2",condition="Degraded",status="Unknown"} 1
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(want),
+		"ocm_manifestworks"); err != nil {
+		t.Errorf("GatherAndCompare() unexpected metrics, %v", err)
+	}
+}
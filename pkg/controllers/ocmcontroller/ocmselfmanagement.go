@@ -0,0 +1,327 @@
+// Copyright Contributors to the Open Cluster Management project
+package ocmcontroller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclusterdeleteprotection"
+)
+
+const (
+	selfManagementResyncInterval = time.Minute
+
+	// selfManagementMarkerFile marks the agent dir is created by the self management, the agent dir is only removed
+	// with the marker file
+	selfManagementMarkerFile = ".selfmanagement"
+)
+
+// selfManagementController maintains the self managed cluster, it keeps the self managed cluster and its
+// namespace in the desired state, and deregisters the previous self managed clusters after the self managed
+// cluster name is changed. The agent is started once the self managed cluster is accepted, and it is restarted
+// if the self managed cluster is recreated.
+type selfManagementController struct {
+	kubeClient      kubernetes.Interface
+	clusterClient   clusterclient.Interface
+	workClient      workclient.Interface
+	clusterLister   clusterlisterv1.ManagedClusterLister
+	selfClusterInfo *ClusterInfo
+	startAgent      func(ctx context.Context)
+
+	// agentClusterUID is the uid of the self managed cluster that the running agent is started for
+	agentClusterUID types.UID
+	stopAgent       context.CancelFunc
+}
+
+func newSelfManagementController(
+	kubeClient kubernetes.Interface,
+	clusterClient clusterclient.Interface,
+	workClient workclient.Interface,
+	clusterInformer factory.Informer,
+	clusterLister clusterlisterv1.ManagedClusterLister,
+	selfClusterInfo *ClusterInfo,
+	startAgent func(ctx context.Context),
+	recorder events.Recorder) factory.Controller {
+	c := &selfManagementController{
+		kubeClient:      kubeClient,
+		clusterClient:   clusterClient,
+		workClient:      workClient,
+		clusterLister:   clusterLister,
+		selfClusterInfo: selfClusterInfo,
+		startAgent:      startAgent,
+	}
+
+	return factory.New().
+		WithInformers(clusterInformer).
+		WithSync(c.sync).
+		ResyncEvery(selfManagementResyncInterval).
+		ToController("SelfManagementController", recorder)
+}
+
+func (c *selfManagementController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	// deregister the self managed clusters that have a different name
+	if err := deregisterSelfManagedClusters(ctx, c.kubeClient, c.clusterClient, c.workClient,
+		c.clusterLister, sets.New(c.selfClusterInfo.ClusterName)); err != nil {
+		return err
+	}
+
+	if err := createNamespace(ctx, c.kubeClient, c.selfClusterInfo.ClusterName); err != nil {
+		return err
+	}
+
+	cluster, err := c.applySelfManagedCluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionHubAccepted) {
+		klog.Info("Waiting for self managed cluster to be accepted")
+		return nil
+	}
+
+	if c.agentClusterUID == cluster.UID {
+		return nil
+	}
+	if c.stopAgent != nil {
+		klog.Infof("The self managed cluster %s is recreated, restart the agent", cluster.Name)
+		c.stopAgent()
+	}
+
+	agentCtx, stopAgent := context.WithCancel(ctx)
+	c.agentClusterUID = cluster.UID
+	c.stopAgent = stopAgent
+	c.startAgent(agentCtx)
+	return nil
+}
+
+func (c *selfManagementController) applySelfManagedCluster(ctx context.Context) (*clusterv1.ManagedCluster, error) {
+	clientConfigs := []clusterv1.ClientConfig{
+		{
+			URL:      c.selfClusterInfo.URL,
+			CABundle: c.selfClusterInfo.CABundle,
+		},
+	}
+
+	cluster, err := c.clusterClient.ClusterV1().ManagedClusters().Get(ctx, c.selfClusterInfo.ClusterName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return c.clusterClient.ClusterV1().ManagedClusters().Create(
+			ctx,
+			&clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: c.selfClusterInfo.ClusterName,
+					Labels: map[string]string{
+						SelfManagementClusterLabel: "",
+					},
+				},
+				Spec: clusterv1.ManagedClusterSpec{
+					HubAcceptsClient:            true,
+					ManagedClusterClientConfigs: clientConfigs,
+				},
+			},
+			metav1.CreateOptions{},
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !cluster.DeletionTimestamp.IsZero() {
+		// wait for the cluster to be deleted, and then recreate it
+		return cluster, nil
+	}
+
+	_, hasLabel := cluster.Labels[SelfManagementClusterLabel]
+	if hasLabel && cluster.Spec.HubAcceptsClient && reflect.DeepEqual(cluster.Spec.ManagedClusterClientConfigs, clientConfigs) {
+		return cluster, nil
+	}
+
+	updated := cluster.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	updated.Labels[SelfManagementClusterLabel] = ""
+	updated.Spec.HubAcceptsClient = true
+	updated.Spec.ManagedClusterClientConfigs = clientConfigs
+	return c.clusterClient.ClusterV1().ManagedClusters().Update(ctx, updated, metav1.UpdateOptions{})
+}
+
+// deregisterSelfManagedClusters deletes the self managed clusters except the kept ones, the cluster namespaces
+// are deleted after the clusters are removed. The agents of the deleted clusters are not running anymore, so
+// the finalizers of their ManifestWorks are removed by the controlplane, the resources that were applied by the
+// ManifestWorks are left on the cluster.
+func deregisterSelfManagedClusters(ctx context.Context,
+	kubeClient kubernetes.Interface,
+	clusterClient clusterclient.Interface,
+	workClient workclient.Interface,
+	clusterLister clusterlisterv1.ManagedClusterLister,
+	kept sets.Set[string]) error {
+	selector := labels.SelectorFromSet(labels.Set{SelfManagementClusterLabel: ""})
+	clusters, err := clusterLister.List(selector)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, cluster := range clusters {
+		if kept.Has(cluster.Name) {
+			continue
+		}
+
+		if err := deregisterSelfManagedCluster(ctx, kubeClient, clusterClient, workClient, cluster.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to deregister self managed clusters, %v", errs)
+	}
+	return nil
+}
+
+func deregisterSelfManagedCluster(ctx context.Context,
+	kubeClient kubernetes.Interface,
+	clusterClient clusterclient.Interface,
+	workClient workclient.Interface,
+	clusterName string) error {
+	klog.Infof("Deregistering the self managed cluster %s", clusterName)
+
+	// the cluster may still have ManifestWorks and addons, force delete it
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, managedclusterdeleteprotection.ForceDeleteAnnotation)
+	_, err := clusterClient.ClusterV1().ManagedClusters().Patch(
+		ctx, clusterName, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	err = clusterClient.ClusterV1().ManagedClusters().Delete(ctx, clusterName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	works, err := workClient.WorkV1().ManifestWorks(clusterName).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, work := range works.Items {
+		if work.DeletionTimestamp.IsZero() {
+			if err := workClient.WorkV1().ManifestWorks(clusterName).Delete(
+				ctx, work.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+
+		if err := removeManifestWorkFinalizer(ctx, workClient, &work); err != nil {
+			return err
+		}
+	}
+
+	// the cluster namespace is deleted after the cluster resources are cleaned up
+	_, err = clusterClient.ClusterV1().ManagedClusters().Get(ctx, clusterName, metav1.GetOptions{})
+	if err == nil {
+		return fmt.Errorf("the self managed cluster %s is being deleted", clusterName)
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	err = kubeClient.CoreV1().Namespaces().Delete(ctx, clusterName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func removeManifestWorkFinalizer(ctx context.Context, workClient workclient.Interface, work *workv1.ManifestWork) error {
+	finalizers := []string{}
+	for _, finalizer := range work.Finalizers {
+		if finalizer == workv1.ManifestWorkFinalizer {
+			continue
+		}
+		finalizers = append(finalizers, finalizer)
+	}
+
+	if len(finalizers) == len(work.Finalizers) {
+		return nil
+	}
+
+	updated := work.DeepCopy()
+	updated.Finalizers = finalizers
+	_, err := workClient.WorkV1().ManifestWorks(work.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// markAgentDir creates the agent dir with the marker file of the self management
+func markAgentDir(agentDir string) error {
+	if err := os.MkdirAll(agentDir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(agentDir, selfManagementMarkerFile), []byte{}, 0600)
+}
+
+// cleanupAgentState removes the local state of the self managed cluster agent, including the hub kubeconfig
+// secret on the current cluster and the agent data directory. Nothing is removed if the agent dir is not created
+// by the self management.
+func cleanupAgentState(ctx context.Context, inClusterKubeClient kubernetes.Interface,
+	agentDir, secretNamespace, secretName string) error {
+	_, err := os.Stat(path.Join(agentDir, selfManagementMarkerFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if inClusterKubeClient != nil {
+		err := inClusterKubeClient.CoreV1().Secrets(secretNamespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	// the marker file is removed at last, so the cleanup is retried if it fails
+	return os.RemoveAll(agentDir)
+}
+
+func createNamespace(ctx context.Context, kubeClient kubernetes.Interface, ns string) error {
+	_, err := kubeClient.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := kubeClient.CoreV1().Namespaces().Create(
+			ctx,
+			&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: ns,
+				},
+			},
+			metav1.CreateOptions{},
+		)
+		return err
+	}
+
+	return err
+}
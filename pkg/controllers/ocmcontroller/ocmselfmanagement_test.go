@@ -0,0 +1,162 @@
+// Copyright Contributors to the Open Cluster Management project
+package ocmcontroller
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+)
+
+func TestCleanupAgentState(t *testing.T) {
+	hubKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hub-kubeconfig-secret", Namespace: "open-cluster-management-agent"},
+	}
+
+	tests := []struct {
+		name        string
+		marked      bool
+		wantCleaned bool
+	}{
+		{
+			name:        "agent dir is created by the self management",
+			marked:      true,
+			wantCleaned: true,
+		},
+		{
+			name: "agent dir is not created by the self management",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentDir := path.Join(t.TempDir(), "agent")
+			if err := os.MkdirAll(path.Join(agentDir, "hub-kubeconfig"), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			if tt.marked {
+				if err := markAgentDir(agentDir); err != nil {
+					t.Fatal(err)
+				}
+			}
+			kubeClient := kubefake.NewSimpleClientset(hubKubeconfigSecret)
+
+			if err := cleanupAgentState(context.TODO(), kubeClient, agentDir,
+				hubKubeconfigSecret.Namespace, hubKubeconfigSecret.Name); err != nil {
+				t.Fatalf("cleanupAgentState() error = %v", err)
+			}
+
+			_, err := os.Stat(agentDir)
+			if cleaned := os.IsNotExist(err); cleaned != tt.wantCleaned {
+				t.Errorf("cleanupAgentState() removed the agent dir = %v, want %v", cleaned, tt.wantCleaned)
+			}
+			if tt.wantCleaned {
+				testingcommon.AssertActions(t, kubeClient.Actions(), "delete")
+			} else {
+				testingcommon.AssertNoActions(t, kubeClient.Actions())
+			}
+		})
+	}
+}
+
+func TestSelfManagementSync(t *testing.T) {
+	newCluster := func(uid types.UID, accepted bool) *clusterv1.ManagedCluster {
+		cluster := &clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "local-cluster",
+				UID:    uid,
+				Labels: map[string]string{SelfManagementClusterLabel: ""},
+			},
+			Spec: clusterv1.ManagedClusterSpec{
+				HubAcceptsClient:            true,
+				ManagedClusterClientConfigs: []clusterv1.ClientConfig{{URL: "https://127.0.0.1:6443"}},
+			},
+		}
+		if accepted {
+			meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+				Type:   clusterv1.ManagedClusterConditionHubAccepted,
+				Status: metav1.ConditionTrue,
+				Reason: "HubClusterAdminAccepted",
+			})
+		}
+		return cluster
+	}
+
+	tests := []struct {
+		name string
+		// clusters are the self managed cluster in each sync
+		clusters    []*clusterv1.ManagedCluster
+		wantStarted int
+		wantStopped int
+	}{
+		{
+			name:     "cluster is not accepted",
+			clusters: []*clusterv1.ManagedCluster{newCluster("uid1", false)},
+		},
+		{
+			name:        "cluster is accepted",
+			clusters:    []*clusterv1.ManagedCluster{newCluster("uid1", true), newCluster("uid1", true)},
+			wantStarted: 1,
+		},
+		{
+			name: "cluster is recreated",
+			clusters: []*clusterv1.ManagedCluster{
+				newCluster("uid1", true), newCluster("uid2", false), newCluster("uid2", true),
+			},
+			wantStarted: 2,
+			wantStopped: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agentCtxs := []context.Context{}
+			c := &selfManagementController{
+				kubeClient: kubefake.NewSimpleClientset(),
+				workClient: workfake.NewSimpleClientset(),
+				selfClusterInfo: &ClusterInfo{
+					ClusterName: "local-cluster",
+					URL:         "https://127.0.0.1:6443",
+				},
+				startAgent: func(ctx context.Context) {
+					agentCtxs = append(agentCtxs, ctx)
+				},
+			}
+
+			for _, cluster := range tt.clusters {
+				c.clusterClient = clusterfake.NewSimpleClientset(cluster)
+				clusterInformers := clusterinformers.NewSharedInformerFactory(c.clusterClient, 0)
+				if err := clusterInformers.Cluster().V1().ManagedClusters().Informer().GetStore().Add(cluster); err != nil {
+					t.Fatal(err)
+				}
+				c.clusterLister = clusterInformers.Cluster().V1().ManagedClusters().Lister()
+
+				if err := c.sync(context.TODO(), testingcommon.NewFakeSyncContext(t, "local-cluster")); err != nil {
+					t.Fatalf("sync() error = %v", err)
+				}
+			}
+
+			if len(agentCtxs) != tt.wantStarted {
+				t.Errorf("sync() started the agent %d times, want %d", len(agentCtxs), tt.wantStarted)
+			}
+			stopped := 0
+			for _, ctx := range agentCtxs {
+				if ctx.Err() != nil {
+					stopped++
+				}
+			}
+			if stopped != tt.wantStopped {
+				t.Errorf("sync() stopped the agent %d times, want %d", stopped, tt.wantStopped)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright Contributors to the Open Cluster Management project
+package workquota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	clusterinformerv1 "open-cluster-management.io/api/client/cluster/informers/externalversions/cluster/v1"
+	clusterlisterv1 "open-cluster-management.io/api/client/cluster/listers/cluster/v1"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkquota"
+)
+
+const (
+	// QuotaStatusConfigMapName is the name of the configmap in the cluster namespace that reports the
+	// ManifestWork limits and usage of the namespace
+	QuotaStatusConfigMapName = "manifestwork-quota"
+	// QuotaStatusConfigMapLabelKey is the label of the quota status configmaps
+	QuotaStatusConfigMapLabelKey = "multicluster-controlplane.open-cluster-management.io/manifestwork-quota"
+
+	limitsManifestWorksKey    = "limits.manifestworks"
+	limitsManifestsPerWorkKey = "limits.manifestsPerWork"
+	limitsPayloadSizeKey      = "limits.payloadSize"
+	usedManifestWorksKey      = "used.manifestworks"
+	usedPayloadSizeKey        = "used.payloadSize"
+	quotaStatusErrorKey       = "error"
+)
+
+// manifestWorkQuotaController reports the ManifestWork limits and usage of the cluster namespaces that have
+// limits, the limits are enforced by the ManifestWorkQuota admission plugin.
+type manifestWorkQuotaController struct {
+	kubeClient      kubernetes.Interface
+	namespaceLister corev1listers.NamespaceLister
+	configMapLister corev1listers.ConfigMapLister
+	clusterLister   clusterlisterv1.ManagedClusterLister
+	workLister      worklisterv1.ManifestWorkLister
+	config          *manifestworkquota.Configuration
+	eventRecorder   events.Recorder
+}
+
+// NewManifestWorkQuotaController returns a controller to report the ManifestWork quota status, the configMapInformer
+// only needs to watch the configmaps with the QuotaStatusConfigMapLabelKey label.
+func NewManifestWorkQuotaController(
+	kubeClient kubernetes.Interface,
+	namespaceInformer corev1informers.NamespaceInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	clusterInformer clusterinformerv1.ManagedClusterInformer,
+	workInformer workinformerv1.ManifestWorkInformer,
+	config *manifestworkquota.Configuration,
+	recorder events.Recorder) factory.Controller {
+	c := &manifestWorkQuotaController{
+		kubeClient:      kubeClient,
+		namespaceLister: namespaceInformer.Lister(),
+		configMapLister: configMapInformer.Lister(),
+		clusterLister:   clusterInformer.Lister(),
+		workLister:      workInformer.Lister(),
+		config:          config,
+		eventRecorder:   recorder.WithComponentSuffix("manifestwork-quota-controller"),
+	}
+
+	return factory.New().
+		WithInformersQueueKeysFunc(
+			func(obj runtime.Object) []string {
+				accessor, _ := meta.Accessor(obj)
+				return []string{accessor.GetName()}
+			},
+			namespaceInformer.Informer(), clusterInformer.Informer()).
+		WithInformersQueueKeysFunc(
+			func(obj runtime.Object) []string {
+				accessor, _ := meta.Accessor(obj)
+				return []string{accessor.GetNamespace()}
+			},
+			workInformer.Informer()).
+		WithSync(c.sync).
+		ToController("ManifestWorkQuotaController", recorder)
+}
+
+func (c *manifestWorkQuotaController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	namespaceName := syncCtx.QueueKey()
+	if len(namespaceName) == 0 || namespaceName == factory.DefaultQueueKey {
+		return nil
+	}
+
+	// only the cluster namespaces are reported
+	if _, err := c.clusterLister.Get(namespaceName); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	namespace, err := c.namespaceLister.Get(namespaceName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !namespace.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	limits, limitsErr := c.config.LimitsFor(namespace)
+	if limitsErr == nil && limits.IsEmpty() {
+		// the namespace has no limits, the status of the previous limits is removed
+		return c.removeQuotaStatus(ctx, namespaceName)
+	}
+
+	works, err := c.workLister.ManifestWorks(namespaceName).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	usage := manifestworkquota.ComputeUsage(works)
+
+	data := map[string]string{
+		usedManifestWorksKey: strconv.FormatInt(usage.ManifestWorks, 10),
+		usedPayloadSizeKey:   resource.NewQuantity(usage.PayloadSize, resource.BinarySI).String(),
+	}
+	if limitsErr != nil {
+		// report the invalid limits on the status, so the namespace admin can fix the annotations
+		data[quotaStatusErrorKey] = limitsErr.Error()
+	}
+	if limits.MaxManifestWorks != nil {
+		data[limitsManifestWorksKey] = strconv.FormatInt(*limits.MaxManifestWorks, 10)
+	}
+	if limits.MaxManifestsPerWork != nil {
+		data[limitsManifestsPerWorkKey] = strconv.FormatInt(*limits.MaxManifestsPerWork, 10)
+	}
+	if limits.MaxPayloadSize != nil {
+		data[limitsPayloadSizeKey] = limits.MaxPayloadSize.String()
+	}
+
+	_, _, err = resourceapply.ApplyConfigMap(ctx, c.kubeClient.CoreV1(), c.eventRecorder, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      QuotaStatusConfigMapName,
+			Namespace: namespaceName,
+			Labels:    map[string]string{QuotaStatusConfigMapLabelKey: "true"},
+		},
+		Data: data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply the quota status of namespace %s, %v", namespaceName, err)
+	}
+	return nil
+}
+
+func (c *manifestWorkQuotaController) removeQuotaStatus(ctx context.Context, namespaceName string) error {
+	if _, err := c.configMapLister.ConfigMaps(namespaceName).Get(QuotaStatusConfigMapName); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err := c.kubeClient.CoreV1().ConfigMaps(namespaceName).Delete(ctx, QuotaStatusConfigMapName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove the quota status of namespace %s, %v", namespaceName, err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright Contributors to the Open Cluster Management project
+package workquota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+	clusterfake "open-cluster-management.io/api/client/cluster/clientset/versioned/fake"
+	clusterinformers "open-cluster-management.io/api/client/cluster/informers/externalversions"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	testingcommon "open-cluster-management.io/ocm/pkg/common/testing"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkquota"
+)
+
+func TestSync(t *testing.T) {
+	quotaStatus := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      QuotaStatusConfigMapName,
+			Namespace: "cluster1",
+			Labels:    map[string]string{QuotaStatusConfigMapLabelKey: "true"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		defaults     manifestworkquota.Limits
+		annotations  map[string]string
+		notCluster   bool
+		existing     []runtime.Object
+		wantVerbs    []string
+		wantDataKeys []string
+	}{
+		{
+			name: "no limits",
+		},
+		{
+			name:      "the limits are removed",
+			existing:  []runtime.Object{quotaStatus},
+			wantVerbs: []string{"delete"},
+		},
+		{
+			name:       "not a cluster namespace",
+			defaults:   manifestworkquota.Limits{MaxManifestWorks: ptr.To[int64](10)},
+			notCluster: true,
+		},
+		{
+			name:         "default limits",
+			defaults:     manifestworkquota.Limits{MaxManifestWorks: ptr.To[int64](10)},
+			wantVerbs:    []string{"get", "create"},
+			wantDataKeys: []string{limitsManifestWorksKey, usedManifestWorksKey, usedPayloadSizeKey},
+		},
+		{
+			name:         "namespace limits",
+			annotations:  map[string]string{manifestworkquota.MaxManifestsPerWorkAnnotation: "5"},
+			wantVerbs:    []string{"get", "create"},
+			wantDataKeys: []string{limitsManifestsPerWorkKey, usedManifestWorksKey, usedPayloadSizeKey},
+		},
+		{
+			name:         "invalid namespace limits",
+			annotations:  map[string]string{manifestworkquota.MaxManifestWorksAnnotation: "ten"},
+			wantVerbs:    []string{"get", "create"},
+			wantDataKeys: []string{quotaStatusErrorKey, usedManifestWorksKey, usedPayloadSizeKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: tt.annotations},
+			}
+			kubeClient := kubefake.NewSimpleClientset(append([]runtime.Object{namespace}, tt.existing...)...)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+			if err := kubeInformers.Core().V1().Namespaces().Informer().GetStore().Add(namespace); err != nil {
+				t.Fatal(err)
+			}
+			for _, obj := range tt.existing {
+				if err := kubeInformers.Core().V1().ConfigMaps().Informer().GetStore().Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			clusterInformers := clusterinformers.NewSharedInformerFactory(clusterfake.NewSimpleClientset(), 0)
+			if !tt.notCluster {
+				if err := clusterInformers.Cluster().V1().ManagedClusters().Informer().GetStore().Add(
+					&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			workInformers := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 0)
+			if err := workInformers.Work().V1().ManifestWorks().Informer().GetStore().Add(
+				&workv1.ManifestWork{ObjectMeta: metav1.ObjectMeta{Name: "work1", Namespace: "cluster1"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &manifestWorkQuotaController{
+				kubeClient:      kubeClient,
+				namespaceLister: kubeInformers.Core().V1().Namespaces().Lister(),
+				configMapLister: kubeInformers.Core().V1().ConfigMaps().Lister(),
+				clusterLister:   clusterInformers.Cluster().V1().ManagedClusters().Lister(),
+				workLister:      workInformers.Work().V1().ManifestWorks().Lister(),
+				config:          &manifestworkquota.Configuration{Default: tt.defaults},
+				eventRecorder:   eventstesting.NewTestingEventRecorder(t),
+			}
+
+			kubeClient.ClearActions()
+			if err := c.sync(context.TODO(), testingcommon.NewFakeSyncContext(t, "cluster1")); err != nil {
+				t.Fatalf("sync() error = %v", err)
+			}
+
+			testingcommon.AssertActions(t, kubeClient.Actions(), tt.wantVerbs...)
+
+			for _, action := range kubeClient.Actions() {
+				create, ok := action.(clienttesting.CreateAction)
+				if !ok {
+					continue
+				}
+				configMap := create.GetObject().(*corev1.ConfigMap)
+				if len(configMap.Data) != len(tt.wantDataKeys) {
+					t.Errorf("the quota status = %v, want keys %v", configMap.Data, tt.wantDataKeys)
+				}
+				for _, key := range tt.wantDataKeys {
+					if _, ok := configMap.Data[key]; !ok {
+						t.Errorf("the quota status = %v, want key %q", configMap.Data, key)
+					}
+				}
+			}
+		})
+	}
+}
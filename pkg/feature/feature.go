@@ -9,16 +9,30 @@ import (
 const (
 	// ManagedServiceAccount will start new controllers in the controlplane agent process to synchronize ServiceAccount to the managed clusters
 	// and collecting the tokens from these local service accounts as secret resources back to the hub cluster.
+	// On the controlplane, it grants the permissions of the addon agents to the managed clusters.
 	ManagedServiceAccount featuregate.Feature = "ManagedServiceAccount"
 
+	// ClusterClaimCollector will start the cluster claim collectors in the controlplane agent process to refresh the
+	// ClusterClaims of the managed cluster, e.g. the kubernetes version, node count and platform of the cluster.
+	ClusterClaimCollector featuregate.Feature = "ClusterClaimCollector"
+
+	// ResourceUsageScore will start the resource usage score addon in the controlplane agent process to publish the
+	// cpu, memory and pod headroom of the managed cluster as an AddOnPlacementScore to the cluster namespace.
+	// On the controlplane, it grants the permissions of the addon agents to the managed clusters.
+	ResourceUsageScore featuregate.Feature = "ResourceUsageScore"
+
 	// ManagedServiceAccountEphemeralIdentity allow user to set TTL on the ManagedServiceAccount resource via spec.ttlSecondsAfterCreation
 	ManagedServiceAccountEphemeralIdentity featuregate.Feature = "ManagedServiceAccountEphemeralIdentity"
 )
 
 var DefaultControlPlaneFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	ManagedServiceAccount:                  {Default: false, PreRelease: featuregate.Alpha},
+	ResourceUsageScore:                     {Default: false, PreRelease: featuregate.Alpha},
 	ManagedServiceAccountEphemeralIdentity: {Default: false, PreRelease: featuregate.Alpha},
 }
 
 var DefaultControlPlaneAgentFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	ManagedServiceAccount: {Default: false, PreRelease: featuregate.Alpha},
+	ClusterClaimCollector: {Default: false, PreRelease: featuregate.Alpha},
+	ResourceUsageScore:    {Default: false, PreRelease: featuregate.Alpha},
 }
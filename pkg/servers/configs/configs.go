@@ -14,11 +14,21 @@ import (
 
 const DefaultAPIServerPort = 9443
 
+const DefaultGRPCServerPort = 8090
+
 const (
 	defaultControlPlaneDataDir = "/.ocm"
 	defaultControlPlaneCADir   = "/.ocm/cert/controlplane-ca"
 	defaultETCDMode            = "embed"
 	defaultETCDPrefix          = "/registry"
+	defaultWorkDriver          = WorkDriverKube
+)
+
+const (
+	// WorkDriverKube is the work driver that the agents watch the ManifestWorks from the controlplane apiserver
+	WorkDriverKube = "kube"
+	// WorkDriverGRPC is the work driver that the agents receive the ManifestWorks from the controlplane gRPC server
+	WorkDriverGRPC = "grpc"
 )
 
 type ControlplaneRunConfig struct {
@@ -26,6 +36,12 @@ type ControlplaneRunConfig struct {
 	Apiserver     ApiserverConfig  `yaml:"apiserver"`
 	Etcd          EtcdConfig       `yaml:"etcd"`
 	Aggregator    AggregatorConfig `yaml:"aggregator"`
+	// WorkDriver is the driver that the managed cluster agents use to receive the ManifestWorks,
+	// it can be kube or grpc. For grpc, the controlplane serves the ManifestWorks with a gRPC server.
+	WorkDriver string           `yaml:"workDriver"`
+	GRPCServer GRPCServerConfig `yaml:"grpcServer"`
+	// AutoApproval contains the rules to approve the managed cluster CSRs and accept the managed clusters
+	AutoApproval AutoApprovalConfig `yaml:"autoApproval"`
 }
 
 type ApiserverConfig struct {
@@ -54,6 +70,37 @@ type AggregatorConfig struct {
 	RequestHeaderAllowedNames        []string `yaml:"requestheaderAllowedNames"`
 }
 
+type GRPCServerConfig struct {
+	Port int `yaml:"port"`
+}
+
+type AutoApprovalConfig struct {
+	Rules []AutoApprovalRule `yaml:"rules"`
+}
+
+// AutoApprovalRule matches a joining managed cluster, the rule matches the cluster only if all of its
+// conditions match, the empty conditions are ignored.
+type AutoApprovalRule struct {
+	Name string `yaml:"name"`
+	// ClusterNamePattern is the regular expression of the managed cluster name
+	ClusterNamePattern string `yaml:"clusterNamePattern"`
+	// ClusterClaims are the cluster claims that are reported on the ManagedCluster status
+	ClusterClaims map[string]string `yaml:"clusterClaims"`
+	// Labels are the labels of the ManagedCluster
+	Labels map[string]string `yaml:"labels"`
+	// TokenDescriptionPattern is the regular expression of the bootstrap token description
+	TokenDescriptionPattern string `yaml:"tokenDescriptionPattern"`
+	// TokenGroups are the groups that the bootstrap token must have
+	TokenGroups []string `yaml:"tokenGroups"`
+
+	// ApproveCSR approves the bootstrap CSRs of the managed cluster
+	ApproveCSR bool `yaml:"approveCSR"`
+	// AcceptCluster accepts the managed cluster
+	AcceptCluster bool `yaml:"acceptCluster"`
+	// ClusterSet is the ManagedClusterSet that the managed cluster is added to
+	ClusterSet string `yaml:"clusterSet"`
+}
+
 func LoadConfig(configDir string) (*ControlplaneRunConfig, error) {
 	configFile := path.Join(configDir, "ocmconfig.yaml")
 	configFileData, err := os.ReadFile(configFile)
@@ -78,6 +125,14 @@ func LoadConfig(configDir string) (*ControlplaneRunConfig, error) {
 		c.Etcd.Prefix = defaultETCDPrefix
 	}
 
+	if c.WorkDriver == "" {
+		c.WorkDriver = defaultWorkDriver
+	}
+
+	if c.GRPCServer.Port == 0 {
+		c.GRPCServer.Port = DefaultGRPCServerPort
+	}
+
 	if len(c.Etcd.Servers) == 0 {
 		c.Etcd.Servers = []string{"http://127.0.0.1:2379"}
 	}
@@ -0,0 +1,90 @@
+// Copyright Contributors to the Open Cluster Management project
+package grpcserver
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+)
+
+// grpcAuthenticator authenticates the grpc requests with the apiserver request authenticator, so the
+// agents can use the same client certificates or tokens that they use to access the apiserver.
+type grpcAuthenticator struct {
+	authenticator authenticator.Request
+}
+
+func newAuthenticator(authenticator authenticator.Request) *grpcAuthenticator {
+	return &grpcAuthenticator{authenticator: authenticator}
+}
+
+func (a *grpcAuthenticator) unaryInterceptor(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	userInfo, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(request.WithUser(ctx, userInfo), req)
+}
+
+func (a *grpcAuthenticator) streamInterceptor(
+	srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	userInfo, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: request.WithUser(ss.Context(), userInfo)})
+}
+
+// authenticate converts the grpc metadata and the tls peer state to a http request, and then authenticates
+// it with the apiserver authenticator.
+func (a *grpcAuthenticator) authenticate(ctx context.Context) (user.Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			req.Header.Set("Authorization", values[0])
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state := tlsInfo.State
+			req.TLS = &state
+		}
+	}
+
+	resp, ok, err := a.authenticator.AuthenticateRequest(req)
+	if err != nil {
+		klog.V(4).Infof("failed to authenticate grpc request, %v", err)
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	return resp.User, nil
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,144 @@
+// Copyright Contributors to the Open Cluster Management project
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// fakeAuthenticator accepts the bearer token "valid" and the client certificate of the "agent" common name
+var fakeAuthenticator = authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+	switch {
+	case req.Header.Get("Authorization") == "Bearer valid":
+		return &authenticator.Response{User: &user.DefaultInfo{Name: "token-user"}}, true, nil
+	case req.Header.Get("Authorization") == "Bearer broken":
+		return nil, false, fmt.Errorf("failed to review the token")
+	case req.TLS != nil && len(req.TLS.PeerCertificates) != 0:
+		if cn := req.TLS.PeerCertificates[0].Subject.CommonName; cn == "agent" {
+			return &authenticator.Response{User: &user.DefaultInfo{Name: cn}}, true, nil
+		}
+	}
+	return nil, false, nil
+})
+
+func newPeerContext(commonName string) context.Context {
+	cert := &x509.Certificate{}
+	cert.Subject.CommonName = commonName
+	return peer.NewContext(context.TODO(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+	})
+}
+
+func newTokenContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantUser string
+	}{
+		{
+			name:     "valid token",
+			ctx:      newTokenContext("valid"),
+			wantUser: "token-user",
+		},
+		{
+			name: "invalid token",
+			ctx:  newTokenContext("invalid"),
+		},
+		{
+			name: "failed to authenticate the token",
+			ctx:  newTokenContext("broken"),
+		},
+		{
+			name:     "valid client certificate",
+			ctx:      newPeerContext("agent"),
+			wantUser: "agent",
+		},
+		{
+			name: "unknown client certificate",
+			ctx:  newPeerContext("unknown"),
+		},
+		{
+			name: "anonymous",
+			ctx:  context.TODO(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled context.Context
+			_, err := newAuthenticator(fakeAuthenticator).unaryInterceptor(tt.ctx, nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ any) (any, error) {
+					handled = ctx
+					return nil, nil
+				})
+
+			if len(tt.wantUser) == 0 {
+				if status.Code(err) != codes.Unauthenticated {
+					t.Errorf("expected unauthenticated error, but got %v", err)
+				}
+				if handled != nil {
+					t.Errorf("the unauthenticated request is handled")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unaryInterceptor() error = %v", err)
+			}
+			userInfo, ok := request.UserFrom(handled)
+			if !ok || userInfo.GetName() != tt.wantUser {
+				t.Errorf("expected the user %q, but got %v", tt.wantUser, userInfo)
+			}
+		})
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	a := newAuthenticator(fakeAuthenticator)
+
+	var handled grpc.ServerStream
+	handler := func(_ any, stream grpc.ServerStream) error {
+		handled = stream
+		return nil
+	}
+
+	err := a.streamInterceptor(nil, &fakeServerStream{ctx: newTokenContext("invalid")}, &grpc.StreamServerInfo{}, handler)
+	if status.Code(err) != codes.Unauthenticated || handled != nil {
+		t.Errorf("expected unauthenticated error, but got %v", err)
+	}
+
+	if err := a.streamInterceptor(nil, &fakeServerStream{ctx: newPeerContext("agent")}, &grpc.StreamServerInfo{},
+		handler); err != nil {
+		t.Fatalf("streamInterceptor() error = %v", err)
+	}
+	userInfo, ok := request.UserFrom(handled.Context())
+	if !ok || userInfo.GetName() != "agent" {
+		t.Errorf("expected the user %q, but got %v", "agent", userInfo)
+	}
+}
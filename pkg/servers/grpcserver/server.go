@@ -0,0 +1,112 @@
+// Copyright Contributors to the Open Cluster Management project
+package grpcserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/klog/v2"
+	aggregatorapiserver "k8s.io/kube-aggregator/pkg/apiserver"
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	pbv1 "open-cluster-management.io/sdk-go/pkg/cloudevents/generic/options/grpc/protobuf/v1"
+
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/bootstrap"
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/options"
+	"open-cluster-management.io/multicluster-controlplane/pkg/util"
+)
+
+// InstallGRPCServer starts a CloudEvents gRPC server in the controlplane process, the managed cluster agents
+// can receive the ManifestWorks and report their status with the grpc workload source driver. The server has
+// its own listener and reuses the authenticator and authorizer of the apiserver.
+func InstallGRPCServer(opts options.ServerRunOptions) func(<-chan struct{}, *aggregatorapiserver.Config) error {
+	return func(stopCh <-chan struct{}, aggregatorConfig *aggregatorapiserver.Config) error {
+		klog.Info("starting grpc server")
+
+		servingCert, err := dynamiccertificates.NewDynamicServingContentFromFiles(
+			"grpc-serving-cert", opts.GRPCServerCertFile, opts.GRPCServerKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load grpc serving cert, %v", err)
+		}
+
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.GRPCServerBindPort))
+		if err != nil {
+			return fmt.Errorf("failed to listen on grpc port %d, %v", opts.GRPCServerBindPort, err)
+		}
+
+		restConfig := aggregatorConfig.GenericConfig.LoopbackClientConfig
+		restConfig.ContentType = "application/json"
+
+		apiextensionsClient, err := apiextensionsclient.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+
+		workClient, err := workclientset.NewForConfig(restConfig)
+		if err != nil {
+			return err
+		}
+
+		authenticator := newAuthenticator(aggregatorConfig.GenericConfig.Authentication.Authenticator)
+
+		tlsConfig := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			// the client certificates are verified by the apiserver authenticator
+			ClientAuth: tls.RequestClientCert,
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				certPEM, keyPEM := servingCert.CurrentCertKeyContent()
+				cert, err := tls.X509KeyPair(certPEM, keyPEM)
+				if err != nil {
+					return nil, err
+				}
+				return &cert, nil
+			},
+		}
+
+		grpcServer := grpc.NewServer(
+			grpc.Creds(credentials.NewTLS(tlsConfig)),
+			grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+				MinTime:             10 * time.Second,
+				PermitWithoutStream: true,
+			}),
+			grpc.UnaryInterceptor(authenticator.unaryInterceptor),
+			grpc.StreamInterceptor(authenticator.streamInterceptor),
+		)
+
+		ctx := util.GoContext(stopCh)
+
+		go func() {
+			if bootstrap.WaitFOROCMCRDsReady(ctx, apiextensionsClient) {
+				klog.Infof("ocm crds are ready")
+			}
+
+			workInformers := workinformers.NewSharedInformerFactory(workClient, 10*time.Minute)
+			service := newWorkService(ctx, workClient, workInformers.Work().V1().ManifestWorks(),
+				aggregatorConfig.GenericConfig.Authorization.Authorizer)
+			pbv1.RegisterCloudEventServiceServer(grpcServer, service)
+
+			go servingCert.Run(ctx, 1)
+			go workInformers.Start(ctx.Done())
+
+			klog.Infof("serving grpc on %s", listener.Addr().String())
+			if err := grpcServer.Serve(listener); err != nil {
+				klog.Errorf("failed to serve grpc, %v", err)
+			}
+		}()
+
+		go func() {
+			<-ctx.Done()
+			klog.Info("stopping grpc server")
+			grpcServer.GracefulStop()
+		}()
+
+		return nil
+	}
+}
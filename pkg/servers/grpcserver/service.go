@@ -0,0 +1,418 @@
+// Copyright Contributors to the Open Cluster Management project
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cloudeventstypes "github.com/cloudevents/sdk-go/v2/types"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	workclientset "open-cluster-management.io/api/client/work/clientset/versioned"
+	workinformerv1 "open-cluster-management.io/api/client/work/informers/externalversions/work/v1"
+	worklisterv1 "open-cluster-management.io/api/client/work/listers/work/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/sdk-go/pkg/cloudevents/generic/options/grpc/protobuf/v1"
+	"open-cluster-management.io/sdk-go/pkg/cloudevents/generic/options/grpc/protocol"
+	"open-cluster-management.io/sdk-go/pkg/cloudevents/generic/payload"
+	"open-cluster-management.io/sdk-go/pkg/cloudevents/generic/types"
+	"open-cluster-management.io/sdk-go/pkg/cloudevents/work/common"
+	"open-cluster-management.io/sdk-go/pkg/cloudevents/work/source/codec"
+)
+
+// SourceID is the source of the cloudevents that are sent by the controlplane.
+const SourceID = "multicluster-controlplane"
+
+// subscriberBufferSize is the number of events that can be queued for a subscriber, if a subscriber cannot
+// catch up, its subscription is closed and the agent will resync the works after it subscribes again.
+const subscriberBufferSize = 1000
+
+type subscriber struct {
+	clusterName string
+	events      chan *v1.CloudEvent
+	overflowed  chan struct{}
+	once        sync.Once
+}
+
+func (s *subscriber) overflow() {
+	s.once.Do(func() { close(s.overflowed) })
+}
+
+// workService implements the CloudEventService with the ManifestWorks of the controlplane, the ManifestWork
+// specs are sent to the subscribed agents, and the status reported by the agents are updated to the
+// ManifestWorks.
+type workService struct {
+	v1.UnimplementedCloudEventServiceServer
+
+	ctx        context.Context
+	codec      *codec.ManifestBundleCodec
+	workClient workclientset.Interface
+	workLister worklisterv1.ManifestWorkLister
+	authorizer authorizer.Authorizer
+
+	lock        sync.RWMutex
+	subscribers map[string]*subscriber
+}
+
+func newWorkService(ctx context.Context,
+	workClient workclientset.Interface,
+	workInformer workinformerv1.ManifestWorkInformer,
+	authorizer authorizer.Authorizer) *workService {
+	s := &workService{
+		ctx:         ctx,
+		codec:       codec.NewManifestBundleCodec(),
+		workClient:  workClient,
+		workLister:  workInformer.Lister(),
+		authorizer:  authorizer,
+		subscribers: map[string]*subscriber{},
+	}
+
+	_, err := workInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			work, ok := obj.(*workv1.ManifestWork)
+			if !ok {
+				return
+			}
+			s.broadcast(work, common.CreateRequestAction)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldWork, ok := oldObj.(*workv1.ManifestWork)
+			if !ok {
+				return
+			}
+			newWork, ok := newObj.(*workv1.ManifestWork)
+			if !ok {
+				return
+			}
+			// the status is maintained by the agents, only send the spec changes
+			if oldWork.Generation == newWork.Generation &&
+				equality.Semantic.DeepEqual(oldWork.DeletionTimestamp, newWork.DeletionTimestamp) {
+				return
+			}
+			if !newWork.DeletionTimestamp.IsZero() {
+				s.broadcast(newWork, common.DeleteRequestAction)
+				return
+			}
+			s.broadcast(newWork, common.UpdateRequestAction)
+		},
+		DeleteFunc: func(obj interface{}) {
+			work, ok := obj.(*workv1.ManifestWork)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				work, ok = tombstone.Obj.(*workv1.ManifestWork)
+				if !ok {
+					return
+				}
+			}
+			// the work is removed from the controlplane, notify the agent to delete it
+			deleted := work.DeepCopy()
+			if deleted.DeletionTimestamp.IsZero() {
+				now := metav1.Now()
+				deleted.DeletionTimestamp = &now
+				deleted.Generation = deleted.Generation + 1
+			}
+			s.broadcast(deleted, common.DeleteRequestAction)
+		},
+	})
+	if err != nil {
+		klog.Errorf("failed to add manifestwork event handler, %v", err)
+	}
+
+	return s
+}
+
+// Publish handles the events that are published by the agents, they are the spec resync requests and
+// the status update requests.
+func (s *workService) Publish(ctx context.Context, req *v1.PublishRequest) (*empty.Empty, error) {
+	evt, err := binding.ToEvent(ctx, protocol.NewMessage(req.Event))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to convert the event, %v", err)
+	}
+
+	eventType, err := types.ParseCloudEventsType(evt.Type())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse the event type, %v", err)
+	}
+
+	if eventType.CloudEventsDataType != s.codec.EventDataType() {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported event data type %s", eventType.CloudEventsDataType)
+	}
+
+	clusterName, err := cloudeventstypes.ToString(evt.Extensions()[types.ExtensionClusterName])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to get the clustername extension, %v", err)
+	}
+
+	switch {
+	case eventType.SubResource == types.SubResourceSpec && eventType.Action == types.ResyncRequestAction:
+		if err := s.authorize(ctx, clusterName, "list", ""); err != nil {
+			return nil, err
+		}
+		if err := s.respondSpecResyncRequest(ctx, clusterName, evt); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to resync the works, %v", err)
+		}
+	case eventType.SubResource == types.SubResourceStatus:
+		if err := s.authorize(ctx, clusterName, "update", "status"); err != nil {
+			return nil, err
+		}
+		if err := s.updateStatus(ctx, clusterName, eventType.Action, evt); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to update the work status, %v", err)
+		}
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported event type %s", eventType)
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// Subscribe sends the spec of the ManifestWorks in the subscribed cluster to the agent.
+func (s *workService) Subscribe(req *v1.SubscriptionRequest, stream v1.CloudEventService_SubscribeServer) error {
+	if len(req.ClusterName) == 0 {
+		return status.Error(codes.InvalidArgument, "the cluster name is required")
+	}
+
+	if req.DataType != s.codec.EventDataType().String() {
+		return status.Errorf(codes.InvalidArgument, "unsupported data type %s", req.DataType)
+	}
+
+	if err := s.authorize(stream.Context(), req.ClusterName, "watch", ""); err != nil {
+		return err
+	}
+
+	id := string(uuid.NewUUID())
+	sub := &subscriber{
+		clusterName: req.ClusterName,
+		events:      make(chan *v1.CloudEvent, subscriberBufferSize),
+		overflowed:  make(chan struct{}),
+	}
+
+	s.lock.Lock()
+	s.subscribers[id] = sub
+	s.lock.Unlock()
+
+	klog.V(4).Infof("the agent of cluster %s subscribed (%s)", req.ClusterName, id)
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.subscribers, id)
+		s.lock.Unlock()
+		klog.V(4).Infof("the agent of cluster %s unsubscribed (%s)", req.ClusterName, id)
+	}()
+
+	for {
+		select {
+		case evt := <-sub.events:
+			if err := stream.Send(evt); err != nil {
+				return err
+			}
+		case <-sub.overflowed:
+			return status.Errorf(codes.ResourceExhausted, "too many pending events for cluster %s", req.ClusterName)
+		case <-stream.Context().Done():
+			return nil
+		case <-s.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *workService) respondSpecResyncRequest(ctx context.Context, clusterName string, evt *cloudevents.Event) error {
+	resourceVersions, err := payload.DecodeSpecResyncRequest(*evt)
+	if err != nil {
+		return err
+	}
+
+	works, err := s.workLister.ManifestWorks(clusterName).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	versions := map[string]int64{}
+	for _, version := range resourceVersions.Versions {
+		versions[version.ResourceID] = version.ResourceVersion
+	}
+
+	for _, work := range works {
+		lastVersion, known := versions[string(work.UID)]
+		delete(versions, string(work.UID))
+
+		// respond with the deleting work regardless of its version
+		if work.DeletionTimestamp.IsZero() && known && work.Generation <= lastVersion {
+			continue
+		}
+
+		if err := s.send(clusterName, work, types.ResyncResponseAction); err != nil {
+			return err
+		}
+	}
+
+	// the works do not exist on the controlplane, but exist on the agent, delete them
+	for resourceID, version := range versions {
+		eventType := types.CloudEventsType{
+			CloudEventsDataType: s.codec.EventDataType(),
+			SubResource:         types.SubResourceSpec,
+			Action:              types.ResyncResponseAction,
+		}
+		deleteEvt := types.NewEventBuilder(SourceID, eventType).
+			WithResourceID(resourceID).
+			WithResourceVersion(version).
+			WithClusterName(clusterName).
+			WithDeletionTimestamp(metav1.Now().Time).
+			NewEvent()
+		if err := s.sendEvent(ctx, clusterName, &deleteEvt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *workService) updateStatus(ctx context.Context, clusterName string, action types.EventAction, evt *cloudevents.Event) error {
+	received, err := s.codec.Decode(evt)
+	if err != nil {
+		return err
+	}
+
+	work, err := s.findWork(clusterName, string(received.UID))
+	if errors.IsNotFound(err) {
+		klog.V(4).Infof("the work %s in the cluster %s is not found, ignore its status", received.UID, clusterName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if action == common.DeleteRequestAction {
+		klog.V(4).Infof("the work %s/%s is deleted from the cluster", work.Namespace, work.Name)
+		return nil
+	}
+
+	// ignore the status of an outdated spec, the agent will report the status again for the latest spec
+	generation, err := strconv.ParseInt(received.ResourceVersion, 10, 64)
+	if err != nil {
+		return err
+	}
+	if generation < work.Generation {
+		klog.V(4).Infof("the status of the work %s/%s is outdated, ignore it", work.Namespace, work.Name)
+		return nil
+	}
+
+	if equality.Semantic.DeepEqual(work.Status, received.Status) {
+		return nil
+	}
+
+	updated := work.DeepCopy()
+	updated.Status = received.Status
+	_, err = s.workClient.WorkV1().ManifestWorks(clusterName).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *workService) findWork(clusterName, uid string) (*workv1.ManifestWork, error) {
+	works, err := s.workLister.ManifestWorks(clusterName).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, work := range works {
+		if string(work.UID) == uid {
+			return work, nil
+		}
+	}
+
+	return nil, errors.NewNotFound(common.ManifestWorkGR, uid)
+}
+
+func (s *workService) authorize(ctx context.Context, clusterName, verb, subresource string) error {
+	userInfo, ok := request.UserFrom(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	decision, reason, err := s.authorizer.Authorize(ctx, authorizer.AttributesRecord{
+		User:            userInfo,
+		Verb:            verb,
+		Namespace:       clusterName,
+		APIGroup:        workv1.GroupName,
+		APIVersion:      workv1.GroupVersion.Version,
+		Resource:        "manifestworks",
+		Subresource:     subresource,
+		ResourceRequest: true,
+	})
+	if err != nil {
+		klog.V(4).Infof("failed to authorize the user %s, %v", userInfo.GetName(), err)
+	}
+	if decision != authorizer.DecisionAllow {
+		return status.Errorf(codes.PermissionDenied, "the user %s is not allowed to %s the manifestworks in the cluster %s: %s",
+			userInfo.GetName(), verb, clusterName, reason)
+	}
+
+	return nil
+}
+
+// broadcast sends the work spec to all of the subscribers of the work cluster
+func (s *workService) broadcast(work *workv1.ManifestWork, action types.EventAction) {
+	if err := s.send(work.Namespace, work, action); err != nil {
+		klog.Errorf("failed to send the work %s/%s, %v", work.Namespace, work.Name, err)
+	}
+}
+
+func (s *workService) send(clusterName string, work *workv1.ManifestWork, action types.EventAction) error {
+	eventType := types.CloudEventsType{
+		CloudEventsDataType: s.codec.EventDataType(),
+		SubResource:         types.SubResourceSpec,
+		Action:              action,
+	}
+
+	// the agents compare the resource version to determine whether the spec is changed, use the generation
+	// of the work as its resource version, so the status updates do not cause the agents to reapply the work.
+	toSend := work.DeepCopy()
+	toSend.ResourceVersion = strconv.FormatInt(work.Generation, 10)
+
+	evt, err := s.codec.Encode(SourceID, eventType, toSend)
+	if err != nil {
+		return err
+	}
+
+	return s.sendEvent(s.ctx, clusterName, evt)
+}
+
+func (s *workService) sendEvent(ctx context.Context, clusterName string, evt *cloudevents.Event) error {
+	pbEvt := &v1.CloudEvent{}
+	if err := protocol.WritePBMessage(ctx, binding.ToMessage(evt), pbEvt); err != nil {
+		return fmt.Errorf("failed to convert the event, %v", err)
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for id, sub := range s.subscribers {
+		if sub.clusterName != clusterName {
+			continue
+		}
+
+		select {
+		case sub.events <- pbEvt:
+		default:
+			klog.Warningf("the events of the cluster %s are overflowed (%s)", clusterName, id)
+			sub.overflow()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright Contributors to the Open Cluster Management project
+package grpcserver
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	workv1 "open-cluster-management.io/api/work/v1"
+	pbv1 "open-cluster-management.io/sdk-go/pkg/cloudevents/generic/options/grpc/protobuf/v1"
+)
+
+// fakeAuthorizer allows the agent of the cluster1 to access the manifestworks in its cluster namespace
+var fakeAuthorizer = authorizer.AuthorizerFunc(func(_ context.Context, a authorizer.Attributes) (
+	authorizer.Decision, string, error) {
+	if a.GetUser().GetName() == "agent" && a.GetNamespace() == "cluster1" &&
+		a.GetAPIGroup() == workv1.GroupName && a.GetResource() == "manifestworks" {
+		return authorizer.DecisionAllow, "", nil
+	}
+	return authorizer.DecisionNoOpinion, "forbidden", nil
+})
+
+func newTestWorkService(ctx context.Context) *workService {
+	workClient := workfake.NewSimpleClientset()
+	workInformers := workinformers.NewSharedInformerFactory(workClient, 0)
+	return newWorkService(ctx, workClient, workInformers.Work().V1().ManifestWorks(), fakeAuthorizer)
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name        string
+		user        user.Info
+		clusterName string
+		wantCode    codes.Code
+	}{
+		{
+			name:        "the agent of the cluster",
+			user:        &user.DefaultInfo{Name: "agent"},
+			clusterName: "cluster1",
+			wantCode:    codes.OK,
+		},
+		{
+			name:        "the agent of another cluster",
+			user:        &user.DefaultInfo{Name: "agent"},
+			clusterName: "cluster2",
+			wantCode:    codes.PermissionDenied,
+		},
+		{
+			name:        "unknown user",
+			user:        &user.DefaultInfo{Name: "unknown"},
+			clusterName: "cluster1",
+			wantCode:    codes.PermissionDenied,
+		},
+		{
+			name:        "unauthenticated",
+			clusterName: "cluster1",
+			wantCode:    codes.Unauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.TODO()
+			if tt.user != nil {
+				ctx = request.WithUser(ctx, tt.user)
+			}
+
+			err := newTestWorkService(context.TODO()).authorize(ctx, tt.clusterName, "watch", "")
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("authorize() error = %v, want code %v", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+type fakeSubscribeServer struct {
+	pbv1.CloudEventService_SubscribeServer
+	ctx context.Context
+}
+
+func (s *fakeSubscribeServer) Context() context.Context {
+	return s.ctx
+}
+
+func TestSubscribe(t *testing.T) {
+	service := newTestWorkService(context.TODO())
+	dataType := service.codec.EventDataType().String()
+
+	tests := []struct {
+		name     string
+		req      *pbv1.SubscriptionRequest
+		user     string
+		wantCode codes.Code
+	}{
+		{
+			name:     "no cluster name",
+			req:      &pbv1.SubscriptionRequest{DataType: dataType},
+			user:     "agent",
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "unsupported data type",
+			req:      &pbv1.SubscriptionRequest{ClusterName: "cluster1", DataType: "io.open-cluster-management.works.v1alpha1.manifests"},
+			user:     "agent",
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "not allowed",
+			req:      &pbv1.SubscriptionRequest{ClusterName: "cluster2", DataType: dataType},
+			user:     "agent",
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "subscribed",
+			req:      &pbv1.SubscriptionRequest{ClusterName: "cluster1", DataType: dataType},
+			user:     "agent",
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the stream is closed, so the subscription returns once it is accepted
+			ctx, cancel := context.WithCancel(request.WithUser(context.TODO(), &user.DefaultInfo{Name: tt.user}))
+			cancel()
+
+			err := service.Subscribe(tt.req, &fakeSubscribeServer{ctx: ctx})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("Subscribe() error = %v, want code %v", err, tt.wantCode)
+			}
+
+			service.lock.RLock()
+			defer service.lock.RUnlock()
+			if len(service.subscribers) != 0 {
+				t.Errorf("the subscriber is not removed, %v", service.subscribers)
+			}
+		})
+	}
+}
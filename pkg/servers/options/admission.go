@@ -21,6 +21,7 @@ package options
 import (
 	"github.com/spf13/pflag"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/admission"
 	"k8s.io/apiserver/pkg/server"
 	genericoptions "k8s.io/apiserver/pkg/server/options"
@@ -65,6 +66,16 @@ func (a *AdmissionOptions) AddFlags(fs *pflag.FlagSet) {
 	a.GenericAdmission.AddFlags(fs)
 }
 
+// IsPluginEnabled returns true if the admission plugin is enabled
+func (a *AdmissionOptions) IsPluginEnabled(pluginName string) bool {
+	if a == nil {
+		return false
+	}
+
+	return sets.New(a.GenericAdmission.EnablePlugins...).Has(pluginName) &&
+		!sets.New(a.GenericAdmission.DisablePlugins...).Has(pluginName)
+}
+
 // Validate verifies flags passed to kube-apiserver AdmissionOptions.
 // Kube-apiserver verifies PluginNames and then call generic AdmissionOptions.Validate.
 func (a *AdmissionOptions) Validate() []error {
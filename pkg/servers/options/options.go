@@ -36,6 +36,7 @@ import (
 	apiextensionsapiserver "k8s.io/apiextensions-apiserver/pkg/apiserver"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	genericoptions "k8s.io/apiserver/pkg/server/options"
 	"k8s.io/apiserver/pkg/storage/storagebackend"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
@@ -55,9 +56,12 @@ import (
 	netutils "k8s.io/utils/net"
 
 	"open-cluster-management.io/multicluster-controlplane/pkg/certificate"
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/autoapproval"
+	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/bootstrap"
 	kubectrmgroptions "open-cluster-management.io/multicluster-controlplane/pkg/controllers/kubecontroller/options"
 	"open-cluster-management.io/multicluster-controlplane/pkg/etcd"
 	"open-cluster-management.io/multicluster-controlplane/pkg/servers/configs"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkquota"
 )
 
 // ServerRunOptions runs a kubernetes api server.
@@ -112,8 +116,27 @@ type ServerRunOptions struct {
 	// SelfManagementClusterName is the name of self management cluster, by default, it's local-cluster
 	SelfManagementClusterName string
 
+	// DefaultBootstrapTokenTTL is the TTL of the default bootstrap token, the default bootstrap token is
+	// recreated after it is expired, it is not created if the TTL is 0
+	DefaultBootstrapTokenTTL time.Duration
+	// DisabledAdmissionPolicies are the names of the default ValidatingAdmissionPolicies that are not applied
+	DisabledAdmissionPolicies []string
+
 	// options for registration hub controller
 	RegistrationOpts *registrationhub.HubManagerOptions
+	// AutoApprovalRules are the rules to approve the managed cluster CSRs and accept the managed clusters
+	AutoApprovalRules []configs.AutoApprovalRule
+	// ManifestWorkQuota is the configuration of the ManifestWorkQuota admission plugin, it is used to report
+	// the quota status of the cluster namespaces, it is nil if the plugin is disabled
+	ManifestWorkQuota *manifestworkquota.Configuration
+
+	// WorkDriver is the driver that the managed cluster agents use to receive the ManifestWorks
+	WorkDriver string
+	// GRPCServerBindPort is the port of the gRPC server, the server is only started with the grpc work driver
+	GRPCServerBindPort int
+	// GRPCServerCertFile and GRPCServerKeyFile are the serving cert and key of the gRPC server
+	GRPCServerCertFile string
+	GRPCServerKeyFile  string
 
 	// EnableDelegatingAuthentication delegate the authentication with controlplane hosing cluster
 	EnableDelegatingAuthentication bool
@@ -172,10 +195,20 @@ func NewServerRunOptions() *ServerRunOptions {
 		"ServiceAccount",
 		"MutatingAdmissionWebhook",
 		"ValidatingAdmissionWebhook",
+		"ValidatingAdmissionPolicy",
 		"ResourceQuota",
 		"ManagedClusterMutating",
 		"ManagedClusterValidating",
+		"ManagedClusterDeleteProtection",
 		"ManagedClusterSetBindingValidating",
+		"ManifestWorkPolicy",
+		"ManifestWorkQuota",
+		"ManifestWorkReplicaSetValidating",
+		"PlacementValidating",
+		"ClusterManagementAddOnValidating",
+		"ManagedClusterAddOnValidating",
+		"AddOnDeploymentConfigValidating",
+		"AddOnTemplateValidating",
 	}
 	admission.GenericAdmission.DisablePlugins = []string{}
 
@@ -239,6 +272,8 @@ func NewServerRunOptions() *ServerRunOptions {
 
 		ControlplaneConfigDir: "/controlplane_config",
 
+		DefaultBootstrapTokenTTL: bootstrap.DefaultBootstrapTokenTTL,
+
 		RegistrationOpts: registrationhub.NewHubManagerOptions(),
 	}
 }
@@ -257,6 +292,15 @@ func (options *ServerRunOptions) AddFlags(fs *pflag.FlagSet) {
 		"Name of the self managed cluster name.")
 	fs.BoolVar(&options.EnableDelegatingAuthentication, "delegating-authentication", options.EnableDelegatingAuthentication,
 		"Delegate authentication to the controlplane hosting cluster.")
+	fs.DurationVar(&options.DefaultBootstrapTokenTTL, "default-bootstrap-token-ttl", options.DefaultBootstrapTokenTTL,
+		"The TTL of the default bootstrap token, a new default token is created after the previous one is expired. "+
+			"The default bootstrap token is not created if it is 0.")
+	fs.StringSliceVar(&options.DisabledAdmissionPolicies, "disabled-admission-policies", options.DisabledAdmissionPolicies,
+		fmt.Sprintf("The default ValidatingAdmissionPolicies that are not applied, the disabled policies are removed if "+
+			"they exist. Available policies: %s.", strings.Join(bootstrap.DefaultAdmissionPolicies(), ", ")))
+	fs.StringVar(&options.Admission.GenericAdmission.ConfigFile, "admission-control-config-file",
+		options.Admission.GenericAdmission.ConfigFile, "File with admission control configuration, "+
+			"e.g. the rules of the ManifestWorkPolicy plugin.")
 }
 
 // Complete set default Options.
@@ -276,6 +320,14 @@ func (s *ServerRunOptions) Complete(stopCh <-chan struct{}) error {
 		return err
 	}
 
+	if s.Admission.IsPluginEnabled(manifestworkquota.PluginName) {
+		quota, err := manifestworkquota.LoadConfigurationFromFile(s.Admission.GenericAdmission.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to load the %s configuration, %v", manifestworkquota.PluginName, err)
+		}
+		s.ManifestWorkQuota = quota
+	}
+
 	// GenericServerRunOptions
 	if err := s.GenericServerRunOptions.DefaultAdvertiseAddress(s.SecureServing.SecureServingOptions); err != nil {
 		return err
@@ -502,6 +554,11 @@ func (o *ServerRunOptions) InitServerRunOptions(cfg *configs.ControlplaneRunConf
 	o.SecureServing.ServerCert.CertKey.CertFile = certificate.ServingCertFile(certsDir)
 	o.SecureServing.ServerCert.CertKey.KeyFile = certificate.ServingKeyFile(certsDir)
 	o.ControlplaneDataDir = cfg.DataDirectory
+	o.WorkDriver = cfg.WorkDriver
+	o.AutoApprovalRules = cfg.AutoApproval.Rules
+	o.GRPCServerBindPort = cfg.GRPCServer.Port
+	o.GRPCServerCertFile = certificate.GRPCServingCertFile(certsDir)
+	o.GRPCServerKeyFile = certificate.GRPCServingKeyFile(certsDir)
 
 	return nil
 }
@@ -516,11 +573,30 @@ func (s *ServerRunOptions) Validate() error {
 	errs = append(errs, s.Admission.Validate()...)
 	errs = append(errs, s.APIEnablement.Validate(legacyscheme.Scheme, apiextensionsapiserver.Scheme, aggregatorscheme.Scheme)...)
 	errs = append(errs, validateTokenRequest(s)...)
+	errs = append(errs, validateAdmissionPolicies(s)...)
+	if s.WorkDriver != configs.WorkDriverKube && s.WorkDriver != configs.WorkDriverGRPC {
+		errs = append(errs, fmt.Errorf("unknown work driver %q, it should be %s or %s",
+			s.WorkDriver, configs.WorkDriverKube, configs.WorkDriverGRPC))
+	}
+	if err := autoapproval.ValidateRules(s.AutoApprovalRules); err != nil {
+		errs = append(errs, err)
+	}
 	errs = append(errs, s.Metrics.Validate()...)
 	errs = append(errs, s.ExtraOptions.EmbeddedEtcd.Validate()...)
 	return utilerrors.NewAggregate(errs)
 }
 
+func validateAdmissionPolicies(options *ServerRunOptions) []error {
+	errs := []error{}
+	policies := sets.New(bootstrap.DefaultAdmissionPolicies()...)
+	for _, name := range options.DisabledAdmissionPolicies {
+		if !policies.Has(name) {
+			errs = append(errs, fmt.Errorf("--disabled-admission-policies: unknown policy %q", name))
+		}
+	}
+	return errs
+}
+
 func validateTokenRequest(options *ServerRunOptions) []error {
 	var errs []error
 
@@ -24,10 +24,19 @@ import (
 	mutatingwebhook "k8s.io/apiserver/pkg/admission/plugin/webhook/mutating"
 	validatingwebhook "k8s.io/apiserver/pkg/admission/plugin/webhook/validating"
 
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/addondeploymentconfigvalidating"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/addontemplatevalidating"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/clustermanagementaddonvalidating"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclusteraddonvalidating"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclusterdeleteprotection"
 	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclustermutating"
 	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclustersetbindingvalidating"
 	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclustervalidating"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkpolicy"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkquota"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkreplicasetvalidating"
 	"open-cluster-management.io/multicluster-controlplane/plugin/admission/manifestworkvalidating"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/placementvalidating"
 )
 
 // AllOrderedPlugins is the list of all the plugins in order.
@@ -45,8 +54,17 @@ var AllOrderedPlugins = []string{
 	// self-defined plugins
 	managedclustermutating.PluginName,             // ManagedClusterMutating
 	managedclustervalidating.PluginName,           // ManagedClusterValidating
+	managedclusterdeleteprotection.PluginName,     // ManagedClusterDeleteProtection
 	managedclustersetbindingvalidating.PluginName, // ManagedClusterSetBindingValidating
 	manifestworkvalidating.PluginName,             // ManifestWorkValidating
+	manifestworkpolicy.PluginName,                 // ManifestWorkPolicy
+	manifestworkquota.PluginName,                  // ManifestWorkQuota
+	manifestworkreplicasetvalidating.PluginName,   // ManifestWorkReplicaSetValidating
+	placementvalidating.PluginName,                // PlacementValidating
+	clustermanagementaddonvalidating.PluginName,   // ClusterManagementAddOnValidating
+	managedclusteraddonvalidating.PluginName,      // ManagedClusterAddOnValidating
+	addondeploymentconfigvalidating.PluginName,    // AddOnDeploymentConfigValidating
+	addontemplatevalidating.PluginName,            // AddOnTemplateValidating
 	// new admission plugins should generally be inserted above here
 	// webhook, resourcequota, and deny plugins must go at the end
 
@@ -72,8 +90,17 @@ func RegisterAllAdmissionPlugins(plugins *admission.Plugins) {
 	// self-defined admission plugins
 	managedclustermutating.Register(plugins)
 	managedclustervalidating.Register(plugins)
+	managedclusterdeleteprotection.Register(plugins)
 	managedclustersetbindingvalidating.Register(plugins)
 	manifestworkvalidating.Register(plugins)
+	manifestworkpolicy.Register(plugins)
+	manifestworkquota.Register(plugins)
+	manifestworkreplicasetvalidating.Register(plugins)
+	placementvalidating.Register(plugins)
+	clustermanagementaddonvalidating.Register(plugins)
+	managedclusteraddonvalidating.Register(plugins)
+	addondeploymentconfigvalidating.Register(plugins)
+	addontemplatevalidating.Register(plugins)
 }
 
 // DefaultOffAdmissionPlugins get admission plugins off by default for kube-apiserver.
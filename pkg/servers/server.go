@@ -17,6 +17,8 @@ import (
 
 	"open-cluster-management.io/multicluster-controlplane/pkg/controllers"
 	"open-cluster-management.io/multicluster-controlplane/pkg/controllers/ocmcontroller"
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/configs"
+	"open-cluster-management.io/multicluster-controlplane/pkg/servers/grpcserver"
 	"open-cluster-management.io/multicluster-controlplane/pkg/servers/options"
 	"open-cluster-management.io/multicluster-controlplane/pkg/util"
 )
@@ -42,9 +44,12 @@ func NewServer(options options.ServerRunOptions) *server {
 	}
 
 	s.AddController("multicluster-controlplane-crd", ocmcontroller.InstallCRD)
-	s.AddController("multicluster-controlplane-registration-resource", ocmcontroller.InstallHubResource)
+	s.AddController("multicluster-controlplane-registration-resource", ocmcontroller.InstallHubResource(options))
 	s.AddController("multicluster-controlplane-controllers", ocmcontroller.InstallControllers(options))
 	s.AddController("multicluster-controlplane-selfmanagement", ocmcontroller.InstallSelfManagementCluster(options))
+	if options.WorkDriver == configs.WorkDriverGRPC {
+		s.AddController("multicluster-controlplane-grpc-server", grpcserver.InstallGRPCServer(options))
+	}
 	if options.Authentication.DelegatingAuthenticatorConfig != nil {
 		s.AddController("multicluster-controlplane-authentication-delegator",
 			func(stopCh <-chan struct{}, aggregatorConfig *aggregatorapiserver.Config) error {
@@ -0,0 +1,296 @@
+// Copyright Contributors to the Open Cluster Management project
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apiserver/pkg/admission"
+	genericadmissioninitializer "k8s.io/apiserver/pkg/admission/initializer"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/generic"
+	"k8s.io/apiserver/pkg/admission/plugin/webhook/request"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/client-go/kubernetes"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	admissionutil "open-cluster-management.io/multicluster-controlplane/plugin/admission/util"
+)
+
+// the webhooks that need the kube client, e.g. the ocm webhooks use it to do the subject access reviews
+type wantsExternalKubeClientSet interface {
+	SetExternalKubeClientSet(client kubernetes.Interface)
+}
+
+// the webhooks that need to validate whether they are initialized
+type initializationValidator interface {
+	ValidateInitialization() error
+}
+
+// webhookPlugin is the common part of the mutating and validating plugins, it converts the admission attributes
+// to a controller-runtime admission request and the typed objects.
+type webhookPlugin struct {
+	*admission.Handler
+
+	gvr       schema.GroupVersionResource
+	gvk       schema.GroupVersionKind
+	newObject func() runtime.Object
+	webhook   any
+
+	ignoreSubresources bool
+}
+
+func newWebhookPlugin(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind,
+	newObject func() runtime.Object, webhook any, operations []admission.Operation) *webhookPlugin {
+	if len(operations) == 0 {
+		operations = []admission.Operation{admission.Create, admission.Update}
+	}
+
+	return &webhookPlugin{
+		Handler:   admission.NewHandler(operations...),
+		gvr:       gvr,
+		gvk:       gvk,
+		newObject: newObject,
+		webhook:   webhook,
+	}
+}
+
+func (p *webhookPlugin) SetExternalKubeClientSet(client kubernetes.Interface) {
+	if w, ok := p.webhook.(wantsExternalKubeClientSet); ok {
+		w.SetExternalKubeClientSet(client)
+	}
+}
+
+func (p *webhookPlugin) ValidateInitialization() error {
+	if p.webhook == nil {
+		return fmt.Errorf("missing webhook")
+	}
+	if p.newObject == nil {
+		return fmt.Errorf("missing object constructor")
+	}
+	if w, ok := p.webhook.(initializationValidator); ok {
+		return w.ValidateInitialization()
+	}
+	return nil
+}
+
+// handles returns false if the request is not for the webhook resource
+func (p *webhookPlugin) handles(a admission.Attributes) bool {
+	if a.GetKind() != p.gvk {
+		return false
+	}
+	if p.ignoreSubresources && len(a.GetSubresource()) != 0 {
+		return false
+	}
+	return true
+}
+
+// admissionContext builds the controller-runtime admission request from the admission attributes, the raw of
+// the objects is set, so the webhooks can decode the objects from the request.
+func (p *webhookPlugin) admissionContext(ctx context.Context, a admission.Attributes) (context.Context, error) {
+	v := admission.VersionedAttributes{
+		Attributes:         a,
+		VersionedOldObject: a.GetOldObject(),
+		VersionedObject:    a.GetObject(),
+		VersionedKind:      a.GetKind(),
+	}
+
+	i := generic.WebhookInvocation{
+		Resource: p.gvr,
+		Kind:     p.gvk,
+	}
+
+	uid := types.UID(uuid.NewUUID())
+	ar := request.CreateV1AdmissionReview(uid, &v, &i)
+
+	if obj := a.GetObject(); obj != nil {
+		if err := admissionutil.Convert_runtime_Object_To_runtime_RawExtension_Raw(&obj, &ar.Request.Object); err != nil {
+			return nil, fmt.Errorf("failed to convert the object of %s to raw, %v", p.gvk.Kind, err)
+		}
+	}
+	if old := a.GetOldObject(); old != nil {
+		if err := admissionutil.Convert_runtime_Object_To_runtime_RawExtension_Raw(&old, &ar.Request.OldObject); err != nil {
+			return nil, fmt.Errorf("failed to convert the old object of %s to raw, %v", p.gvk.Kind, err)
+		}
+	}
+
+	r := runtimeadmission.Request{AdmissionRequest: *ar.Request}
+	return runtimeadmission.NewContextWithRequest(ctx, r), nil
+}
+
+func (p *webhookPlugin) toTyped(obj runtime.Object) (runtime.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T of %s", obj, p.gvk.Kind)
+	}
+
+	typed := p.newObject()
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, err
+	}
+	return typed, nil
+}
+
+// MutatingPlugin runs a controller-runtime CustomDefaulter as an in-process mutating admission plugin
+type MutatingPlugin struct {
+	*webhookPlugin
+	defaulter runtimeadmission.CustomDefaulter
+}
+
+var _ admission.MutationInterface = &MutatingPlugin{}
+var _ admission.InitializationValidator = &MutatingPlugin{}
+var _ = genericadmissioninitializer.WantsExternalKubeClientSet(&MutatingPlugin{})
+
+// NewMutatingPlugin returns a mutating admission plugin for the given resource, the plugin handles the create
+// and update operations if the operations are not specified.
+func NewMutatingPlugin(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, newObject func() runtime.Object,
+	defaulter runtimeadmission.CustomDefaulter, operations ...admission.Operation) *MutatingPlugin {
+	return &MutatingPlugin{
+		webhookPlugin: newWebhookPlugin(gvr, gvk, newObject, defaulter, operations),
+		defaulter:     defaulter,
+	}
+}
+
+// WithSubresourcesIgnored makes the plugin skip the requests of the subresources, e.g. status
+func (p *MutatingPlugin) WithSubresourcesIgnored() *MutatingPlugin {
+	p.ignoreSubresources = true
+	return p
+}
+
+func (p *MutatingPlugin) Admit(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	if !p.handles(a) {
+		return nil
+	}
+
+	// there is nothing to mutate on deletion
+	if a.GetOperation() == admission.Delete || a.GetOperation() == admission.Connect {
+		return nil
+	}
+
+	admissionContext, err := p.admissionContext(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	typed, err := p.toTyped(a.GetObject())
+	if err != nil {
+		return err
+	}
+
+	if err := p.defaulter.Default(admissionContext, typed); err != nil {
+		return toAdmissionError(a, err)
+	}
+
+	updated, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+	if err != nil {
+		return err
+	}
+	a.GetObject().(*unstructured.Unstructured).Object = updated
+
+	return nil
+}
+
+// ValidatingPlugin runs a controller-runtime CustomValidator as an in-process validating admission plugin
+type ValidatingPlugin struct {
+	*webhookPlugin
+	validator runtimeadmission.CustomValidator
+
+	pluginName      string
+	enforcementMode EnforcementMode
+}
+
+var _ admission.ValidationInterface = &ValidatingPlugin{}
+var _ admission.InitializationValidator = &ValidatingPlugin{}
+var _ = genericadmissioninitializer.WantsExternalKubeClientSet(&ValidatingPlugin{})
+
+// NewValidatingPlugin returns a validating admission plugin for the given resource, the plugin handles the create
+// and update operations if the operations are not specified.
+func NewValidatingPlugin(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, newObject func() runtime.Object,
+	validator runtimeadmission.CustomValidator, operations ...admission.Operation) *ValidatingPlugin {
+	return &ValidatingPlugin{
+		webhookPlugin:   newWebhookPlugin(gvr, gvk, newObject, validator, operations),
+		validator:       validator,
+		enforcementMode: EnforcementModeEnforce,
+	}
+}
+
+// WithSubresourcesIgnored makes the plugin skip the requests of the subresources, e.g. status
+func (p *ValidatingPlugin) WithSubresourcesIgnored() *ValidatingPlugin {
+	p.ignoreSubresources = true
+	return p
+}
+
+func (p *ValidatingPlugin) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	if !p.handles(a) {
+		return nil
+	}
+
+	admissionContext, err := p.admissionContext(ctx, a)
+	if err != nil {
+		return err
+	}
+
+	var warnings runtimeadmission.Warnings
+	switch a.GetOperation() {
+	case admission.Create:
+		obj, err := p.toTyped(a.GetObject())
+		if err != nil {
+			return err
+		}
+		warnings, err = p.validator.ValidateCreate(admissionContext, obj)
+		addWarnings(ctx, warnings)
+		return p.enforce(ctx, a, toAdmissionError(a, err))
+	case admission.Update:
+		obj, err := p.toTyped(a.GetObject())
+		if err != nil {
+			return err
+		}
+		oldObj, err := p.toTyped(a.GetOldObject())
+		if err != nil {
+			return err
+		}
+		warnings, err = p.validator.ValidateUpdate(admissionContext, oldObj, obj)
+		addWarnings(ctx, warnings)
+		return p.enforce(ctx, a, toAdmissionError(a, err))
+	case admission.Delete:
+		// the old object is the object that is being deleted, it may be nil if the apiserver does not
+		// load the object before deleting it
+		if a.GetOldObject() == nil {
+			return nil
+		}
+		oldObj, err := p.toTyped(a.GetOldObject())
+		if err != nil {
+			return err
+		}
+		warnings, err = p.validator.ValidateDelete(admissionContext, oldObj)
+		addWarnings(ctx, warnings)
+		return p.enforce(ctx, a, toAdmissionError(a, err))
+	}
+
+	return nil
+}
+
+// toAdmissionError returns the api status errors of the webhooks as they are, the other errors are the denials of
+// the webhooks, they are returned as forbidden errors in the same way of the controller-runtime webhook server.
+func toAdmissionError(a admission.Attributes, err error) error {
+	if err == nil {
+		return nil
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return err
+	}
+	return admission.NewForbidden(a, err)
+}
+
+func addWarnings(ctx context.Context, warnings runtimeadmission.Warnings) {
+	for _, w := range warnings {
+		warning.AddWarning(ctx, "", w)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright Contributors to the Open Cluster Management project
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/warning"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var (
+	clusterResource = clusterv1.GroupVersion.WithResource("managedclusters")
+	clusterKind     = clusterv1.GroupVersion.WithKind("ManagedCluster")
+)
+
+// recordingValidator records the validated objects and returns the warnings and the error
+type recordingValidator struct {
+	warnings  runtimeadmission.Warnings
+	err       error
+	validated []string
+}
+
+func (v *recordingValidator) validate(ctx context.Context, operation string, objs ...runtime.Object) (
+	runtimeadmission.Warnings, error) {
+	if _, err := runtimeadmission.RequestFromContext(ctx); err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		cluster := obj.(*clusterv1.ManagedCluster)
+		v.validated = append(v.validated, fmt.Sprintf("%s %s", operation, cluster.Name))
+	}
+	return v.warnings, v.err
+}
+
+func (v *recordingValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	return v.validate(ctx, "create", obj)
+}
+
+func (v *recordingValidator) ValidateUpdate(ctx context.Context, oldObj, obj runtime.Object) (
+	runtimeadmission.Warnings, error) {
+	return v.validate(ctx, "update", oldObj, obj)
+}
+
+func (v *recordingValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	return v.validate(ctx, "delete", obj)
+}
+
+// labelDefaulter adds a label to the ManagedClusters
+type labelDefaulter struct {
+	err error
+}
+
+func (d *labelDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	if d.err != nil {
+		return d.err
+	}
+	cluster := obj.(*clusterv1.ManagedCluster)
+	if cluster.Labels == nil {
+		cluster.Labels = map[string]string{}
+	}
+	cluster.Labels["defaulted"] = "true"
+	return nil
+}
+
+// warningRecorder records the warnings of the request
+type warningRecorder struct {
+	warnings []string
+}
+
+func (r *warningRecorder) AddWarning(_, text string) {
+	r.warnings = append(r.warnings, text)
+}
+
+func newClusterObject(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(clusterKind)
+	obj.SetName(name)
+	return obj
+}
+
+func newClusterAttributes(obj, oldObj runtime.Object, subresource string, operation admission.Operation) admission.Attributes {
+	return admission.NewAttributesRecord(obj, oldObj, clusterKind, "", "cluster1", clusterResource, subresource,
+		operation, nil, false, &user.DefaultInfo{Name: "user1"})
+}
+
+func TestValidatingPlugin(t *testing.T) {
+	invalid := apierrors.NewInvalid(clusterKind.GroupKind(), "cluster1", field.ErrorList{
+		field.Invalid(field.NewPath("spec"), "", "invalid"),
+	})
+
+	tests := []struct {
+		name          string
+		attrs         admission.Attributes
+		warnings      runtimeadmission.Warnings
+		validationErr error
+		wantValidated []string
+		wantWarnings  []string
+		wantErr       func(error) bool
+	}{
+		{
+			name:          "create",
+			attrs:         newClusterAttributes(newClusterObject("cluster1"), nil, "", admission.Create),
+			wantValidated: []string{"create cluster1"},
+		},
+		{
+			name: "update",
+			attrs: newClusterAttributes(newClusterObject("cluster1"), newClusterObject("cluster1"), "",
+				admission.Update),
+			wantValidated: []string{"update cluster1", "update cluster1"},
+		},
+		{
+			name:          "delete",
+			attrs:         newClusterAttributes(nil, newClusterObject("cluster1"), "", admission.Delete),
+			wantValidated: []string{"delete cluster1"},
+		},
+		{
+			name:  "delete without the old object",
+			attrs: newClusterAttributes(nil, nil, "", admission.Delete),
+		},
+		{
+			name:  "the subresources are ignored",
+			attrs: newClusterAttributes(newClusterObject("cluster1"), newClusterObject("cluster1"), "status", admission.Update),
+		},
+		{
+			name: "another kind",
+			attrs: admission.NewAttributesRecord(newClusterObject("cluster1"), nil,
+				clusterv1.GroupVersion.WithKind("Placement"), "", "cluster1", clusterResource, "",
+				admission.Create, nil, false, &user.DefaultInfo{Name: "user1"}),
+		},
+		{
+			name:          "the warnings are passed through",
+			attrs:         newClusterAttributes(newClusterObject("cluster1"), nil, "", admission.Create),
+			warnings:      runtimeadmission.Warnings{"the field is deprecated"},
+			wantValidated: []string{"create cluster1"},
+			wantWarnings:  []string{"the field is deprecated"},
+		},
+		{
+			name:          "the invalid object is denied",
+			attrs:         newClusterAttributes(newClusterObject("cluster1"), nil, "", admission.Create),
+			warnings:      runtimeadmission.Warnings{"the field is deprecated"},
+			validationErr: invalid,
+			wantValidated: []string{"create cluster1"},
+			wantWarnings:  []string{"the field is deprecated"},
+			wantErr:       apierrors.IsInvalid,
+		},
+		{
+			name:          "the denial is forbidden",
+			attrs:         newClusterAttributes(newClusterObject("cluster1"), nil, "", admission.Create),
+			validationErr: fmt.Errorf("the cluster is denied"),
+			wantValidated: []string{"create cluster1"},
+			wantErr:       apierrors.IsForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &recordingValidator{warnings: tt.warnings, err: tt.validationErr}
+			plugin := NewValidatingPlugin(clusterResource, clusterKind,
+				func() runtime.Object { return &clusterv1.ManagedCluster{} },
+				validator, admission.Create, admission.Update, admission.Delete,
+			).WithSubresourcesIgnored()
+
+			recorder := &warningRecorder{}
+			err := plugin.Validate(warning.WithWarningRecorder(context.TODO(), recorder), tt.attrs, nil)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("Validate() error = %v", err)
+			case tt.wantErr != nil && !tt.wantErr(err):
+				t.Errorf("Validate() unexpected error = %v", err)
+			}
+
+			if !reflect.DeepEqual(validator.validated, tt.wantValidated) {
+				t.Errorf("validated %v, want %v", validator.validated, tt.wantValidated)
+			}
+			if !reflect.DeepEqual(recorder.warnings, tt.wantWarnings) {
+				t.Errorf("warnings %v, want %v", recorder.warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestMutatingPlugin(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   admission.Operation
+		subresource string
+		defaultErr  error
+		wantLabel   bool
+		wantErr     func(error) bool
+	}{
+		{
+			name:      "create",
+			operation: admission.Create,
+			wantLabel: true,
+		},
+		{
+			name:      "update",
+			operation: admission.Update,
+			wantLabel: true,
+		},
+		{
+			name:        "the subresources are ignored",
+			operation:   admission.Update,
+			subresource: "status",
+		},
+		{
+			name:       "the denial is forbidden",
+			operation:  admission.Create,
+			defaultErr: fmt.Errorf("the cluster is denied"),
+			wantErr:    apierrors.IsForbidden,
+		},
+		{
+			name:       "the api errors are returned as they are",
+			operation:  admission.Create,
+			defaultErr: apierrors.NewBadRequest("bad request"),
+			wantErr:    apierrors.IsBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := NewMutatingPlugin(clusterResource, clusterKind,
+				func() runtime.Object { return &clusterv1.ManagedCluster{} },
+				&labelDefaulter{err: tt.defaultErr},
+			).WithSubresourcesIgnored()
+
+			obj := newClusterObject("cluster1")
+			var oldObj runtime.Object
+			if tt.operation == admission.Update {
+				oldObj = newClusterObject("cluster1")
+			}
+			err := plugin.Admit(context.TODO(), admission.NewAttributesRecord(obj, oldObj, clusterKind, "", "cluster1",
+				clusterResource, tt.subresource, tt.operation, &metav1.CreateOptions{}, false,
+				&user.DefaultInfo{Name: "user1"}), nil)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("Admit() error = %v", err)
+			case tt.wantErr != nil && !tt.wantErr(err):
+				t.Errorf("Admit() unexpected error = %v", err)
+			}
+
+			if labeled := obj.GetLabels()["defaulted"] == "true"; labeled != tt.wantLabel {
+				t.Errorf("the object is mutated = %v, want %v", labeled, tt.wantLabel)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright Contributors to the Open Cluster Management project
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/klog/v2"
+)
+
+type EnforcementMode string
+
+const (
+	// EnforcementModeEnforce rejects the requests that are denied by the plugin, it is the default mode
+	EnforcementModeEnforce EnforcementMode = "Enforce"
+	// EnforcementModeWarn admits the requests that are denied by the validation of the plugin and returns the
+	// denials as warnings
+	EnforcementModeWarn EnforcementMode = "Warn"
+	// EnforcementModeAudit admits the requests that are denied by the validation of the plugin and records the
+	// denials in the audit annotations and the violation metric
+	EnforcementModeAudit EnforcementMode = "Audit"
+)
+
+// auditAnnotationKeySuffix is appended to the plugin name to build the audit annotation key, e.g.
+// managedclustervalidating.admission.open-cluster-management.io/violation
+const auditAnnotationKeySuffix = ".admission.open-cluster-management.io/violation"
+
+var (
+	violations = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      "ocm",
+			Name:           "admission_violations_total",
+			Help:           "Number of the requests that are denied by the OCM admission plugins but admitted by the enforcement mode.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"plugin", "operation", "enforcement_mode"},
+	)
+	registerMetrics sync.Once
+)
+
+// Configuration is the configuration of the validating plugins in the admission config file
+type Configuration struct {
+	// EnforcementMode is one of Enforce, Warn and Audit, the default is Enforce
+	EnforcementMode EnforcementMode `json:"enforcementMode,omitempty"`
+}
+
+// LoadConfiguration loads the configuration of the plugin from the plugin config, the enforce mode is used if
+// the plugin is not configured in the admission config file.
+func LoadConfiguration(pluginName string, config io.Reader) (*Configuration, error) {
+	cfg := &Configuration{EnforcementMode: EnforcementModeEnforce}
+	if config == nil {
+		return cfg, nil
+	}
+
+	if err := utilyaml.NewYAMLOrJSONDecoder(config, 4096).Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode the %s configuration, %v", pluginName, err)
+	}
+
+	switch cfg.EnforcementMode {
+	case "":
+		cfg.EnforcementMode = EnforcementModeEnforce
+	case EnforcementModeEnforce, EnforcementModeWarn, EnforcementModeAudit:
+	default:
+		return nil, fmt.Errorf("the enforcementMode %q of %s is invalid, it must be %s, %s or %s", cfg.EnforcementMode,
+			pluginName, EnforcementModeEnforce, EnforcementModeWarn, EnforcementModeAudit)
+	}
+
+	return cfg, nil
+}
+
+// WithEnforcementMode sets the enforcement mode of the plugin, the plugin name is used in the warnings, audit
+// annotations and metric.
+func (p *ValidatingPlugin) WithEnforcementMode(pluginName string, mode EnforcementMode) *ValidatingPlugin {
+	p.pluginName = pluginName
+	p.enforcementMode = mode
+	if mode == EnforcementModeWarn || mode == EnforcementModeAudit {
+		registerMetrics.Do(func() {
+			legacyregistry.MustRegister(violations)
+		})
+	}
+	return p
+}
+
+// enforce returns the denial of the validator if the plugin is in the enforce mode, otherwise the validation
+// denial is recorded and the request is admitted.
+func (p *ValidatingPlugin) enforce(ctx context.Context, a admission.Attributes, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// the authorization denials, e.g. the subject access reviews of the validators, and the other errors are
+	// always returned, so the enforcement mode cannot bypass the RBAC
+	if !isValidationDenial(err) {
+		return err
+	}
+
+	switch p.enforcementMode {
+	case EnforcementModeWarn:
+		warning.AddWarning(ctx, "", fmt.Sprintf("%s (%s mode): %v", p.pluginName, p.enforcementMode, err))
+	case EnforcementModeAudit:
+		key := strings.ToLower(p.pluginName) + auditAnnotationKeySuffix
+		if annotationErr := a.AddAnnotation(key, err.Error()); annotationErr != nil {
+			klog.Warningf("failed to add the audit annotation of %s, %v", p.pluginName, annotationErr)
+		}
+	default:
+		return err
+	}
+
+	violations.WithLabelValues(p.pluginName, string(a.GetOperation()), string(p.enforcementMode)).Inc()
+	return nil
+}
+
+// isValidationDenial returns true if the object is denied by the validation rather than the authorization
+func isValidationDenial(err error) bool {
+	return apierrors.IsInvalid(err) || apierrors.IsBadRequest(err)
+}
@@ -0,0 +1,188 @@
+// Copyright Contributors to the Open Cluster Management project
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+type fakeValidator struct {
+	err error
+}
+
+func (v *fakeValidator) ValidateCreate(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, v.err
+}
+
+func (v *fakeValidator) ValidateUpdate(_ context.Context, _, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, v.err
+}
+
+func (v *fakeValidator) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, v.err
+}
+
+// annotatedAttributes records the audit annotations of the request
+type annotatedAttributes struct {
+	admission.Attributes
+	annotations map[string]string
+}
+
+func (a *annotatedAttributes) AddAnnotation(key, value string) error {
+	a.annotations[key] = value
+	return nil
+}
+
+func TestValidatingPlugin_EnforcementMode(t *testing.T) {
+	clusterResource := clusterv1.GroupVersion.WithResource("managedclusters")
+	clusterKind := clusterv1.GroupVersion.WithKind("ManagedCluster")
+
+	invalid := apierrors.NewInvalid(clusterKind.GroupKind(), "cluster1", field.ErrorList{
+		field.Invalid(field.NewPath("spec"), "", "invalid"),
+	})
+	badRequest := apierrors.NewBadRequest("bad request")
+	forbidden := apierrors.NewForbidden(clusterResource.GroupResource(), "cluster1",
+		fmt.Errorf("user %q cannot update the HubAcceptsClient field", "user1"))
+	internal := apierrors.NewInternalError(fmt.Errorf("failed to create the subject access review"))
+
+	tests := []struct {
+		name           string
+		mode           EnforcementMode
+		validationErr  error
+		wantErr        bool
+		wantAnnotation bool
+	}{
+		{
+			name: "admitted",
+			mode: EnforcementModeEnforce,
+		},
+		{
+			name:          "invalid in enforce mode",
+			mode:          EnforcementModeEnforce,
+			validationErr: invalid,
+			wantErr:       true,
+		},
+		{
+			name:          "forbidden in enforce mode",
+			mode:          EnforcementModeEnforce,
+			validationErr: forbidden,
+			wantErr:       true,
+		},
+		{
+			name:          "invalid in warn mode",
+			mode:          EnforcementModeWarn,
+			validationErr: invalid,
+		},
+		{
+			name:          "bad request in warn mode",
+			mode:          EnforcementModeWarn,
+			validationErr: badRequest,
+		},
+		{
+			name:          "forbidden in warn mode",
+			mode:          EnforcementModeWarn,
+			validationErr: forbidden,
+			wantErr:       true,
+		},
+		{
+			name:          "internal error in warn mode",
+			mode:          EnforcementModeWarn,
+			validationErr: internal,
+			wantErr:       true,
+		},
+		{
+			name:           "invalid in audit mode",
+			mode:           EnforcementModeAudit,
+			validationErr:  invalid,
+			wantAnnotation: true,
+		},
+		{
+			name:          "forbidden in audit mode",
+			mode:          EnforcementModeAudit,
+			validationErr: forbidden,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := NewValidatingPlugin(clusterResource, clusterKind,
+				func() runtime.Object { return &clusterv1.ManagedCluster{} },
+				&fakeValidator{err: tt.validationErr},
+			).WithEnforcementMode("ManagedClusterValidating", tt.mode)
+
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(clusterKind)
+			obj.SetName("cluster1")
+			attrs := &annotatedAttributes{
+				Attributes: admission.NewAttributesRecord(obj, nil, clusterKind, "", "cluster1", clusterResource, "",
+					admission.Create, &metav1.CreateOptions{}, false, &user.DefaultInfo{Name: "user1"}),
+				annotations: map[string]string{},
+			}
+
+			err := plugin.Validate(context.TODO(), attrs, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && err != tt.validationErr {
+				t.Errorf("Validate() error = %v, want %v", err, tt.validationErr)
+			}
+
+			_, hasAnnotation := attrs.annotations["managedclustervalidating"+auditAnnotationKeySuffix]
+			if hasAnnotation != tt.wantAnnotation {
+				t.Errorf("the audit annotations = %v, want annotation %v", attrs.annotations, tt.wantAnnotation)
+			}
+		})
+	}
+}
+
+func TestLoadConfiguration(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantMode EnforcementMode
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			wantMode: EnforcementModeEnforce,
+		},
+		{
+			name:     "warn",
+			config:   "enforcementMode: Warn",
+			wantMode: EnforcementModeWarn,
+		},
+		{
+			name:     "audit in json",
+			config:   `{"enforcementMode": "Audit"}`,
+			wantMode: EnforcementModeAudit,
+		},
+		{
+			name:    "unknown mode",
+			config:  "enforcementMode: Dryrun",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfiguration("TestPlugin", strings.NewReader(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.EnforcementMode != tt.wantMode {
+				t.Errorf("LoadConfiguration() mode = %v, want %v", cfg.EnforcementMode, tt.wantMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright Contributors to the Open Cluster Management project
+package addondeploymentconfigvalidating
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	certutil "k8s.io/client-go/util/cert"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// AddOnDeploymentConfigWebhook validates the AddOnDeploymentConfigs, the configs are rendered into the addon
+// agent manifests, so an invalid config breaks the addon rollout on all of the clusters that use it.
+type AddOnDeploymentConfigWebhook struct{}
+
+func (r *AddOnDeploymentConfigWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	config, ok := obj.(*addonv1alpha1.AddOnDeploymentConfig)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request addondeploymentconfig obj format is not right")
+	}
+	return nil, r.validate(config)
+}
+
+func (r *AddOnDeploymentConfigWebhook) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (runtimeadmission.Warnings, error) {
+	config, ok := newObj.(*addonv1alpha1.AddOnDeploymentConfig)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request addondeploymentconfig obj format is not right")
+	}
+	return nil, r.validate(config)
+}
+
+func (r *AddOnDeploymentConfigWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *AddOnDeploymentConfigWebhook) validate(config *addonv1alpha1.AddOnDeploymentConfig) error {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+
+	variables := sets.New[string]()
+	for i, variable := range config.Spec.CustomizedVariables {
+		variablePath := specPath.Child("customizedVariables").Index(i)
+		if len(variable.Name) == 0 {
+			errs = append(errs, field.Required(variablePath.Child("name"), ""))
+			continue
+		}
+		if variables.Has(variable.Name) {
+			errs = append(errs, field.Duplicate(variablePath.Child("name"), variable.Name))
+		}
+		variables.Insert(variable.Name)
+	}
+
+	if config.Spec.NodePlacement != nil {
+		errs = append(errs, metav1validation.ValidateLabels(config.Spec.NodePlacement.NodeSelector,
+			specPath.Child("nodePlacement", "nodeSelector"))...)
+		for i, toleration := range config.Spec.NodePlacement.Tolerations {
+			errs = append(errs, validateToleration(&toleration, specPath.Child("nodePlacement", "tolerations").Index(i))...)
+		}
+	}
+
+	for i, registry := range config.Spec.Registries {
+		registryPath := specPath.Child("registries").Index(i)
+		if len(registry.Source) == 0 {
+			errs = append(errs, field.Required(registryPath.Child("source"), ""))
+		}
+		if len(registry.Mirror) == 0 {
+			errs = append(errs, field.Required(registryPath.Child("mirror"), ""))
+		}
+	}
+
+	errs = append(errs, validateProxyConfig(&config.Spec.ProxyConfig, specPath.Child("proxyConfig"))...)
+
+	if len(config.Spec.AgentInstallNamespace) != 0 {
+		for _, msg := range validation.IsDNS1123Label(config.Spec.AgentInstallNamespace) {
+			errs = append(errs, field.Invalid(specPath.Child("agentInstallNamespace"), config.Spec.AgentInstallNamespace, msg))
+		}
+	}
+
+	containers := sets.New[string]()
+	for i, requirements := range config.Spec.ResourceRequirements {
+		requirementsPath := specPath.Child("resourceRequirements").Index(i)
+		// the container id is in the format of {resource type}:{resource name}:{container name}
+		if len(strings.Split(requirements.ContainerID, ":")) != 3 {
+			errs = append(errs, field.Invalid(requirementsPath.Child("containerID"), requirements.ContainerID,
+				"must be in the format of {resource type}:{resource name}:{container name}"))
+		} else if containers.Has(requirements.ContainerID) {
+			errs = append(errs, field.Duplicate(requirementsPath.Child("containerID"), requirements.ContainerID))
+		}
+		containers.Insert(requirements.ContainerID)
+
+		errs = append(errs, validateResourceRequirements(&requirements.Resources, requirementsPath.Child("resources"))...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(addonv1alpha1.GroupVersion.WithKind("AddOnDeploymentConfig").GroupKind(), config.Name, errs)
+}
+
+func validateToleration(toleration *corev1.Toleration, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(toleration.Key) != 0 {
+		errs = append(errs, metav1validation.ValidateLabelName(toleration.Key, fldPath.Child("key"))...)
+	}
+
+	switch toleration.Operator {
+	case "", corev1.TolerationOpEqual:
+		if len(toleration.Key) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("operator"), toleration.Operator,
+				"operator must be Exists when key is empty"))
+		}
+	case corev1.TolerationOpExists:
+		if len(toleration.Value) != 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("value"), toleration.Value,
+				"value must be empty when operator is Exists"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("operator"), toleration.Operator, []string{
+			string(corev1.TolerationOpEqual),
+			string(corev1.TolerationOpExists),
+		}))
+	}
+
+	switch toleration.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("effect"), toleration.Effect, []string{
+			string(corev1.TaintEffectNoSchedule),
+			string(corev1.TaintEffectPreferNoSchedule),
+			string(corev1.TaintEffectNoExecute),
+		}))
+	}
+
+	if toleration.TolerationSeconds != nil && toleration.Effect != corev1.TaintEffectNoExecute {
+		errs = append(errs, field.Invalid(fldPath.Child("effect"), toleration.Effect,
+			"effect must be NoExecute when tolerationSeconds is set"))
+	}
+
+	return errs
+}
+
+func validateProxyConfig(proxyConfig *addonv1alpha1.ProxyConfig, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(proxyConfig.HTTPProxy) != 0 {
+		errs = append(errs, validateProxyURL(proxyConfig.HTTPProxy, fldPath.Child("httpProxy"))...)
+	}
+	if len(proxyConfig.HTTPSProxy) != 0 {
+		errs = append(errs, validateProxyURL(proxyConfig.HTTPSProxy, fldPath.Child("httpsProxy"))...)
+	}
+	if len(proxyConfig.CABundle) != 0 {
+		if _, err := certutil.ParseCertsPEM(proxyConfig.CABundle); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("caBundle"), "<ca bundle>",
+				fmt.Sprintf("failed to parse the ca bundle, %v", err)))
+		}
+	}
+
+	return errs
+}
+
+func validateProxyURL(proxyURL string, fldPath *field.Path) field.ErrorList {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, proxyURL, err.Error())}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return field.ErrorList{field.Invalid(fldPath, proxyURL, "the scheme must be http or https")}
+	}
+	if len(u.Host) == 0 {
+		return field.ErrorList{field.Invalid(fldPath, proxyURL, "the host is required")}
+	}
+	return nil
+}
+
+func validateResourceRequirements(requirements *corev1.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for name, quantity := range requirements.Limits {
+		if quantity.Sign() < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("limits").Key(string(name)), quantity.String(),
+				"must be greater than or equal to 0"))
+		}
+	}
+
+	for name, quantity := range requirements.Requests {
+		if quantity.Sign() < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("requests").Key(string(name)), quantity.String(),
+				"must be greater than or equal to 0"))
+			continue
+		}
+		limit, ok := requirements.Limits[name]
+		if ok && quantity.Cmp(limit) > 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("requests").Key(string(name)), quantity.String(),
+				fmt.Sprintf("must be less than or equal to %s limit of %s", name, limit.String())))
+		}
+	}
+
+	return errs
+}
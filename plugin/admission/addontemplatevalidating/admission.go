@@ -0,0 +1,33 @@
+// Copyright Contributors to the Open Cluster Management project
+package addontemplatevalidating
+
+import (
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/adapter"
+)
+
+const PluginName = "AddOnTemplateValidating"
+
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(config io.Reader) (admission.Interface, error) {
+		cfg, err := adapter.LoadConfiguration(PluginName, config)
+		if err != nil {
+			return nil, err
+		}
+		return NewPlugin().WithEnforcementMode(PluginName, cfg.EnforcementMode), nil
+	})
+}
+
+func NewPlugin() *adapter.ValidatingPlugin {
+	return adapter.NewValidatingPlugin(
+		addonv1alpha1.GroupVersion.WithResource("addontemplates"),
+		addonv1alpha1.GroupVersion.WithKind("AddOnTemplate"),
+		func() runtime.Object { return &addonv1alpha1.AddOnTemplate{} },
+		&AddOnTemplateWebhook{},
+	)
+}
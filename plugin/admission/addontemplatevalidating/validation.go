@@ -0,0 +1,138 @@
+// Copyright Contributors to the Open Cluster Management project
+package addontemplatevalidating
+
+import (
+	"context"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	"open-cluster-management.io/ocm/pkg/work/webhook/common"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// AddOnTemplateWebhook validates the agent manifests and the registrations of the AddOnTemplates, the manifests
+// are validated with the same rules of the ManifestWorks, since they are deployed by the ManifestWorks.
+type AddOnTemplateWebhook struct{}
+
+func (r *AddOnTemplateWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	template, ok := obj.(*addonv1alpha1.AddOnTemplate)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request addontemplate obj format is not right")
+	}
+	return nil, r.validate(template)
+}
+
+func (r *AddOnTemplateWebhook) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (runtimeadmission.Warnings, error) {
+	template, ok := newObj.(*addonv1alpha1.AddOnTemplate)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request addontemplate obj format is not right")
+	}
+	return nil, r.validate(template)
+}
+
+func (r *AddOnTemplateWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *AddOnTemplateWebhook) validate(template *addonv1alpha1.AddOnTemplate) error {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+
+	if len(template.Spec.AddonName) == 0 {
+		errs = append(errs, field.Required(specPath.Child("addonName"), ""))
+	}
+
+	if err := common.ManifestValidator.ValidateManifests(template.Spec.AgentSpec.Workload.Manifests); err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("agentSpec", "workload", "manifests"), "<manifests>", err.Error()))
+	}
+
+	for i, registration := range template.Spec.Registration {
+		errs = append(errs, validateRegistration(&registration, specPath.Child("registration").Index(i))...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(addonv1alpha1.GroupVersion.WithKind("AddOnTemplate").GroupKind(), template.Name, errs)
+}
+
+func validateRegistration(registration *addonv1alpha1.RegistrationSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch registration.Type {
+	case addonv1alpha1.RegistrationTypeKubeClient:
+		if registration.KubeClient == nil {
+			return errs
+		}
+		for i, permission := range registration.KubeClient.HubPermissions {
+			errs = append(errs, validateHubPermission(&permission, fldPath.Child("kubeClient", "hubPermissions").Index(i))...)
+		}
+	case addonv1alpha1.RegistrationTypeCustomSigner:
+		signerPath := fldPath.Child("customSigner")
+		if registration.CustomSigner == nil {
+			return append(errs, field.Required(signerPath, "customSigner is required when the type is CustomSigner"))
+		}
+		if len(registration.CustomSigner.SignerName) == 0 {
+			errs = append(errs, field.Required(signerPath.Child("signerName"), ""))
+		}
+		if registration.CustomSigner.SignerName == certificatesv1.KubeAPIServerClientSignerName {
+			errs = append(errs, field.Invalid(signerPath.Child("signerName"), registration.CustomSigner.SignerName,
+				"the KubeClient type must be used for the kube-apiserver client signer"))
+		}
+		if len(registration.CustomSigner.SigningCA.Name) == 0 {
+			errs = append(errs, field.Required(signerPath.Child("signingCA", "name"), ""))
+		}
+		if registration.CustomSigner.Subject != nil && len(registration.CustomSigner.Subject.User) == 0 {
+			errs = append(errs, field.Required(signerPath.Child("subject", "user"), ""))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("type"), registration.Type, []string{
+			string(addonv1alpha1.RegistrationTypeKubeClient),
+			string(addonv1alpha1.RegistrationTypeCustomSigner),
+		}))
+	}
+
+	return errs
+}
+
+func validateHubPermission(permission *addonv1alpha1.HubPermissionConfig, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch permission.Type {
+	case addonv1alpha1.HubPermissionsBindingCurrentCluster:
+		if permission.CurrentCluster == nil || len(permission.CurrentCluster.ClusterRoleName) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("currentCluster", "clusterRoleName"),
+				"clusterRoleName is required when the type is CurrentCluster"))
+		}
+	case addonv1alpha1.HubPermissionsBindingSingleNamespace:
+		bindingPath := fldPath.Child("singleNamespace")
+		if permission.SingleNamespace == nil {
+			return append(errs, field.Required(bindingPath, "singleNamespace is required when the type is SingleNamespace"))
+		}
+		if len(permission.SingleNamespace.Namespace) == 0 {
+			errs = append(errs, field.Required(bindingPath.Child("namespace"), ""))
+		} else {
+			for _, msg := range validation.ValidateNamespaceName(permission.SingleNamespace.Namespace, false) {
+				errs = append(errs, field.Invalid(bindingPath.Child("namespace"), permission.SingleNamespace.Namespace, msg))
+			}
+		}
+		roleRef := permission.SingleNamespace.RoleRef
+		if roleRef.Kind != "Role" && roleRef.Kind != "ClusterRole" {
+			errs = append(errs, field.NotSupported(bindingPath.Child("roleRef", "kind"), roleRef.Kind, []string{"Role", "ClusterRole"}))
+		}
+		if len(roleRef.Name) == 0 {
+			errs = append(errs, field.Required(bindingPath.Child("roleRef", "name"), ""))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("type"), permission.Type, []string{
+			string(addonv1alpha1.HubPermissionsBindingCurrentCluster),
+			string(addonv1alpha1.HubPermissionsBindingSingleNamespace),
+		}))
+	}
+
+	return errs
+}
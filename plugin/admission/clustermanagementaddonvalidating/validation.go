@@ -0,0 +1,142 @@
+// Copyright Contributors to the Open Cluster Management project
+package clustermanagementaddonvalidating
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1alpha1 "open-cluster-management.io/api/cluster/v1alpha1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/util"
+)
+
+// ClusterManagementAddOnWebhook validates the supported configs and the install strategy of the
+// ClusterManagementAddOns.
+type ClusterManagementAddOnWebhook struct {
+	checker *util.ResourceChecker
+}
+
+func (r *ClusterManagementAddOnWebhook) SetExternalKubeClientSet(client kubernetes.Interface) {
+	r.checker = util.NewResourceChecker(client.Discovery())
+}
+
+func (r *ClusterManagementAddOnWebhook) ValidateInitialization() error {
+	if r.checker == nil {
+		return fmt.Errorf("missing kube client")
+	}
+	return nil
+}
+
+func (r *ClusterManagementAddOnWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	cma, ok := obj.(*addonv1alpha1.ClusterManagementAddOn)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request clustermanagementaddon obj format is not right")
+	}
+	return nil, r.validate(cma)
+}
+
+func (r *ClusterManagementAddOnWebhook) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (runtimeadmission.Warnings, error) {
+	cma, ok := newObj.(*addonv1alpha1.ClusterManagementAddOn)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request clustermanagementaddon obj format is not right")
+	}
+	return nil, r.validate(cma)
+}
+
+func (r *ClusterManagementAddOnWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *ClusterManagementAddOnWebhook) validate(cma *addonv1alpha1.ClusterManagementAddOn) error {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+
+	supportedConfigs := sets.New[addonv1alpha1.ConfigGroupResource]()
+	for i, config := range cma.Spec.SupportedConfigs {
+		configPath := specPath.Child("supportedConfigs").Index(i)
+		if supportedConfigs.Has(config.ConfigGroupResource) {
+			errs = append(errs, field.Duplicate(configPath, config.ConfigGroupResource))
+			continue
+		}
+		supportedConfigs.Insert(config.ConfigGroupResource)
+
+		errs = append(errs, util.ValidateConfigGroupResource(config.ConfigGroupResource, configPath, r.checker)...)
+		if config.DefaultConfig != nil {
+			errs = append(errs, util.ValidateConfigReferent(*config.DefaultConfig, configPath.Child("defaultConfig"))...)
+		}
+	}
+
+	errs = append(errs, validateInstallStrategy(&cma.Spec.InstallStrategy, specPath.Child("installStrategy"), r.checker)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(addonv1alpha1.GroupVersion.WithKind("ClusterManagementAddOn").GroupKind(), cma.Name, errs)
+}
+
+func validateInstallStrategy(strategy *addonv1alpha1.InstallStrategy, fldPath *field.Path,
+	checker *util.ResourceChecker) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch strategy.Type {
+	case "", addonv1alpha1.AddonInstallStrategyManual:
+		if len(strategy.Placements) != 0 {
+			errs = append(errs, field.Forbidden(fldPath.Child("placements"),
+				"placements are only allowed when the type is Placements"))
+		}
+		return errs
+	case addonv1alpha1.AddonInstallStrategyPlacements:
+	default:
+		return append(errs, field.NotSupported(fldPath.Child("type"), strategy.Type, []string{
+			addonv1alpha1.AddonInstallStrategyManual,
+			addonv1alpha1.AddonInstallStrategyPlacements,
+		}))
+	}
+
+	placements := sets.New[addonv1alpha1.PlacementRef]()
+	for i, placement := range strategy.Placements {
+		placementPath := fldPath.Child("placements").Index(i)
+		if placements.Has(placement.PlacementRef) {
+			errs = append(errs, field.Duplicate(placementPath, placement.PlacementRef))
+			continue
+		}
+		placements.Insert(placement.PlacementRef)
+
+		if len(placement.Name) == 0 {
+			errs = append(errs, field.Required(placementPath.Child("name"), ""))
+		}
+		if len(placement.Namespace) == 0 {
+			errs = append(errs, field.Required(placementPath.Child("namespace"), ""))
+		} else {
+			for _, msg := range validation.ValidateNamespaceName(placement.Namespace, false) {
+				errs = append(errs, field.Invalid(placementPath.Child("namespace"), placement.Namespace, msg))
+			}
+		}
+
+		errs = append(errs, util.ValidateAddOnConfigs(placement.Configs, placementPath.Child("configs"), checker)...)
+		errs = append(errs, validateRolloutStrategy(&placement.RolloutStrategy, placementPath.Child("rolloutStrategy"))...)
+	}
+
+	return errs
+}
+
+func validateRolloutStrategy(strategy *clusterv1alpha1.RolloutStrategy, fldPath *field.Path) field.ErrorList {
+	switch strategy.Type {
+	case "", clusterv1alpha1.All, clusterv1alpha1.Progressive, clusterv1alpha1.ProgressivePerGroup:
+		return nil
+	}
+
+	return field.ErrorList{field.NotSupported(fldPath.Child("type"), strategy.Type, []string{
+		string(clusterv1alpha1.All),
+		string(clusterv1alpha1.Progressive),
+		string(clusterv1alpha1.ProgressivePerGroup),
+	})}
+}
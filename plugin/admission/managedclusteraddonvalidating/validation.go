@@ -0,0 +1,71 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclusteraddonvalidating
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/util"
+)
+
+// ManagedClusterAddOnWebhook validates the install namespace and the configs of the ManagedClusterAddOns.
+type ManagedClusterAddOnWebhook struct {
+	checker *util.ResourceChecker
+}
+
+func (r *ManagedClusterAddOnWebhook) SetExternalKubeClientSet(client kubernetes.Interface) {
+	r.checker = util.NewResourceChecker(client.Discovery())
+}
+
+func (r *ManagedClusterAddOnWebhook) ValidateInitialization() error {
+	if r.checker == nil {
+		return fmt.Errorf("missing kube client")
+	}
+	return nil
+}
+
+func (r *ManagedClusterAddOnWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	addon, ok := obj.(*addonv1alpha1.ManagedClusterAddOn)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request managedclusteraddon obj format is not right")
+	}
+	return nil, r.validate(addon)
+}
+
+func (r *ManagedClusterAddOnWebhook) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (runtimeadmission.Warnings, error) {
+	addon, ok := newObj.(*addonv1alpha1.ManagedClusterAddOn)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request managedclusteraddon obj format is not right")
+	}
+	return nil, r.validate(addon)
+}
+
+func (r *ManagedClusterAddOnWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *ManagedClusterAddOnWebhook) validate(addon *addonv1alpha1.ManagedClusterAddOn) error {
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+
+	if len(addon.Spec.InstallNamespace) != 0 {
+		for _, msg := range validation.IsDNS1123Label(addon.Spec.InstallNamespace) {
+			errs = append(errs, field.Invalid(specPath.Child("installNamespace"), addon.Spec.InstallNamespace, msg))
+		}
+	}
+
+	errs = append(errs, util.ValidateAddOnConfigs(addon.Spec.Configs, specPath.Child("configs"), r.checker)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(addonv1alpha1.GroupVersion.WithKind("ManagedClusterAddOn").GroupKind(), addon.Name, errs)
+}
@@ -0,0 +1,184 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclusterdeleteprotection
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apiserver/pkg/admission"
+	genericadmissioninitializer "k8s.io/apiserver/pkg/admission/initializer"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/dynamic"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+)
+
+const PluginName = "ManagedClusterDeleteProtection"
+
+// ForceDeleteAnnotation allows to delete the ManagedCluster, ManagedClusterSet or cluster namespace that still has
+// the ManifestWorks or addons if its value is "true", the annotation can only be set to "true" by the users who
+// have the update permission of the managedclusters/force-delete subresource.
+const ForceDeleteAnnotation = "multicluster-controlplane.open-cluster-management.io/force-delete"
+
+var (
+	managedClusterGVK    = clusterv1.GroupVersion.WithKind("ManagedCluster")
+	managedClusterSetGVK = clusterv1beta2.GroupVersion.WithKind("ManagedClusterSet")
+	namespaceGVK         = corev1.SchemeGroupVersion.WithKind("Namespace")
+)
+
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(config io.Reader) (admission.Interface, error) {
+		return NewPlugin(), nil
+	})
+}
+
+// Plugin rejects the deletion of the ManagedClusters, ManagedClusterSets and cluster namespaces that still have
+// the ManifestWorks or ManagedClusterAddOns, unless the force-delete annotation is set on the object before it
+// is deleted.
+type Plugin struct {
+	*admission.Handler
+	authorizer    authorizer.Authorizer
+	dynamicClient dynamic.Interface
+}
+
+var _ admission.ValidationInterface = &Plugin{}
+var _ admission.InitializationValidator = &Plugin{}
+var _ = genericadmissioninitializer.WantsAuthorizer(&Plugin{})
+var _ = genericadmissioninitializer.WantsDynamicClient(&Plugin{})
+
+func NewPlugin() *Plugin {
+	return &Plugin{
+		Handler: admission.NewHandler(admission.Create, admission.Update, admission.Delete),
+	}
+}
+
+func (p *Plugin) SetAuthorizer(authz authorizer.Authorizer) {
+	p.authorizer = authz
+}
+
+func (p *Plugin) SetDynamicClient(client dynamic.Interface) {
+	p.dynamicClient = client
+}
+
+func (p *Plugin) ValidateInitialization() error {
+	if p.authorizer == nil {
+		return fmt.Errorf("missing authorizer")
+	}
+	if p.dynamicClient == nil {
+		return fmt.Errorf("missing dynamic client")
+	}
+	return nil
+}
+
+func (p *Plugin) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	kind := a.GetKind()
+	if kind != managedClusterGVK && kind != managedClusterSetGVK && kind != namespaceGVK {
+		return nil
+	}
+	if len(a.GetSubresource()) != 0 {
+		return nil
+	}
+
+	switch a.GetOperation() {
+	case admission.Create, admission.Update:
+		return p.validateForceDeleteAnnotation(ctx, a)
+	case admission.Delete:
+		return p.validateDeletion(ctx, a)
+	}
+	return nil
+}
+
+// validateForceDeleteAnnotation checks whether the requester is allowed to set the force-delete annotation
+func (p *Plugin) validateForceDeleteAnnotation(ctx context.Context, a admission.Attributes) error {
+	forced, err := forceDeleted(a.GetObject())
+	if err != nil || !forced {
+		return err
+	}
+	if a.GetOperation() == admission.Update {
+		oldForced, err := forceDeleted(a.GetOldObject())
+		if err != nil || oldForced {
+			return err
+		}
+	}
+
+	// the set may contain any cluster, so the permission of all clusters is required to force delete a set
+	clusterName := a.GetName()
+	if a.GetKind() == managedClusterSetGVK {
+		clusterName = ""
+	}
+
+	decision, reason, err := p.authorizer.Authorize(ctx, authorizer.AttributesRecord{
+		User:            a.GetUserInfo(),
+		Verb:            "update",
+		APIGroup:        clusterv1.GroupName,
+		APIVersion:      "*",
+		Resource:        "managedclusters",
+		Subresource:     "force-delete",
+		Name:            clusterName,
+		ResourceRequest: true,
+	})
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if decision != authorizer.DecisionAllow {
+		msg := fmt.Sprintf("user %q cannot set the annotation %s, it requires the update permission of "+
+			"managedclusters/force-delete", a.GetUserInfo().GetName(), ForceDeleteAnnotation)
+		if len(reason) != 0 {
+			msg = fmt.Sprintf("%s: %s", msg, reason)
+		}
+		return admission.NewForbidden(a, fmt.Errorf("%s", msg))
+	}
+	return nil
+}
+
+// validateDeletion rejects the deletion if the object is not annotated with the force-delete annotation and
+// there are still ManifestWorks or addons in the cluster namespaces.
+func (p *Plugin) validateDeletion(ctx context.Context, a admission.Attributes) error {
+	// the old object is the object that is being deleted, it may be nil if the apiserver does not load it
+	if a.GetOldObject() == nil {
+		return nil
+	}
+	accessor, err := meta.Accessor(a.GetOldObject())
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	// the object was admitted to be deleted
+	if accessor.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	if accessor.GetAnnotations()[ForceDeleteAnnotation] == "true" {
+		return nil
+	}
+
+	var workloads []workload
+	switch a.GetKind() {
+	case managedClusterGVK:
+		workloads, err = p.clusterWorkloads(ctx, a.GetName())
+	case managedClusterSetGVK:
+		workloads, err = p.clusterSetWorkloads(ctx, a.GetOldObject())
+	case namespaceGVK:
+		workloads, err = p.namespaceWorkloads(ctx, a.GetName())
+	}
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	return admission.NewForbidden(a, fmt.Errorf("there are still %s, set the annotation %s=true before deleting it "+
+		"if the deletion is intended", describeWorkloads(workloads), ForceDeleteAnnotation))
+}
+
+// forceDeleted returns true if the force-delete annotation of the object is "true"
+func forceDeleted(obj interface{}) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, apierrors.NewBadRequest(err.Error())
+	}
+	return accessor.GetAnnotations()[ForceDeleteAnnotation] == "true", nil
+}
@@ -0,0 +1,167 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclusterdeleteprotection
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newObject(gvk schema.GroupVersionKind, namespace, name string, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func TestValidate(t *testing.T) {
+	manifestWorkGVK := manifestWorkGVR.GroupVersion().WithKind("ManifestWork")
+	forced := map[string]string{ForceDeleteAnnotation: "true"}
+	notForced := map[string]string{ForceDeleteAnnotation: "false"}
+	work := newObject(manifestWorkGVK, "cluster1", "work1", nil)
+
+	tests := []struct {
+		name          string
+		kind          schema.GroupVersionKind
+		operation     admission.Operation
+		obj           *unstructured.Unstructured
+		oldObj        *unstructured.Unstructured
+		existing      []runtime.Object
+		allowed       bool
+		wantForbidden bool
+	}{
+		{
+			name:          "delete the cluster that has ManifestWorks",
+			kind:          managedClusterGVK,
+			operation:     admission.Delete,
+			oldObj:        newObject(managedClusterGVK, "", "cluster1", nil),
+			existing:      []runtime.Object{work},
+			wantForbidden: true,
+		},
+		{
+			name:      "force delete the cluster that has ManifestWorks",
+			kind:      managedClusterGVK,
+			operation: admission.Delete,
+			oldObj:    newObject(managedClusterGVK, "", "cluster1", forced),
+			existing:  []runtime.Object{work},
+		},
+		{
+			name:          "the annotation that is not true does not force delete the cluster",
+			kind:          managedClusterGVK,
+			operation:     admission.Delete,
+			oldObj:        newObject(managedClusterGVK, "", "cluster1", notForced),
+			existing:      []runtime.Object{work},
+			wantForbidden: true,
+		},
+		{
+			name:      "delete the cluster that has no workloads",
+			kind:      managedClusterGVK,
+			operation: admission.Delete,
+			oldObj:    newObject(managedClusterGVK, "", "cluster1", nil),
+		},
+		{
+			name:      "delete the namespace of a cluster that has ManifestWorks",
+			kind:      namespaceGVK,
+			operation: admission.Delete,
+			oldObj:    newObject(namespaceGVK, "", "cluster1", nil),
+			existing: []runtime.Object{
+				newObject(managedClusterGVK, "", "cluster1", nil),
+				work,
+			},
+			wantForbidden: true,
+		},
+		{
+			name:      "delete the namespace that is not a cluster namespace",
+			kind:      namespaceGVK,
+			operation: admission.Delete,
+			oldObj:    newObject(namespaceGVK, "", "cluster1", nil),
+			existing:  []runtime.Object{work},
+		},
+		{
+			name:          "set the annotation without permission",
+			kind:          managedClusterGVK,
+			operation:     admission.Create,
+			obj:           newObject(managedClusterGVK, "", "cluster1", forced),
+			wantForbidden: true,
+		},
+		{
+			name:      "set the annotation with permission",
+			kind:      managedClusterGVK,
+			operation: admission.Create,
+			obj:       newObject(managedClusterGVK, "", "cluster1", forced),
+			allowed:   true,
+		},
+		{
+			name:      "set the annotation to false without permission",
+			kind:      managedClusterGVK,
+			operation: admission.Create,
+			obj:       newObject(managedClusterGVK, "", "cluster1", notForced),
+		},
+		{
+			name:      "the annotation is not changed",
+			kind:      managedClusterGVK,
+			operation: admission.Update,
+			obj:       newObject(managedClusterGVK, "", "cluster1", forced),
+			oldObj:    newObject(managedClusterGVK, "", "cluster1", forced),
+		},
+		{
+			name:          "the annotation is changed from false to true without permission",
+			kind:          managedClusterGVK,
+			operation:     admission.Update,
+			obj:           newObject(managedClusterGVK, "", "cluster1", forced),
+			oldObj:        newObject(managedClusterGVK, "", "cluster1", notForced),
+			wantForbidden: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{
+					managedClusterGVR:      "ManagedClusterList",
+					manifestWorkGVR:        "ManifestWorkList",
+					managedClusterAddOnGVR: "ManagedClusterAddOnList",
+				}, tt.existing...)
+
+			plugin := NewPlugin()
+			plugin.SetDynamicClient(dynamicClient)
+			plugin.SetAuthorizer(authorizer.AuthorizerFunc(
+				func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+					if tt.allowed {
+						return authorizer.DecisionAllow, "", nil
+					}
+					return authorizer.DecisionDeny, "", nil
+				}))
+			if err := plugin.ValidateInitialization(); err != nil {
+				t.Fatal(err)
+			}
+
+			var obj, oldObj runtime.Object
+			if tt.obj != nil {
+				obj = tt.obj
+			}
+			if tt.oldObj != nil {
+				oldObj = tt.oldObj
+			}
+			attrs := admission.NewAttributesRecord(obj, oldObj, tt.kind, "", "cluster1",
+				schema.GroupVersionResource{}, "", tt.operation, nil, false, &user.DefaultInfo{Name: "user1"})
+
+			err := plugin.Validate(context.TODO(), attrs, nil)
+			if apierrors.IsForbidden(err) != tt.wantForbidden {
+				t.Errorf("Validate() error = %v, wantForbidden %v", err, tt.wantForbidden)
+			}
+			if err != nil && !apierrors.IsForbidden(err) {
+				t.Errorf("Validate() error = %v, want a forbidden error", err)
+			}
+		})
+	}
+}
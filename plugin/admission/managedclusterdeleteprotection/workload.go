@@ -0,0 +1,137 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclusterdeleteprotection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+var (
+	managedClusterGVR      = clusterv1.GroupVersion.WithResource("managedclusters")
+	manifestWorkGVR        = workv1.GroupVersion.WithResource("manifestworks")
+	managedClusterAddOnGVR = addonv1alpha1.GroupVersion.WithResource("managedclusteraddons")
+)
+
+// workload is the ManifestWorks and addons in a cluster namespace
+type workload struct {
+	cluster       string
+	manifestWorks int
+	addOns        int
+}
+
+// clusterWorkloads returns the workload of the cluster, it is nil if there is no ManifestWork or addon
+func (p *Plugin) clusterWorkloads(ctx context.Context, clusterName string) ([]workload, error) {
+	// list from the watch cache of the apiserver
+	works, err := p.dynamicClient.Resource(manifestWorkGVR).Namespace(clusterName).List(ctx, metav1.ListOptions{
+		ResourceVersion: "0",
+	})
+	if err != nil {
+		return nil, err
+	}
+	addOns, err := p.dynamicClient.Resource(managedClusterAddOnGVR).Namespace(clusterName).List(ctx, metav1.ListOptions{
+		ResourceVersion: "0",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(works.Items) == 0 && len(addOns.Items) == 0 {
+		return nil, nil
+	}
+	return []workload{{cluster: clusterName, manifestWorks: len(works.Items), addOns: len(addOns.Items)}}, nil
+}
+
+// namespaceWorkloads returns the workload of the namespace if it is the namespace of a cluster that is not being
+// deleted, the namespace of a deleting cluster is cleaned up after the cluster deletion is admitted.
+func (p *Plugin) namespaceWorkloads(ctx context.Context, namespace string) ([]workload, error) {
+	cluster, err := p.dynamicClient.Resource(managedClusterGVR).Get(ctx, namespace, metav1.GetOptions{
+		ResourceVersion: "0",
+	})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cluster.GetDeletionTimestamp() != nil {
+		return nil, nil
+	}
+
+	return p.clusterWorkloads(ctx, namespace)
+}
+
+// clusterSetWorkloads returns the workloads of the clusters that are selected by the ManagedClusterSet
+func (p *Plugin) clusterSetWorkloads(ctx context.Context, obj runtime.Object) ([]workload, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+	clusterSet := &clusterv1beta2.ManagedClusterSet{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, clusterSet); err != nil {
+		return nil, err
+	}
+
+	selector, err := clusterSetSelector(clusterSet)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := p.dynamicClient.Resource(managedClusterGVR).List(ctx, metav1.ListOptions{
+		ResourceVersion: "0",
+		LabelSelector:   selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := []workload{}
+	for _, cluster := range clusters.Items {
+		clusterWorkloads, err := p.clusterWorkloads(ctx, cluster.GetName())
+		if err != nil {
+			return nil, err
+		}
+		workloads = append(workloads, clusterWorkloads...)
+	}
+	return workloads, nil
+}
+
+func clusterSetSelector(clusterSet *clusterv1beta2.ManagedClusterSet) (labels.Selector, error) {
+	switch clusterSet.Spec.ClusterSelector.SelectorType {
+	case clusterv1beta2.LabelSelector:
+		if clusterSet.Spec.ClusterSelector.LabelSelector == nil {
+			return labels.Nothing(), nil
+		}
+		return metav1.LabelSelectorAsSelector(clusterSet.Spec.ClusterSelector.LabelSelector)
+	default:
+		// the ExclusiveClusterSetLabel is the default selector type
+		return labels.SelectorFromSet(labels.Set{clusterv1beta2.ClusterSetLabel: clusterSet.Name}), nil
+	}
+}
+
+func describeWorkloads(workloads []workload) string {
+	manifestWorks, addOns := 0, 0
+	clusters := []string{}
+	for _, w := range workloads {
+		manifestWorks += w.manifestWorks
+		addOns += w.addOns
+		clusters = append(clusters, w.cluster)
+	}
+
+	const maxClusters = 5
+	if len(clusters) > maxClusters {
+		clusters = append(clusters[:maxClusters], "...")
+	}
+	return fmt.Sprintf("%d ManifestWork(s) and %d ManagedClusterAddOn(s) in the cluster namespace(s) [%s]",
+		manifestWorks, addOns, strings.Join(clusters, ", "))
+}
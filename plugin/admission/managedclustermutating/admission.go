@@ -2,102 +2,37 @@
 package managedclustermutating
 
 import (
-	"context"
-	"fmt"
 	"io"
 
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/apiserver/pkg/admission"
-	"k8s.io/apiserver/pkg/admission/plugin/webhook/generic"
-	"k8s.io/apiserver/pkg/admission/plugin/webhook/request"
 	clusterv1api "open-cluster-management.io/api/cluster/v1"
-	clusterwebhookv1 "open-cluster-management.io/ocm/pkg/registration/webhook/v1"
-	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
-	admissionutil "open-cluster-management.io/multicluster-controlplane/plugin/admission/util"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/adapter"
 )
 
 const PluginName = "ManagedClusterMutating"
 
 func Register(plugins *admission.Plugins) {
 	plugins.Register(PluginName, func(config io.Reader) (admission.Interface, error) {
-		return NewPlugin(), nil
+		cfg, err := loadConfiguration(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewPlugin(cfg)
 	})
 }
 
-type Plugin struct {
-	*admission.Handler
-	webhook *clusterwebhookv1.ManagedClusterWebhook
-}
-
-func (p *Plugin) ValidateInitialization() error {
-	return nil
-}
-
-var _ admission.MutationInterface = &Plugin{}
-var _ admission.InitializationValidator = &Plugin{}
-
-func NewPlugin() *Plugin {
-	return &Plugin{
-		Handler: admission.NewHandler(admission.Create, admission.Update),
-		webhook: &clusterwebhookv1.ManagedClusterWebhook{},
-	}
-}
-
-func (p *Plugin) Admit(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
-	v := admission.VersionedAttributes{
-		Attributes:         a,
-		VersionedOldObject: a.GetOldObject(),
-		VersionedObject:    a.GetObject(),
-		VersionedKind:      a.GetKind(),
-	}
-
-	gvr := clusterv1api.GroupVersion.WithResource("managedclusters")
-	gvk := clusterv1api.GroupVersion.WithKind("ManagedCluster")
-
-	// resource is not mcl
-	if a.GetKind() != gvk {
-		return nil
-	}
-
-	// we just need gvr in code logic
-	i := generic.WebhookInvocation{
-		Kind:     gvk,
-		Resource: gvr,
-	}
-
-	uid := types.UID(uuid.NewUUID())
-	ar := request.CreateV1AdmissionReview(uid, &v, &i)
-
-	old := a.GetOldObject()
-	oldRaw := runtime.RawExtension{}
-	err := admissionutil.Convert_runtime_Object_To_runtime_RawExtension_Raw(&old, &oldRaw)
-	if err != nil {
-		return fmt.Errorf("error occured in ManagedClusterMutating: failed to convert Object to RawExtension")
-	}
-	ar.Request.OldObject = oldRaw
-
-	cluster := &clusterv1api.ManagedCluster{}
-	obj := a.GetObject().(*unstructured.Unstructured)
-	err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, cluster)
-	if err != nil {
-		return err
-	}
-
-	r := runtimeadmission.Request{AdmissionRequest: *ar.Request}
-	admissionContext := runtimeadmission.NewContextWithRequest(ctx, r)
-	if err := p.webhook.Default(admissionContext, cluster); err != nil {
-		return err
-	}
-
-	updated, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cluster)
+func NewPlugin(cfg *Configuration) (*adapter.MutatingPlugin, error) {
+	webhook, err := NewManagedClusterMutatingWebhook(cfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	obj.Object = updated
 
-	return nil
+	return adapter.NewMutatingPlugin(
+		clusterv1api.GroupVersion.WithResource("managedclusters"),
+		clusterv1api.GroupVersion.WithKind("ManagedCluster"),
+		func() runtime.Object { return &clusterv1api.ManagedCluster{} },
+		webhook,
+	), nil
 }
@@ -0,0 +1,137 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclustermutating
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Configuration is the configuration of the ManagedClusterMutating plugin in the admission config file
+type Configuration struct {
+	// ClusterSetAssignments are the ordered rules to choose the ManagedClusterSet of the new clusters, the first
+	// matched rule is used.
+	ClusterSetAssignments []AssignmentRule `json:"clusterSetAssignments,omitempty"`
+}
+
+// AssignmentRule puts a new cluster into the ClusterSet if the cluster matches all of the specified conditions.
+// The cluster names and users are regular expressions that must match the whole value.
+type AssignmentRule struct {
+	Name       string `json:"name"`
+	ClusterSet string `json:"clusterSet"`
+	// ClusterSelector selects the clusters by their labels
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// ClusterNames matches the names of the clusters
+	ClusterNames []string `json:"clusterNames,omitempty"`
+	// Users matches the name of the user that creates the cluster, e.g. the bootstrap user of the agent
+	Users []string `json:"users,omitempty"`
+	// Groups matches the groups of the user that creates the cluster, e.g. the extra groups of a bootstrap token
+	Groups []string `json:"groups,omitempty"`
+}
+
+// loadConfiguration loads the configuration from the plugin config, there is no assignment rule if the plugin
+// is not configured in the admission config file.
+func loadConfiguration(config io.Reader) (*Configuration, error) {
+	cfg := &Configuration{}
+	if config == nil {
+		return cfg, nil
+	}
+
+	if err := utilyaml.NewYAMLOrJSONDecoder(config, 4096).Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode the %s configuration, %v", PluginName, err)
+	}
+
+	for i, rule := range cfg.ClusterSetAssignments {
+		if len(rule.Name) == 0 {
+			return nil, fmt.Errorf("the name of the cluster set assignment %d is required", i)
+		}
+		if len(rule.ClusterSet) == 0 {
+			return nil, fmt.Errorf("the clusterSet of the cluster set assignment %q is required", rule.Name)
+		}
+		// the cluster set is set to the clusterset label of the clusters
+		if errs := validation.IsValidLabelValue(rule.ClusterSet); len(errs) != 0 {
+			return nil, fmt.Errorf("the clusterSet %q of the cluster set assignment %q is invalid, %v",
+				rule.ClusterSet, rule.Name, errs)
+		}
+	}
+
+	return cfg, nil
+}
+
+type compiledRule struct {
+	AssignmentRule
+	selector     labels.Selector
+	clusterNames []*regexp.Regexp
+	users        []*regexp.Regexp
+	groups       sets.Set[string]
+}
+
+func compileRules(rules []AssignmentRule) ([]compiledRule, error) {
+	compiledRules := []compiledRule{}
+	for _, rule := range rules {
+		selector := labels.Everything()
+		if rule.ClusterSelector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(rule.ClusterSelector); err != nil {
+				return nil, fmt.Errorf("the clusterSelector of the cluster set assignment %q is invalid, %v", rule.Name, err)
+			}
+		}
+		clusterNames, err := compilePatterns(rule.ClusterNames)
+		if err != nil {
+			return nil, fmt.Errorf("the clusterNames of the cluster set assignment %q are invalid, %v", rule.Name, err)
+		}
+		users, err := compilePatterns(rule.Users)
+		if err != nil {
+			return nil, fmt.Errorf("the users of the cluster set assignment %q are invalid, %v", rule.Name, err)
+		}
+		compiledRules = append(compiledRules, compiledRule{
+			AssignmentRule: rule,
+			selector:       selector,
+			clusterNames:   clusterNames,
+			users:          users,
+			groups:         sets.New(rule.Groups...),
+		})
+	}
+	return compiledRules, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		// the pattern must match the whole value
+		regex, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, regex)
+	}
+	return regexps, nil
+}
+
+func (r *compiledRule) matches(clusterName string, clusterLabels map[string]string, user string, groups []string) bool {
+	if !r.selector.Matches(labels.Set(clusterLabels)) {
+		return false
+	}
+	if !matchesPatterns(r.clusterNames, clusterName) || !matchesPatterns(r.users, user) {
+		return false
+	}
+	return r.groups.Len() == 0 || r.groups.HasAny(groups...)
+}
+
+func matchesPatterns(patterns []*regexp.Regexp, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
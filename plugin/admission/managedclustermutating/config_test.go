@@ -0,0 +1,212 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclustermutating
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoadConfiguration(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		wantRules int
+		wantErr   bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "rules",
+			config: `
+clusterSetAssignments:
+- name: dev
+  clusterSet: dev
+  clusterNames: ["dev-.*"]
+- name: prod
+  clusterSet: prod
+  clusterSelector:
+    matchLabels:
+      env: prod
+`,
+			wantRules: 2,
+		},
+		{
+			name: "no name",
+			config: `
+clusterSetAssignments:
+- clusterSet: dev
+`,
+			wantErr: true,
+		},
+		{
+			name: "no cluster set",
+			config: `
+clusterSetAssignments:
+- name: dev
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid cluster set",
+			config: `
+clusterSetAssignments:
+- name: dev
+  clusterSet: dev/set
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadConfiguration(strings.NewReader(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(cfg.ClusterSetAssignments) != tt.wantRules {
+				t.Errorf("loadConfiguration() rules = %v, want %d rules", cfg.ClusterSetAssignments, tt.wantRules)
+			}
+		})
+	}
+}
+
+func TestCompileRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    AssignmentRule
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			rule: AssignmentRule{
+				Name:            "dev",
+				ClusterSet:      "dev",
+				ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}},
+				ClusterNames:    []string{"dev-.*"},
+				Users:           []string{"system:bootstrap:.*"},
+			},
+		},
+		{
+			name: "invalid cluster selector",
+			rule: AssignmentRule{
+				Name:       "dev",
+				ClusterSet: "dev",
+				ClusterSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: "Unknown"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid cluster names",
+			rule:    AssignmentRule{Name: "dev", ClusterSet: "dev", ClusterNames: []string{"dev-("}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid users",
+			rule:    AssignmentRule{Name: "dev", ClusterSet: "dev", Users: []string{"["}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileRules([]AssignmentRule{tt.rule}); (err != nil) != tt.wantErr {
+				t.Errorf("compileRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name          string
+		rule          AssignmentRule
+		clusterName   string
+		clusterLabels map[string]string
+		user          string
+		groups        []string
+		want          bool
+	}{
+		{
+			name:        "no conditions",
+			rule:        AssignmentRule{},
+			clusterName: "cluster1",
+			want:        true,
+		},
+		{
+			name:        "the cluster name matches",
+			rule:        AssignmentRule{ClusterNames: []string{"dev-.*", "test"}},
+			clusterName: "dev-cluster1",
+			want:        true,
+		},
+		{
+			name:        "the pattern must match the whole cluster name",
+			rule:        AssignmentRule{ClusterNames: []string{"dev"}},
+			clusterName: "dev-cluster1",
+		},
+		{
+			name:          "the labels match",
+			rule:          AssignmentRule{ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+			clusterName:   "cluster1",
+			clusterLabels: map[string]string{"env": "prod", "region": "us"},
+			want:          true,
+		},
+		{
+			name:          "the labels do not match",
+			rule:          AssignmentRule{ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+			clusterName:   "cluster1",
+			clusterLabels: map[string]string{"env": "dev"},
+		},
+		{
+			name:        "the user matches",
+			rule:        AssignmentRule{Users: []string{"system:bootstrap:.*"}},
+			clusterName: "cluster1",
+			user:        "system:bootstrap:abcdef",
+			want:        true,
+		},
+		{
+			name:        "the user does not match",
+			rule:        AssignmentRule{Users: []string{"system:bootstrap:.*"}},
+			clusterName: "cluster1",
+			user:        "admin",
+		},
+		{
+			name:        "the group matches",
+			rule:        AssignmentRule{Groups: []string{"system:bootstrappers:dev"}},
+			clusterName: "cluster1",
+			groups:      []string{"system:bootstrappers", "system:bootstrappers:dev"},
+			want:        true,
+		},
+		{
+			name:        "the group does not match",
+			rule:        AssignmentRule{Groups: []string{"system:bootstrappers:dev"}},
+			clusterName: "cluster1",
+			groups:      []string{"system:bootstrappers"},
+		},
+		{
+			name: "all of the conditions must match",
+			rule: AssignmentRule{
+				ClusterNames: []string{"dev-.*"},
+				Users:        []string{"system:bootstrap:.*"},
+			},
+			clusterName: "dev-cluster1",
+			user:        "admin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := compileRules([]AssignmentRule{tt.rule})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rules[0].matches(tt.clusterName, tt.clusterLabels, tt.user, tt.groups); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
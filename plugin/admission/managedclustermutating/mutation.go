@@ -0,0 +1,71 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclustermutating
+
+import (
+	"context"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	clusterwebhookv1 "open-cluster-management.io/ocm/pkg/registration/webhook/v1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ManagedClusterMutatingWebhook puts the new clusters into the ManagedClusterSets by the assignment rules before
+// the ocm defaulting, so the clusters that are not matched by any rule are still put into the default set.
+// The chosen set is validated by the ManagedClusterValidating like the set that is specified by the requester,
+// so the requester must be allowed to join the set.
+type ManagedClusterMutatingWebhook struct {
+	*clusterwebhookv1.ManagedClusterWebhook
+	rules []compiledRule
+}
+
+func NewManagedClusterMutatingWebhook(cfg *Configuration) (*ManagedClusterMutatingWebhook, error) {
+	rules, err := compileRules(cfg.ClusterSetAssignments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ManagedClusterMutatingWebhook{
+		ManagedClusterWebhook: &clusterwebhookv1.ManagedClusterWebhook{},
+		rules:                 rules,
+	}, nil
+}
+
+func (r *ManagedClusterMutatingWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	req, err := runtimeadmission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	cluster, ok := obj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return apierrors.NewBadRequest("Request cluster obj format is not right")
+	}
+
+	// the rules are only applied to the new clusters, the existing clusters are not moved to another set
+	// when their labels are changed.
+	if req.Operation == admissionv1.Create && len(cluster.Labels[clusterv1beta2.ClusterSetLabel]) == 0 {
+		r.assignClusterSet(cluster, req.UserInfo.Username, req.UserInfo.Groups)
+	}
+
+	return r.ManagedClusterWebhook.Default(ctx, obj)
+}
+
+func (r *ManagedClusterMutatingWebhook) assignClusterSet(cluster *clusterv1.ManagedCluster, user string, groups []string) {
+	for _, rule := range r.rules {
+		if !rule.matches(cluster.Name, cluster.Labels, user, groups) {
+			continue
+		}
+
+		klog.V(4).Infof("the cluster %s is assigned to the cluster set %s by the rule %q", cluster.Name, rule.ClusterSet, rule.Name)
+		if cluster.Labels == nil {
+			cluster.Labels = map[string]string{}
+		}
+		cluster.Labels[clusterv1beta2.ClusterSetLabel] = rule.ClusterSet
+		return
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright Contributors to the Open Cluster Management project
+package managedclustermutating
+
+import (
+	"context"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	ocmfeature "open-cluster-management.io/api/feature"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclustervalidating"
+)
+
+func init() {
+	utilruntime.Must(features.HubMutableFeatureGate.Add(ocmfeature.DefaultHubRegistrationFeatureGates))
+}
+
+var (
+	clusterResource = clusterv1.GroupVersion.WithResource("managedclusters")
+	clusterKind     = clusterv1.GroupVersion.WithKind("ManagedCluster")
+)
+
+var testRules = []AssignmentRule{
+	{
+		Name:         "dev-clusters",
+		ClusterSet:   "dev",
+		ClusterNames: []string{"dev-.*"},
+	},
+	{
+		Name:       "bootstrap-users",
+		ClusterSet: "edge",
+		Users:      []string{"system:bootstrap:.*"},
+	},
+	{
+		Name:            "prod-clusters",
+		ClusterSet:      "prod",
+		ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+	},
+}
+
+func newCluster(name string, labels map[string]string) *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(clusterKind)
+	cluster.SetName(name)
+	cluster.SetLabels(labels)
+	return cluster
+}
+
+func newAttributes(cluster, oldCluster runtime.Object, operation admission.Operation,
+	userName string) admission.Attributes {
+	return admission.NewAttributesRecord(cluster, oldCluster, clusterKind, "", "", clusterResource, "",
+		operation, nil, false, &user.DefaultInfo{Name: userName})
+}
+
+func TestAssignClusterSet(t *testing.T) {
+	tests := []struct {
+		name           string
+		cluster        *unstructured.Unstructured
+		oldCluster     *unstructured.Unstructured
+		operation      admission.Operation
+		user           string
+		wantClusterSet string
+	}{
+		{
+			name:           "the cluster name matches",
+			cluster:        newCluster("dev-cluster1", nil),
+			operation:      admission.Create,
+			user:           "admin",
+			wantClusterSet: "dev",
+		},
+		{
+			name:           "the first matched rule is used",
+			cluster:        newCluster("dev-cluster1", map[string]string{"env": "prod"}),
+			operation:      admission.Create,
+			user:           "system:bootstrap:abcdef",
+			wantClusterSet: "dev",
+		},
+		{
+			name:           "the user matches",
+			cluster:        newCluster("cluster1", map[string]string{"env": "prod"}),
+			operation:      admission.Create,
+			user:           "system:bootstrap:abcdef",
+			wantClusterSet: "edge",
+		},
+		{
+			name:           "the labels match",
+			cluster:        newCluster("cluster1", map[string]string{"env": "prod"}),
+			operation:      admission.Create,
+			user:           "admin",
+			wantClusterSet: "prod",
+		},
+		{
+			name:           "no rule matches",
+			cluster:        newCluster("cluster1", nil),
+			operation:      admission.Create,
+			user:           "admin",
+			wantClusterSet: "default",
+		},
+		{
+			name:           "the cluster set is specified",
+			cluster:        newCluster("dev-cluster1", map[string]string{clusterv1beta2.ClusterSetLabel: "test"}),
+			operation:      admission.Create,
+			user:           "admin",
+			wantClusterSet: "test",
+		},
+		{
+			name:           "the existing cluster is not moved",
+			cluster:        newCluster("dev-cluster1", map[string]string{clusterv1beta2.ClusterSetLabel: "default"}),
+			oldCluster:     newCluster("dev-cluster1", map[string]string{clusterv1beta2.ClusterSetLabel: "default"}),
+			operation:      admission.Update,
+			user:           "admin",
+			wantClusterSet: "default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, err := NewPlugin(&Configuration{ClusterSetAssignments: testRules})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var oldCluster runtime.Object
+			if tt.oldCluster != nil {
+				oldCluster = tt.oldCluster
+			}
+			if err := plugin.Admit(context.TODO(), newAttributes(tt.cluster, oldCluster, tt.operation, tt.user), nil); err != nil {
+				t.Fatalf("Admit() error = %v", err)
+			}
+
+			if clusterSet := tt.cluster.GetLabels()[clusterv1beta2.ClusterSetLabel]; clusterSet != tt.wantClusterSet {
+				t.Errorf("the cluster set = %q, want %q", clusterSet, tt.wantClusterSet)
+			}
+		})
+	}
+}
+
+// TestJoinChosenClusterSet verifies the requester must be allowed to join the cluster set that is chosen by
+// the assignment rules
+func TestJoinChosenClusterSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		cluster     *unstructured.Unstructured
+		allowedSets []string
+		wantSARSet  string
+		wantErr     bool
+	}{
+		{
+			name:        "allowed to join the chosen set",
+			cluster:     newCluster("dev-cluster1", nil),
+			allowedSets: []string{"dev"},
+			wantSARSet:  "dev",
+		},
+		{
+			name:        "not allowed to join the chosen set",
+			cluster:     newCluster("dev-cluster1", nil),
+			allowedSets: []string{"default"},
+			wantSARSet:  "dev",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			reviewedSets := []string{}
+			kubeClient.PrependReactor("create", "subjectaccessreviews",
+				func(action clienttesting.Action) (bool, runtime.Object, error) {
+					sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+					attrs := sar.Spec.ResourceAttributes
+					if attrs.Resource != "managedclustersets" || attrs.Subresource != "join" {
+						return true, sar, nil
+					}
+					reviewedSets = append(reviewedSets, attrs.Name)
+					for _, set := range tt.allowedSets {
+						if set == attrs.Name {
+							sar.Status.Allowed = true
+						}
+					}
+					return true, sar, nil
+				})
+
+			mutating, err := NewPlugin(&Configuration{ClusterSetAssignments: testRules})
+			if err != nil {
+				t.Fatal(err)
+			}
+			validating := managedclustervalidating.NewPlugin()
+			validating.SetExternalKubeClientSet(kubeClient)
+
+			attrs := newAttributes(tt.cluster, nil, admission.Create, "admin")
+			if err := mutating.Admit(context.TODO(), attrs, nil); err != nil {
+				t.Fatalf("Admit() error = %v", err)
+			}
+			err = validating.Validate(context.TODO(), attrs, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !apierrors.IsForbidden(err) {
+				t.Errorf("expected forbidden error, but got %v", err)
+			}
+
+			if len(reviewedSets) != 1 || reviewedSets[0] != tt.wantSARSet {
+				t.Errorf("the reviewed cluster sets = %v, want %q", reviewedSets, tt.wantSARSet)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkpolicy
+
+import (
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/adapter"
+)
+
+const PluginName = "ManifestWorkPolicy"
+
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(config io.Reader) (admission.Interface, error) {
+		cfg, err := loadConfiguration(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewPlugin(cfg)
+	})
+}
+
+func NewPlugin(cfg *Configuration) (*adapter.ValidatingPlugin, error) {
+	webhook, err := NewManifestWorkPolicyWebhook(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return adapter.NewValidatingPlugin(
+		workv1.GroupVersion.WithResource("manifestworks"),
+		workv1.GroupVersion.WithKind("ManifestWork"),
+		func() runtime.Object { return &workv1.ManifestWork{} },
+		webhook,
+	).WithSubresourcesIgnored(), nil
+}
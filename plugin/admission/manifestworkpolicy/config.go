@@ -0,0 +1,139 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkpolicy
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+type Action string
+
+const (
+	// ActionDeny rejects the ManifestWork that contains the matched manifests
+	ActionDeny Action = "Deny"
+	// ActionWarn admits the ManifestWork and returns a warning for each matched manifest
+	ActionWarn Action = "Warn"
+)
+
+// Configuration is the configuration of the ManifestWorkPolicy plugin in the admission config file
+type Configuration struct {
+	// Rules are the rules to match the manifests of the ManifestWorks, all of the rules are evaluated
+	Rules []Rule `json:"rules,omitempty"`
+	// Exemptions are the requesters that the rules are not applied to
+	Exemptions Exemptions `json:"exemptions,omitempty"`
+}
+
+// Rule matches the manifests by the GVK, namespace and name. An empty list or "*" of the api groups, versions
+// and kinds matches any value, and "" is the core api group. The namespaces and names are regular expressions
+// that must match the whole value.
+type Rule struct {
+	Name       string   `json:"name"`
+	Action     Action   `json:"action"`
+	APIGroups  []string `json:"apiGroups,omitempty"`
+	Versions   []string `json:"versions,omitempty"`
+	Kinds      []string `json:"kinds,omitempty"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	Names      []string `json:"names,omitempty"`
+	// Message is appended to the error or warning to tell the user why the manifest is not allowed
+	Message string `json:"message,omitempty"`
+}
+
+// Exemptions are the users and groups that can create any manifests, e.g. the controllers that are trusted
+type Exemptions struct {
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// loadConfiguration loads the configuration from the plugin config, the config is nil if the plugin is not
+// configured in the admission config file.
+func loadConfiguration(config io.Reader) (*Configuration, error) {
+	cfg := &Configuration{}
+	if config == nil {
+		return cfg, nil
+	}
+
+	if err := utilyaml.NewYAMLOrJSONDecoder(config, 4096).Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode the %s configuration, %v", PluginName, err)
+	}
+
+	for i, rule := range cfg.Rules {
+		if len(rule.Name) == 0 {
+			return nil, fmt.Errorf("the name of the rule %d is required", i)
+		}
+		if rule.Action != ActionDeny && rule.Action != ActionWarn {
+			return nil, fmt.Errorf("the action %q of the rule %q is invalid, it must be %s or %s",
+				rule.Action, rule.Name, ActionDeny, ActionWarn)
+		}
+	}
+
+	return cfg, nil
+}
+
+type compiledRule struct {
+	Rule
+	namespaces []*regexp.Regexp
+	names      []*regexp.Regexp
+}
+
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	compiledRules := []compiledRule{}
+	for _, rule := range rules {
+		namespaces, err := compilePatterns(rule.Namespaces)
+		if err != nil {
+			return nil, fmt.Errorf("the namespaces of the rule %q are invalid, %v", rule.Name, err)
+		}
+		names, err := compilePatterns(rule.Names)
+		if err != nil {
+			return nil, fmt.Errorf("the names of the rule %q are invalid, %v", rule.Name, err)
+		}
+		compiledRules = append(compiledRules, compiledRule{Rule: rule, namespaces: namespaces, names: names})
+	}
+	return compiledRules, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		// the pattern must match the whole value
+		regex, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, regex)
+	}
+	return regexps, nil
+}
+
+func (r *compiledRule) matches(group, version, kind, namespace, name string) bool {
+	if !matchesValues(r.APIGroups, group) || !matchesValues(r.Versions, version) || !matchesValues(r.Kinds, kind) {
+		return false
+	}
+	return matchesPatterns(r.namespaces, namespace) && matchesPatterns(r.names, name)
+}
+
+func matchesValues(expected []string, actual string) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	for _, value := range expected {
+		if value == "*" || value == actual {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPatterns(patterns []*regexp.Regexp, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	workv1 "open-cluster-management.io/api/work/v1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ManifestWorkPolicyWebhook denies or warns the ManifestWorks that contain the manifests matched by the rules
+type ManifestWorkPolicyWebhook struct {
+	rules        []compiledRule
+	exemptUsers  sets.Set[string]
+	exemptGroups sets.Set[string]
+}
+
+func NewManifestWorkPolicyWebhook(cfg *Configuration) (*ManifestWorkPolicyWebhook, error) {
+	rules, err := compileRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ManifestWorkPolicyWebhook{
+		rules:        rules,
+		exemptUsers:  sets.New(cfg.Exemptions.Users...),
+		exemptGroups: sets.New(cfg.Exemptions.Groups...),
+	}, nil
+}
+
+func (r *ManifestWorkPolicyWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	work, ok := obj.(*workv1.ManifestWork)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request manifestwork obj format is not right")
+	}
+	return r.validate(ctx, work)
+}
+
+func (r *ManifestWorkPolicyWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (runtimeadmission.Warnings, error) {
+	oldWork, ok := oldObj.(*workv1.ManifestWork)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request manifestwork obj format is not right")
+	}
+	work, ok := newObj.(*workv1.ManifestWork)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request manifestwork obj format is not right")
+	}
+
+	// the manifests are not changed, e.g. the finalizers are updated, the existing works are not blocked
+	// by the rules that are added after they are created.
+	if equality.Semantic.DeepEqual(oldWork.Spec.Workload, work.Spec.Workload) {
+		return nil, nil
+	}
+	return r.validate(ctx, work)
+}
+
+func (r *ManifestWorkPolicyWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *ManifestWorkPolicyWebhook) validate(ctx context.Context, work *workv1.ManifestWork) (runtimeadmission.Warnings, error) {
+	if len(r.rules) == 0 {
+		return nil, nil
+	}
+
+	req, err := runtimeadmission.RequestFromContext(ctx)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	if r.exemptUsers.Has(req.UserInfo.Username) || r.exemptGroups.HasAny(req.UserInfo.Groups...) {
+		return nil, nil
+	}
+
+	warnings := runtimeadmission.Warnings{}
+	denied := []string{}
+	for i, manifest := range work.Spec.Workload.Manifests {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(manifest.Raw); err != nil {
+			// the rules cannot be evaluated on the manifests that cannot be parsed, deny them instead of
+			// relying on the other plugins, which may be disabled or in warn mode
+			denied = append(denied, fmt.Sprintf("spec.workload.manifests[%d] is not a valid manifest, %v", i, err))
+			continue
+		}
+
+		gvk := obj.GroupVersionKind()
+		for _, rule := range r.rules {
+			if !rule.matches(gvk.Group, gvk.Version, gvk.Kind, obj.GetNamespace(), obj.GetName()) {
+				continue
+			}
+
+			msg := fmt.Sprintf("spec.workload.manifests[%d] %s %s is matched by the rule %q",
+				i, gvk.Kind, manifestName(obj), rule.Name)
+			if len(rule.Message) != 0 {
+				msg = fmt.Sprintf("%s: %s", msg, rule.Message)
+			}
+
+			switch rule.Action {
+			case ActionDeny:
+				denied = append(denied, msg)
+			case ActionWarn:
+				warnings = append(warnings, msg)
+			}
+		}
+	}
+
+	if len(denied) != 0 {
+		return warnings, apierrors.NewForbidden(workv1.GroupVersion.WithResource("manifestworks").GroupResource(),
+			work.Name, fmt.Errorf("%s", strings.Join(denied, "; ")))
+	}
+	return warnings, nil
+}
+
+func manifestName(obj *unstructured.Unstructured) string {
+	if len(obj.GetNamespace()) == 0 {
+		return obj.GetName()
+	}
+	return fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
+}
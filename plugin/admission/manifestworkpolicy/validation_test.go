@@ -0,0 +1,188 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkpolicy
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	workv1 "open-cluster-management.io/api/work/v1"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newManifest(raw string) workv1.Manifest {
+	return workv1.Manifest{RawExtension: runtime.RawExtension{Raw: []byte(raw)}}
+}
+
+func TestValidate(t *testing.T) {
+	clusterAdminBinding := newManifest(
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRoleBinding","metadata":{"name":"cluster-admin-user1"}}`)
+	viewBinding := newManifest(
+		`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRoleBinding","metadata":{"name":"view-user1"}}`)
+	kubeSystemConfigMap := newManifest(
+		`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm1","namespace":"kube-system"}}`)
+	defaultConfigMap := newManifest(
+		`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm1","namespace":"default"}}`)
+	invalid := newManifest(`{"apiVersion":"v1","kind":"ConfigMap","metadata":`)
+
+	rules := []Rule{
+		{
+			Name:      "deny-cluster-admin-bindings",
+			Action:    ActionDeny,
+			APIGroups: []string{"rbac.authorization.k8s.io"},
+			Kinds:     []string{"ClusterRoleBinding"},
+			Names:     []string{"cluster-admin.*"},
+		},
+		{
+			Name:       "warn-kube-system",
+			Action:     ActionWarn,
+			Namespaces: []string{"kube-system"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		rules        []Rule
+		groups       []string
+		manifests    []workv1.Manifest
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name:      "no rules",
+			manifests: []workv1.Manifest{clusterAdminBinding, invalid},
+		},
+		{
+			name:      "no matched manifests",
+			rules:     rules,
+			manifests: []workv1.Manifest{viewBinding, defaultConfigMap},
+		},
+		{
+			name:      "denied manifest",
+			rules:     rules,
+			manifests: []workv1.Manifest{defaultConfigMap, clusterAdminBinding},
+			wantErr:   true,
+		},
+		{
+			name:         "warned manifest",
+			rules:        rules,
+			manifests:    []workv1.Manifest{kubeSystemConfigMap, defaultConfigMap},
+			wantWarnings: 1,
+		},
+		{
+			name:         "denied and warned manifests",
+			rules:        rules,
+			manifests:    []workv1.Manifest{kubeSystemConfigMap, clusterAdminBinding},
+			wantWarnings: 1,
+			wantErr:      true,
+		},
+		{
+			name:      "the name pattern must match the whole name",
+			rules:     []Rule{{Name: "deny-admin", Action: ActionDeny, Names: []string{"admin"}}},
+			manifests: []workv1.Manifest{clusterAdminBinding},
+		},
+		{
+			name:      "the core api group",
+			rules:     []Rule{{Name: "deny-core", Action: ActionDeny, APIGroups: []string{""}}},
+			manifests: []workv1.Manifest{defaultConfigMap},
+			wantErr:   true,
+		},
+		{
+			name:      "unparsable manifest",
+			rules:     rules,
+			manifests: []workv1.Manifest{defaultConfigMap, invalid},
+			wantErr:   true,
+		},
+		{
+			name:      "exempted group",
+			rules:     rules,
+			groups:    []string{"system:masters"},
+			manifests: []workv1.Manifest{clusterAdminBinding, invalid},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook, err := NewManifestWorkPolicyWebhook(&Configuration{
+				Rules:      tt.rules,
+				Exemptions: Exemptions{Groups: []string{"system:masters"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := runtimeadmission.NewContextWithRequest(context.TODO(), runtimeadmission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					UserInfo: authenticationv1.UserInfo{Username: "user1", Groups: tt.groups},
+				},
+			})
+			work := &workv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{Name: "work1", Namespace: "cluster1"},
+				Spec: workv1.ManifestWorkSpec{
+					Workload: workv1.ManifestsTemplate{Manifests: tt.manifests},
+				},
+			}
+
+			warnings, err := webhook.ValidateCreate(ctx, work)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !apierrors.IsForbidden(err) {
+				t.Errorf("ValidateCreate() error = %v, want a forbidden error", err)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("ValidateCreate() warnings = %v, want %d warnings", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	webhook, err := NewManifestWorkPolicyWebhook(&Configuration{
+		Rules: []Rule{{Name: "deny-all", Action: ActionDeny}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := runtimeadmission.NewContextWithRequest(context.TODO(), runtimeadmission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: "user1"}},
+	})
+	newWork := func(manifests ...workv1.Manifest) *workv1.ManifestWork {
+		return &workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{Name: "work1", Namespace: "cluster1"},
+			Spec:       workv1.ManifestWorkSpec{Workload: workv1.ManifestsTemplate{Manifests: manifests}},
+		}
+	}
+	cm1 := newManifest(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm1","namespace":"default"}}`)
+	cm2 := newManifest(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm2","namespace":"default"}}`)
+
+	tests := []struct {
+		name    string
+		oldWork *workv1.ManifestWork
+		work    *workv1.ManifestWork
+		wantErr bool
+	}{
+		{
+			name:    "the manifests are not changed",
+			oldWork: newWork(cm1),
+			work:    newWork(cm1),
+		},
+		{
+			name:    "the manifests are changed",
+			oldWork: newWork(cm1),
+			work:    newWork(cm1, cm2),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := webhook.ValidateUpdate(ctx, tt.oldWork, tt.work)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkquota
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	genericadmissioninitializer "k8s.io/apiserver/pkg/admission/initializer"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+const PluginName = "ManifestWorkQuota"
+
+var (
+	manifestWorkGVR = workv1.GroupVersion.WithResource("manifestworks")
+	manifestWorkGVK = workv1.GroupVersion.WithKind("ManifestWork")
+)
+
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(config io.Reader) (admission.Interface, error) {
+		cfg, err := LoadConfiguration(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewPlugin(cfg), nil
+	})
+}
+
+// Plugin enforces the ManifestWork limits of the namespaces, the usage is counted from the ManifestWorks in
+// the informer cache of the plugin, so the concurrent requests may exceed the limits slightly.
+type Plugin struct {
+	*admission.Handler
+	config          *Configuration
+	stopCh          <-chan struct{}
+	workLister      cache.GenericLister
+	workSynced      cache.InformerSynced
+	namespaceLister corev1listers.NamespaceLister
+}
+
+var _ admission.ValidationInterface = &Plugin{}
+var _ admission.InitializationValidator = &Plugin{}
+var _ = genericadmissioninitializer.WantsDrainedNotification(&Plugin{})
+var _ = genericadmissioninitializer.WantsDynamicClient(&Plugin{})
+var _ = genericadmissioninitializer.WantsExternalKubeInformerFactory(&Plugin{})
+
+func NewPlugin(cfg *Configuration) *Plugin {
+	return &Plugin{
+		Handler: admission.NewHandler(admission.Create, admission.Update),
+		config:  cfg,
+	}
+}
+
+func (p *Plugin) SetDrainedNotification(stopCh <-chan struct{}) {
+	p.stopCh = stopCh
+}
+
+// SetDynamicClient starts the ManifestWork informer of the plugin, the ManifestWork is a CRD, so it cannot be
+// watched with the kube informer factory. The informer is synced after the CRD is installed.
+func (p *Plugin) SetDynamicClient(client dynamic.Interface) {
+	workInformers := dynamicinformer.NewDynamicSharedInformerFactory(client, 0)
+	workInformer := workInformers.ForResource(manifestWorkGVR)
+	p.workLister = workInformer.Lister()
+	p.workSynced = workInformer.Informer().HasSynced
+	workInformers.Start(p.stopCh)
+}
+
+func (p *Plugin) SetExternalKubeInformerFactory(f informers.SharedInformerFactory) {
+	namespaceInformer := f.Core().V1().Namespaces()
+	p.namespaceLister = namespaceInformer.Lister()
+	// the ManifestWork informer is only required to count the usage, so the ManifestWorks of the namespaces
+	// without the limits are not blocked before the ManifestWork CRD is installed
+	p.SetReadyFunc(namespaceInformer.Informer().HasSynced)
+}
+
+func (p *Plugin) ValidateInitialization() error {
+	if p.config == nil {
+		return fmt.Errorf("missing configuration")
+	}
+	if p.workLister == nil || p.workSynced == nil {
+		return fmt.Errorf("missing manifestwork lister")
+	}
+	if p.namespaceLister == nil {
+		return fmt.Errorf("missing namespace lister")
+	}
+	return nil
+}
+
+func (p *Plugin) Validate(ctx context.Context, a admission.Attributes, o admission.ObjectInterfaces) error {
+	if a.GetKind() != manifestWorkGVK || len(a.GetSubresource()) != 0 {
+		return nil
+	}
+
+	if !p.WaitForReady() {
+		return admission.NewForbidden(a, fmt.Errorf("not yet ready to handle request"))
+	}
+
+	// the default limits are used if the namespace is not found
+	namespace, err := p.namespaceLister.Get(a.GetNamespace())
+	if err != nil && !apierrors.IsNotFound(err) {
+		return apierrors.NewInternalError(err)
+	}
+
+	// the invalid limits are fixed by the namespace admin, they are reported by the quota status
+	limits, err := p.config.LimitsFor(namespace)
+	if err != nil {
+		return p.forbidden(a, fmt.Errorf("the ManifestWork limits of the namespace are invalid, %v", err))
+	}
+	if limits.IsEmpty() {
+		return nil
+	}
+
+	work, err := toManifestWork(a.GetObject())
+	if err != nil {
+		return err
+	}
+
+	var oldWork *workv1.ManifestWork
+	if a.GetOperation() == admission.Update {
+		if oldWork, err = toManifestWork(a.GetOldObject()); err != nil {
+			return err
+		}
+	}
+
+	manifests := int64(len(work.Spec.Workload.Manifests))
+	if limits.MaxManifestsPerWork != nil && manifests > *limits.MaxManifestsPerWork &&
+		(oldWork == nil || manifests > int64(len(oldWork.Spec.Workload.Manifests))) {
+		return p.forbidden(a, fmt.Errorf("the ManifestWork has %d manifests, which exceeds the limit %d of the namespace",
+			manifests, *limits.MaxManifestsPerWork))
+	}
+
+	payloadSize := PayloadSize(work)
+	checkCount := limits.MaxManifestWorks != nil && oldWork == nil
+	checkSize := limits.MaxPayloadSize != nil && (oldWork == nil || payloadSize > PayloadSize(oldWork))
+	if !checkCount && !checkSize {
+		return nil
+	}
+
+	if !p.workSynced() {
+		return admission.NewForbidden(a, fmt.Errorf("not yet ready to handle request"))
+	}
+
+	used, err := p.usage(a.GetNamespace(), a.GetName())
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	if checkCount && used.ManifestWorks+1 > *limits.MaxManifestWorks {
+		return p.forbidden(a, fmt.Errorf("the namespace has %d ManifestWorks, which reaches the limit %d",
+			used.ManifestWorks, *limits.MaxManifestWorks))
+	}
+
+	if checkSize && used.PayloadSize+payloadSize > limits.MaxPayloadSize.Value() {
+		return p.forbidden(a, fmt.Errorf("the manifests size %s of the ManifestWork exceeds the limit %s of the namespace, %s is used",
+			resource.NewQuantity(payloadSize, resource.BinarySI), limits.MaxPayloadSize,
+			resource.NewQuantity(used.PayloadSize, resource.BinarySI)))
+	}
+
+	return nil
+}
+
+func (p *Plugin) forbidden(a admission.Attributes, err error) error {
+	return apierrors.NewForbidden(manifestWorkGVR.GroupResource(), a.GetName(), err)
+}
+
+// usage returns the usage of the namespace without the ManifestWork that is being admitted
+func (p *Plugin) usage(namespace, excluded string) (*Usage, error) {
+	objs, err := p.workLister.ByNamespace(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	works := []*workv1.ManifestWork{}
+	for _, obj := range objs {
+		work, err := toManifestWork(obj)
+		if err != nil {
+			return nil, err
+		}
+		if work.Name == excluded {
+			continue
+		}
+		works = append(works, work)
+	}
+	return ComputeUsage(works), nil
+}
+
+func toManifestWork(obj runtime.Object) (*workv1.ManifestWork, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	work := &workv1.ManifestWork{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, work); err != nil {
+		return nil, err
+	}
+	return work, nil
+}
@@ -0,0 +1,229 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkquota
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func newManifestWork(name string, manifests int) *unstructured.Unstructured {
+	work := &workv1.ManifestWork{
+		TypeMeta:   metav1.TypeMeta{APIVersion: workv1.GroupVersion.String(), Kind: "ManifestWork"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "cluster1"},
+	}
+	for i := 0; i < manifests; i++ {
+		work.Spec.Workload.Manifests = append(work.Spec.Workload.Manifests, workv1.Manifest{
+			RawExtension: runtime.RawExtension{Raw: []byte(fmt.Sprintf(
+				`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm%d","namespace":"default"}}`, i))},
+		})
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(work)
+	if err != nil {
+		panic(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestValidate(t *testing.T) {
+	// the size of a manifest of the test ManifestWorks
+	manifestSize := PayloadSize(&workv1.ManifestWork{Spec: workv1.ManifestWorkSpec{Workload: workv1.ManifestsTemplate{
+		Manifests: []workv1.Manifest{{RawExtension: runtime.RawExtension{
+			Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm0","namespace":"default"}}`),
+		}}},
+	}}})
+
+	tests := []struct {
+		name          string
+		defaults      Limits
+		annotations   map[string]string
+		noNamespace   bool
+		notSynced     bool
+		existing      []*unstructured.Unstructured
+		operation     admission.Operation
+		work          *unstructured.Unstructured
+		oldWork       *unstructured.Unstructured
+		wantForbidden bool
+	}{
+		{
+			name:      "no limits",
+			existing:  []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation: admission.Create,
+			work:      newManifestWork("work2", 1),
+		},
+		{
+			name:      "under the ManifestWork limit",
+			defaults:  Limits{MaxManifestWorks: ptr.To[int64](2)},
+			existing:  []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation: admission.Create,
+			work:      newManifestWork("work2", 1),
+		},
+		{
+			name:          "reaches the ManifestWork limit",
+			defaults:      Limits{MaxManifestWorks: ptr.To[int64](1)},
+			existing:      []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation:     admission.Create,
+			work:          newManifestWork("work2", 1),
+			wantForbidden: true,
+		},
+		{
+			name:          "the namespace annotation cannot raise the default limit",
+			defaults:      Limits{MaxManifestWorks: ptr.To[int64](1)},
+			annotations:   map[string]string{MaxManifestWorksAnnotation: "2"},
+			existing:      []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation:     admission.Create,
+			work:          newManifestWork("work2", 1),
+			wantForbidden: true,
+		},
+		{
+			name:          "the namespace annotation lowers the default limit",
+			defaults:      Limits{MaxManifestWorks: ptr.To[int64](2)},
+			annotations:   map[string]string{MaxManifestWorksAnnotation: "1"},
+			existing:      []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation:     admission.Create,
+			work:          newManifestWork("work2", 1),
+			wantForbidden: true,
+		},
+		{
+			name:          "the namespace annotation sets the limit without the default",
+			annotations:   map[string]string{MaxManifestsPerWorkAnnotation: "1"},
+			operation:     admission.Create,
+			work:          newManifestWork("work1", 2),
+			wantForbidden: true,
+		},
+		{
+			name:      "the ManifestWork informer is not synced for the namespace without limits",
+			notSynced: true,
+			operation: admission.Create,
+			work:      newManifestWork("work1", 1),
+		},
+		{
+			name:          "the ManifestWork informer is not synced for the namespace with limits",
+			defaults:      Limits{MaxManifestWorks: ptr.To[int64](2)},
+			notSynced:     true,
+			operation:     admission.Create,
+			work:          newManifestWork("work1", 1),
+			wantForbidden: true,
+		},
+		{
+			name:          "the default limit is used if the namespace is not found",
+			defaults:      Limits{MaxManifestWorks: ptr.To[int64](1)},
+			noNamespace:   true,
+			existing:      []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation:     admission.Create,
+			work:          newManifestWork("work2", 1),
+			wantForbidden: true,
+		},
+		{
+			name:      "the ManifestWork limit is not checked on update",
+			defaults:  Limits{MaxManifestWorks: ptr.To[int64](1)},
+			existing:  []*unstructured.Unstructured{newManifestWork("work1", 1), newManifestWork("work2", 1)},
+			operation: admission.Update,
+			work:      newManifestWork("work2", 1),
+			oldWork:   newManifestWork("work2", 1),
+		},
+		{
+			name:          "exceeds the manifests limit",
+			defaults:      Limits{MaxManifestsPerWork: ptr.To[int64](1)},
+			operation:     admission.Create,
+			work:          newManifestWork("work1", 2),
+			wantForbidden: true,
+		},
+		{
+			name:      "the manifests are not increased on update",
+			defaults:  Limits{MaxManifestsPerWork: ptr.To[int64](1)},
+			existing:  []*unstructured.Unstructured{newManifestWork("work1", 2)},
+			operation: admission.Update,
+			work:      newManifestWork("work1", 2),
+			oldWork:   newManifestWork("work1", 2),
+		},
+		{
+			name:      "under the payload size limit",
+			defaults:  Limits{MaxPayloadSize: resource.NewQuantity(2*manifestSize, resource.BinarySI)},
+			existing:  []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation: admission.Create,
+			work:      newManifestWork("work2", 1),
+		},
+		{
+			name:          "exceeds the payload size limit",
+			defaults:      Limits{MaxPayloadSize: resource.NewQuantity(2*manifestSize, resource.BinarySI)},
+			existing:      []*unstructured.Unstructured{newManifestWork("work1", 2)},
+			operation:     admission.Create,
+			work:          newManifestWork("work2", 1),
+			wantForbidden: true,
+		},
+		{
+			name:      "the updated ManifestWork is not counted twice",
+			defaults:  Limits{MaxPayloadSize: resource.NewQuantity(2*manifestSize, resource.BinarySI)},
+			existing:  []*unstructured.Unstructured{newManifestWork("work1", 1)},
+			operation: admission.Update,
+			work:      newManifestWork("work1", 2),
+			oldWork:   newManifestWork("work1", 1),
+		},
+		{
+			name:          "invalid namespace annotation",
+			annotations:   map[string]string{MaxPayloadSizeAnnotation: "1 MB"},
+			operation:     admission.Create,
+			work:          newManifestWork("work1", 1),
+			wantForbidden: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if !tt.noNamespace {
+				if err := namespaceIndexer.Add(&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: tt.annotations},
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			workIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc,
+				cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, work := range tt.existing {
+				if err := workIndexer.Add(work); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			plugin := NewPlugin(&Configuration{Default: tt.defaults})
+			plugin.namespaceLister = corev1listers.NewNamespaceLister(namespaceIndexer)
+			plugin.workLister = cache.NewGenericLister(workIndexer, manifestWorkGVR.GroupResource())
+			plugin.workSynced = func() bool { return !tt.notSynced }
+			plugin.SetReadyFunc(func() bool { return true })
+			if err := plugin.ValidateInitialization(); err != nil {
+				t.Fatal(err)
+			}
+
+			var oldObj runtime.Object
+			if tt.oldWork != nil {
+				oldObj = tt.oldWork
+			}
+			attrs := admission.NewAttributesRecord(tt.work, oldObj, manifestWorkGVK, "cluster1", tt.work.GetName(),
+				manifestWorkGVR, "", tt.operation, nil, false, &user.DefaultInfo{Name: "user1"})
+
+			err := plugin.Validate(context.TODO(), attrs, nil)
+			if apierrors.IsForbidden(err) != tt.wantForbidden {
+				t.Errorf("Validate() error = %v, wantForbidden %v", err, tt.wantForbidden)
+			}
+			if err != nil && !apierrors.IsForbidden(err) {
+				t.Errorf("Validate() error = %v, want a forbidden error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkquota
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apiserver/pkg/admission"
+	apiserverapi "k8s.io/apiserver/pkg/apis/apiserver"
+	apiserverapiv1 "k8s.io/apiserver/pkg/apis/apiserver/v1"
+	apiserverapiv1alpha1 "k8s.io/apiserver/pkg/apis/apiserver/v1alpha1"
+)
+
+const (
+	// the annotations on the namespace lower the default limits of the namespace, the annotations cannot raise the
+	// default limits, since they can be updated by the users who can update the namespace
+	MaxManifestWorksAnnotation    = "multicluster-controlplane.open-cluster-management.io/max-manifestworks"
+	MaxManifestsPerWorkAnnotation = "multicluster-controlplane.open-cluster-management.io/max-manifests-per-work"
+	MaxPayloadSizeAnnotation      = "multicluster-controlplane.open-cluster-management.io/max-manifestwork-payload-size"
+)
+
+var configScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(apiserverapi.AddToScheme(configScheme))
+	utilruntime.Must(apiserverapiv1alpha1.AddToScheme(configScheme))
+	utilruntime.Must(apiserverapiv1.AddToScheme(configScheme))
+}
+
+// Limits are the ManifestWork limits of a namespace, the limit is not enforced if it is not set
+type Limits struct {
+	// MaxManifestWorks is the maximum number of the ManifestWorks in the namespace
+	MaxManifestWorks *int64 `json:"maxManifestWorks,omitempty"`
+	// MaxManifestsPerWork is the maximum number of the manifests in a ManifestWork
+	MaxManifestsPerWork *int64 `json:"maxManifestsPerWork,omitempty"`
+	// MaxPayloadSize is the maximum total size of the manifests of all ManifestWorks in the namespace
+	MaxPayloadSize *resource.Quantity `json:"maxPayloadSize,omitempty"`
+}
+
+// IsEmpty returns true if none of the limits is set
+func (l Limits) IsEmpty() bool {
+	return l.MaxManifestWorks == nil && l.MaxManifestsPerWork == nil && l.MaxPayloadSize == nil
+}
+
+// Configuration is the configuration of the ManifestWorkQuota plugin in the admission config file
+type Configuration struct {
+	// Default are the limits of all namespaces, they can be lowered by the namespace annotations
+	Default Limits `json:"default,omitempty"`
+}
+
+// LimitsFor returns the limits of the namespace, the namespace can be nil if it is not found. A namespace
+// annotation is used only if it is lower than the default limit or the default limit is not set.
+func (c *Configuration) LimitsFor(namespace *corev1.Namespace) (Limits, error) {
+	limits := c.Default
+	if namespace == nil {
+		return limits, nil
+	}
+
+	annotations := namespace.Annotations
+	if value, ok := annotations[MaxManifestWorksAnnotation]; ok {
+		max, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return limits, fmt.Errorf("the annotation %s of the namespace %s is invalid, %v", MaxManifestWorksAnnotation, namespace.Name, err)
+		}
+		if limits.MaxManifestWorks == nil || max < *limits.MaxManifestWorks {
+			limits.MaxManifestWorks = &max
+		}
+	}
+	if value, ok := annotations[MaxManifestsPerWorkAnnotation]; ok {
+		max, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return limits, fmt.Errorf("the annotation %s of the namespace %s is invalid, %v", MaxManifestsPerWorkAnnotation, namespace.Name, err)
+		}
+		if limits.MaxManifestsPerWork == nil || max < *limits.MaxManifestsPerWork {
+			limits.MaxManifestsPerWork = &max
+		}
+	}
+	if value, ok := annotations[MaxPayloadSizeAnnotation]; ok {
+		max, err := resource.ParseQuantity(value)
+		if err != nil {
+			return limits, fmt.Errorf("the annotation %s of the namespace %s is invalid, %v", MaxPayloadSizeAnnotation, namespace.Name, err)
+		}
+		if limits.MaxPayloadSize == nil || max.Cmp(*limits.MaxPayloadSize) < 0 {
+			limits.MaxPayloadSize = &max
+		}
+	}
+
+	return limits, nil
+}
+
+// LoadConfiguration loads the configuration from the plugin config, the config is nil if the plugin is not
+// configured in the admission config file.
+func LoadConfiguration(config io.Reader) (*Configuration, error) {
+	cfg := &Configuration{}
+	if config == nil {
+		return cfg, nil
+	}
+
+	if err := utilyaml.NewYAMLOrJSONDecoder(config, 4096).Decode(cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode the %s configuration, %v", PluginName, err)
+	}
+	return cfg, nil
+}
+
+// LoadConfigurationFromFile loads the configuration of the plugin from the admission config file, so the
+// quota controller reports the usage with the same limits that the plugin enforces.
+func LoadConfigurationFromFile(admissionConfigFile string) (*Configuration, error) {
+	provider, err := admission.ReadAdmissionConfiguration([]string{PluginName}, admissionConfigFile, configScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := provider.ConfigFor(PluginName)
+	if err != nil {
+		return nil, err
+	}
+	return LoadConfiguration(config)
+}
@@ -0,0 +1,31 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkquota
+
+import (
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// Usage is the ManifestWork usage of a namespace
+type Usage struct {
+	ManifestWorks int64
+	PayloadSize   int64
+}
+
+// ComputeUsage returns the usage of the given ManifestWorks
+func ComputeUsage(works []*workv1.ManifestWork) *Usage {
+	usage := &Usage{}
+	for _, work := range works {
+		usage.ManifestWorks++
+		usage.PayloadSize += PayloadSize(work)
+	}
+	return usage
+}
+
+// PayloadSize returns the total size of the manifests of the ManifestWork
+func PayloadSize(work *workv1.ManifestWork) int64 {
+	var size int64
+	for _, manifest := range work.Spec.Workload.Manifests {
+		size += int64(len(manifest.Raw))
+	}
+	return size
+}
@@ -0,0 +1,34 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkreplicasetvalidating
+
+import (
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	workv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+	workwebhookv1alpha1 "open-cluster-management.io/ocm/pkg/work/webhook/v1alpha1"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/adapter"
+)
+
+const PluginName = "ManifestWorkReplicaSetValidating"
+
+func Register(plugins *admission.Plugins) {
+	plugins.Register(PluginName, func(config io.Reader) (admission.Interface, error) {
+		cfg, err := adapter.LoadConfiguration(PluginName, config)
+		if err != nil {
+			return nil, err
+		}
+		return NewPlugin().WithEnforcementMode(PluginName, cfg.EnforcementMode), nil
+	})
+}
+
+func NewPlugin() *adapter.ValidatingPlugin {
+	return adapter.NewValidatingPlugin(
+		workv1alpha1.GroupVersion.WithResource("manifestworkreplicasets"),
+		workv1alpha1.GroupVersion.WithKind("ManifestWorkReplicaSet"),
+		func() runtime.Object { return &workv1alpha1.ManifestWorkReplicaSet{} },
+		&workwebhookv1alpha1.ManifestWorkReplicaSetWebhook{},
+	)
+}
@@ -0,0 +1,105 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkreplicasetvalidating
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	ocmfeature "open-cluster-management.io/api/feature"
+	workv1 "open-cluster-management.io/api/work/v1"
+	workv1alpha1 "open-cluster-management.io/api/work/v1alpha1"
+	"open-cluster-management.io/ocm/pkg/features"
+)
+
+func init() {
+	utilruntime.Must(features.HubMutableFeatureGate.Add(ocmfeature.DefaultHubWorkFeatureGates))
+}
+
+func TestManifestWorkReplicaSetValidating(t *testing.T) {
+	configMap := workv1.Manifest{RawExtension: runtime.RawExtension{
+		Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm1","namespace":"default"}}`),
+	}}
+
+	tests := []struct {
+		name           string
+		featureEnabled bool
+		operation      admission.Operation
+		manifests      []workv1.Manifest
+		wantErr        func(error) bool
+	}{
+		{
+			name:           "valid",
+			featureEnabled: true,
+			operation:      admission.Create,
+			manifests:      []workv1.Manifest{configMap},
+		},
+		{
+			name:           "valid update",
+			featureEnabled: true,
+			operation:      admission.Update,
+			manifests:      []workv1.Manifest{configMap},
+		},
+		{
+			name:           "empty manifests",
+			featureEnabled: true,
+			operation:      admission.Create,
+			wantErr:        apierrors.IsBadRequest,
+		},
+		{
+			name:      "the feature is disabled",
+			operation: admission.Create,
+			manifests: []workv1.Manifest{configMap},
+			wantErr:   apierrors.IsForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utilruntime.Must(features.HubMutableFeatureGate.SetFromMap(map[string]bool{
+				string(ocmfeature.ManifestWorkReplicaSet): tt.featureEnabled,
+			}))
+			defer func() {
+				utilruntime.Must(features.HubMutableFeatureGate.SetFromMap(map[string]bool{
+					string(ocmfeature.ManifestWorkReplicaSet): false,
+				}))
+			}()
+
+			mwrSet := &workv1alpha1.ManifestWorkReplicaSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: workv1alpha1.GroupVersion.String(), Kind: "ManifestWorkReplicaSet"},
+				ObjectMeta: metav1.ObjectMeta{Name: "mwrset1", Namespace: "default"},
+				Spec: workv1alpha1.ManifestWorkReplicaSetSpec{
+					ManifestWorkTemplate: workv1.ManifestWorkSpec{
+						Workload: workv1.ManifestsTemplate{Manifests: tt.manifests},
+					},
+					PlacementRefs: []workv1alpha1.LocalPlacementReference{{Name: "placement1"}},
+				},
+			}
+			obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(mwrSet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var oldObj runtime.Object
+			if tt.operation == admission.Update {
+				oldObj = &unstructured.Unstructured{Object: obj}
+			}
+
+			attrs := admission.NewAttributesRecord(&unstructured.Unstructured{Object: obj}, oldObj,
+				workv1alpha1.GroupVersion.WithKind("ManifestWorkReplicaSet"), "default", "mwrset1",
+				workv1alpha1.GroupVersion.WithResource("manifestworkreplicasets"), "", tt.operation, nil,
+				false, &user.DefaultInfo{Name: "user1"})
+			err = NewPlugin().Validate(context.TODO(), attrs, nil)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("Validate() error = %v", err)
+			case tt.wantErr != nil && !tt.wantErr(err):
+				t.Errorf("Validate() unexpected error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkvalidating
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	ocmfeature "open-cluster-management.io/api/feature"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/ocm/pkg/features"
+	"open-cluster-management.io/ocm/pkg/work/webhook/common"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var manifestWorkResource = workv1.GroupVersion.WithResource("manifestworks").GroupResource()
+
+// ManifestWorkWebhook validates the manifests and the executor of the ManifestWorks, it follows the ocm
+// ManifestWork webhook, but the executor is denied with a forbidden error, so the executor check is always
+// enforced whatever the enforcement mode of the plugin is.
+type ManifestWorkWebhook struct {
+	kubeClient kubernetes.Interface
+}
+
+// SetExternalKubeClientSet sets the kube client to review the executor permissions
+func (r *ManifestWorkWebhook) SetExternalKubeClientSet(client kubernetes.Interface) {
+	r.kubeClient = client
+}
+
+func (r *ManifestWorkWebhook) ValidateInitialization() error {
+	if r.kubeClient == nil {
+		return fmt.Errorf("missing kube client")
+	}
+	return nil
+}
+
+func (r *ManifestWorkWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	work, ok := obj.(*workv1.ManifestWork)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request manifestwork obj format is not right")
+	}
+	return nil, r.validateRequest(ctx, work, nil)
+}
+
+func (r *ManifestWorkWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (
+	runtimeadmission.Warnings, error) {
+	newWork, ok := newObj.(*workv1.ManifestWork)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request manifestwork obj format is not right")
+	}
+	oldWork, ok := oldObj.(*workv1.ManifestWork)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request manifestwork obj format is not right")
+	}
+	return nil, r.validateRequest(ctx, newWork, oldWork)
+}
+
+func (r *ManifestWorkWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, nil
+}
+
+// validateRequest checks the executor before the manifests, so the executor is checked even if the manifests
+// are invalid and the invalid manifests are admitted by the enforcement mode.
+func (r *ManifestWorkWebhook) validateRequest(ctx context.Context, newWork, oldWork *workv1.ManifestWork) error {
+	req, err := runtimeadmission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	// do not need to check the executor when it is not changed
+	if oldWork == nil || !reflect.DeepEqual(oldWork.Spec.Executor, newWork.Spec.Executor) {
+		if err := r.validateExecutor(ctx, newWork, req.UserInfo); err != nil {
+			return err
+		}
+	}
+
+	if len(newWork.Spec.Workload.Manifests) == 0 {
+		return apierrors.NewBadRequest("manifests should not be empty")
+	}
+	if err := common.ManifestValidator.ValidateManifests(newWork.Spec.Workload.Manifests); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	return nil
+}
+
+func (r *ManifestWorkWebhook) validateExecutor(ctx context.Context, work *workv1.ManifestWork,
+	userInfo authenticationv1.UserInfo) error {
+	executor := work.Spec.Executor
+	if executor == nil {
+		if !features.HubMutableFeatureGate.Enabled(ocmfeature.NilExecutorValidating) {
+			return nil
+		}
+		// the default service account of the work agent
+		executor = &workv1.ManifestWorkExecutor{
+			Subject: workv1.ManifestWorkExecutorSubject{
+				Type: workv1.ExecutorSubjectTypeServiceAccount,
+				ServiceAccount: &workv1.ManifestWorkSubjectServiceAccount{
+					Name: "klusterlet-work-sa",
+				},
+			},
+		}
+	}
+
+	if executor.Subject.Type == workv1.ExecutorSubjectTypeServiceAccount && executor.Subject.ServiceAccount == nil {
+		return apierrors.NewBadRequest("executor service account can not be nil")
+	}
+
+	sar, err := r.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   userInfo.Username,
+			UID:    userInfo.UID,
+			Groups: userInfo.Groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:     workv1.GroupName,
+				Resource:  "manifestworks",
+				Verb:      "execute-as",
+				Namespace: work.Namespace,
+				Name: fmt.Sprintf("system:serviceaccount:%s:%s",
+					executor.Subject.ServiceAccount.Namespace, executor.Subject.ServiceAccount.Name),
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	if !sar.Status.Allowed {
+		return apierrors.NewForbidden(manifestWorkResource, work.Name, fmt.Errorf(
+			"user %s cannot manipulate the Manifestwork with executor %s/%s in namespace %s",
+			userInfo.Username, executor.Subject.ServiceAccount.Namespace, executor.Subject.ServiceAccount.Name,
+			work.Namespace))
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright Contributors to the Open Cluster Management project
+package manifestworkvalidating
+
+import (
+	"context"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	ocmfeature "open-cluster-management.io/api/feature"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"open-cluster-management.io/ocm/pkg/features"
+
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/adapter"
+)
+
+func init() {
+	utilruntime.Must(features.HubMutableFeatureGate.Add(ocmfeature.DefaultHubWorkFeatureGates))
+}
+
+func TestManifestWorkValidating(t *testing.T) {
+	configMap := workv1.Manifest{RawExtension: runtime.RawExtension{
+		Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm1","namespace":"default"}}`),
+	}}
+	executor := &workv1.ManifestWorkExecutor{
+		Subject: workv1.ManifestWorkExecutorSubject{
+			Type: workv1.ExecutorSubjectTypeServiceAccount,
+			ServiceAccount: &workv1.ManifestWorkSubjectServiceAccount{
+				Namespace: "default",
+				Name:      "executor",
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		mode          adapter.EnforcementMode
+		manifests     []workv1.Manifest
+		executor      *workv1.ManifestWorkExecutor
+		allowed       bool
+		wantForbidden bool
+		wantErr       bool
+	}{
+		{
+			name:      "valid without executor",
+			mode:      adapter.EnforcementModeEnforce,
+			manifests: []workv1.Manifest{configMap},
+		},
+		{
+			name:      "valid with allowed executor",
+			mode:      adapter.EnforcementModeEnforce,
+			manifests: []workv1.Manifest{configMap},
+			executor:  executor,
+			allowed:   true,
+		},
+		{
+			name:    "empty manifests in enforce mode",
+			mode:    adapter.EnforcementModeEnforce,
+			wantErr: true,
+		},
+		{
+			name: "empty manifests in warn mode",
+			mode: adapter.EnforcementModeWarn,
+		},
+		{
+			name:          "denied executor in enforce mode",
+			mode:          adapter.EnforcementModeEnforce,
+			manifests:     []workv1.Manifest{configMap},
+			executor:      executor,
+			wantForbidden: true,
+			wantErr:       true,
+		},
+		{
+			name:          "denied executor in warn mode",
+			mode:          adapter.EnforcementModeWarn,
+			manifests:     []workv1.Manifest{configMap},
+			executor:      executor,
+			wantForbidden: true,
+			wantErr:       true,
+		},
+		{
+			name:          "denied executor with empty manifests in audit mode",
+			mode:          adapter.EnforcementModeAudit,
+			executor:      executor,
+			wantForbidden: true,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "subjectaccessreviews",
+				func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &authorizationv1.SubjectAccessReview{
+						Status: authorizationv1.SubjectAccessReviewStatus{Allowed: tt.allowed},
+					}, nil
+				})
+
+			plugin := NewPlugin().WithEnforcementMode(PluginName, tt.mode)
+			plugin.SetExternalKubeClientSet(kubeClient)
+			if err := plugin.ValidateInitialization(); err != nil {
+				t.Fatal(err)
+			}
+
+			work := &workv1.ManifestWork{
+				TypeMeta:   metav1.TypeMeta{APIVersion: workv1.GroupVersion.String(), Kind: "ManifestWork"},
+				ObjectMeta: metav1.ObjectMeta{Name: "work1", Namespace: "cluster1"},
+				Spec: workv1.ManifestWorkSpec{
+					Workload: workv1.ManifestsTemplate{Manifests: tt.manifests},
+					Executor: tt.executor,
+				},
+			}
+			obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(work)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attrs := admission.NewAttributesRecord(&unstructured.Unstructured{Object: obj}, nil,
+				workv1.GroupVersion.WithKind("ManifestWork"), "cluster1", "work1",
+				workv1.GroupVersion.WithResource("manifestworks"), "", admission.Create, &metav1.CreateOptions{},
+				false, &user.DefaultInfo{Name: "user1"})
+			err = plugin.Validate(context.TODO(), attrs, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if apierrors.IsForbidden(err) != tt.wantForbidden {
+				t.Errorf("Validate() error = %v, want forbidden %v", err, tt.wantForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright Contributors to the Open Cluster Management project
+package placementvalidating
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+	"open-cluster-management.io/ocm/pkg/placement/controllers/scheduling"
+	runtimeadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	minPrioritizerWeight = -10
+	maxPrioritizerWeight = 10
+)
+
+var (
+	builtInPrioritizers = sets.New[string](
+		scheduling.PrioritizerBalance,
+		scheduling.PrioritizerSteady,
+		scheduling.PrioritizerResourceAllocatableCPU,
+		scheduling.PrioritizerResourceAllocatableMemory,
+	)
+
+	tolerationEffects = sets.New[clusterv1.TaintEffect](
+		clusterv1.TaintEffectNoSelect,
+		clusterv1.TaintEffectPreferNoSelect,
+		clusterv1.TaintEffectNoSelectIfNew,
+	)
+)
+
+// PlacementWebhook validates the placements, there is no placement webhook in the ocm, so the validation
+// rules follow the placement scheduler to reject the placements that cannot be scheduled.
+type PlacementWebhook struct{}
+
+func (r *PlacementWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (runtimeadmission.Warnings, error) {
+	placement, ok := obj.(*clusterv1beta1.Placement)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request placement obj format is not right")
+	}
+	return nil, r.validate(placement)
+}
+
+func (r *PlacementWebhook) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (runtimeadmission.Warnings, error) {
+	placement, ok := newObj.(*clusterv1beta1.Placement)
+	if !ok {
+		return nil, apierrors.NewBadRequest("Request placement obj format is not right")
+	}
+	return nil, r.validate(placement)
+}
+
+func (r *PlacementWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (runtimeadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (r *PlacementWebhook) validate(placement *clusterv1beta1.Placement) error {
+	errs := validatePlacementSpec(&placement.Spec, field.NewPath("spec"))
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(clusterv1beta1.GroupVersion.WithKind("Placement").GroupKind(), placement.Name, errs)
+}
+
+func validatePlacementSpec(spec *clusterv1beta1.PlacementSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if spec.NumberOfClusters != nil && *spec.NumberOfClusters < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("numberOfClusters"), *spec.NumberOfClusters,
+			"must be greater than or equal to 0"))
+	}
+
+	for i, predicate := range spec.Predicates {
+		errs = append(errs, validateClusterSelector(&predicate.RequiredClusterSelector,
+			fldPath.Child("predicates").Index(i).Child("requiredClusterSelector"))...)
+	}
+
+	errs = append(errs, validatePrioritizerPolicy(&spec.PrioritizerPolicy, fldPath.Child("prioritizerPolicy"))...)
+	errs = append(errs, validateSpreadPolicy(&spec.SpreadPolicy, fldPath.Child("spreadPolicy"))...)
+
+	for i, toleration := range spec.Tolerations {
+		errs = append(errs, validateToleration(&toleration, fldPath.Child("tolerations").Index(i))...)
+	}
+
+	groupNames := sets.New[string]()
+	groupsPath := fldPath.Child("decisionStrategy", "groupStrategy", "decisionGroups")
+	for i, group := range spec.DecisionStrategy.GroupStrategy.DecisionGroups {
+		if len(group.GroupName) != 0 {
+			if groupNames.Has(group.GroupName) {
+				errs = append(errs, field.Duplicate(groupsPath.Index(i).Child("groupName"), group.GroupName))
+			}
+			groupNames.Insert(group.GroupName)
+		}
+		errs = append(errs, validateClusterSelector(&group.ClusterSelector, groupsPath.Index(i).Child("groupClusterSelector"))...)
+	}
+
+	return errs
+}
+
+func validateClusterSelector(selector *clusterv1beta1.ClusterSelector, fldPath *field.Path) field.ErrorList {
+	errs := metav1validation.ValidateLabelSelector(&selector.LabelSelector,
+		metav1validation.LabelSelectorValidationOptions{}, fldPath.Child("labelSelector"))
+
+	for i, expression := range selector.ClaimSelector.MatchExpressions {
+		errs = append(errs, metav1validation.ValidateLabelSelectorRequirement(expression,
+			metav1validation.LabelSelectorValidationOptions{},
+			fldPath.Child("claimSelector", "matchExpressions").Index(i))...)
+	}
+
+	return errs
+}
+
+func validatePrioritizerPolicy(policy *clusterv1beta1.PrioritizerPolicy, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch policy.Mode {
+	case "", clusterv1beta1.PrioritizerPolicyModeAdditive, clusterv1beta1.PrioritizerPolicyModeExact:
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("mode"), policy.Mode, []string{
+			string(clusterv1beta1.PrioritizerPolicyModeAdditive),
+			string(clusterv1beta1.PrioritizerPolicyModeExact),
+		}))
+	}
+
+	for i, config := range policy.Configurations {
+		configPath := fldPath.Child("configurations").Index(i)
+
+		if config.Weight < minPrioritizerWeight || config.Weight > maxPrioritizerWeight {
+			errs = append(errs, field.Invalid(configPath.Child("weight"), config.Weight,
+				fmt.Sprintf("must be between %d and %d", minPrioritizerWeight, maxPrioritizerWeight)))
+		}
+
+		if config.ScoreCoordinate == nil {
+			errs = append(errs, field.Required(configPath.Child("scoreCoordinate"), ""))
+			continue
+		}
+
+		coordinatePath := configPath.Child("scoreCoordinate")
+		switch config.ScoreCoordinate.Type {
+		case clusterv1beta1.ScoreCoordinateTypeBuiltIn:
+			if !builtInPrioritizers.Has(config.ScoreCoordinate.BuiltIn) {
+				errs = append(errs, field.NotSupported(coordinatePath.Child("builtIn"),
+					config.ScoreCoordinate.BuiltIn, sets.List(builtInPrioritizers)))
+			}
+		case clusterv1beta1.ScoreCoordinateTypeAddOn:
+			addOn := config.ScoreCoordinate.AddOn
+			if addOn == nil {
+				errs = append(errs, field.Required(coordinatePath.Child("addOn"),
+					"addOn is required when the type is AddOn"))
+				continue
+			}
+			if len(addOn.ResourceName) == 0 {
+				errs = append(errs, field.Required(coordinatePath.Child("addOn", "resourceName"), ""))
+			}
+			if len(addOn.ScoreName) == 0 {
+				errs = append(errs, field.Required(coordinatePath.Child("addOn", "scoreName"), ""))
+			}
+		default:
+			errs = append(errs, field.NotSupported(coordinatePath.Child("type"), config.ScoreCoordinate.Type, []string{
+				clusterv1beta1.ScoreCoordinateTypeBuiltIn,
+				clusterv1beta1.ScoreCoordinateTypeAddOn,
+			}))
+		}
+	}
+
+	return errs
+}
+
+func validateSpreadPolicy(policy *clusterv1beta1.SpreadPolicy, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, constraint := range policy.SpreadConstraints {
+		constraintPath := fldPath.Child("spreadConstraints").Index(i)
+
+		if len(constraint.TopologyKey) == 0 {
+			errs = append(errs, field.Required(constraintPath.Child("topologyKey"), ""))
+		} else {
+			errs = append(errs, metav1validation.ValidateLabelName(constraint.TopologyKey, constraintPath.Child("topologyKey"))...)
+		}
+
+		switch constraint.TopologyKeyType {
+		case clusterv1beta1.TopologyKeyTypeClaim, clusterv1beta1.TopologyKeyTypeLabel:
+		default:
+			errs = append(errs, field.NotSupported(constraintPath.Child("topologyKeyType"), constraint.TopologyKeyType, []string{
+				string(clusterv1beta1.TopologyKeyTypeClaim),
+				string(clusterv1beta1.TopologyKeyTypeLabel),
+			}))
+		}
+
+		if constraint.MaxSkew <= 0 {
+			errs = append(errs, field.Invalid(constraintPath.Child("maxSkew"), constraint.MaxSkew, "must be greater than 0"))
+		}
+
+		switch constraint.WhenUnsatisfiable {
+		case "", clusterv1beta1.DoNotSchedule, clusterv1beta1.ScheduleAnyway:
+		default:
+			errs = append(errs, field.NotSupported(constraintPath.Child("whenUnsatisfiable"), constraint.WhenUnsatisfiable, []string{
+				string(clusterv1beta1.DoNotSchedule),
+				string(clusterv1beta1.ScheduleAnyway),
+			}))
+		}
+	}
+
+	return errs
+}
+
+func validateToleration(toleration *clusterv1beta1.Toleration, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(toleration.Key) != 0 {
+		errs = append(errs, metav1validation.ValidateLabelName(toleration.Key, fldPath.Child("key"))...)
+	}
+
+	switch toleration.Operator {
+	case "", clusterv1beta1.TolerationOpEqual:
+		// an empty key with the Equal operator matches nothing
+		if len(toleration.Key) == 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("operator"), toleration.Operator,
+				"operator must be Exists when key is empty"))
+		}
+	case clusterv1beta1.TolerationOpExists:
+		if len(toleration.Value) != 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("value"), toleration.Value,
+				"value must be empty when operator is Exists"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("operator"), toleration.Operator, []string{
+			string(clusterv1beta1.TolerationOpEqual),
+			string(clusterv1beta1.TolerationOpExists),
+		}))
+	}
+
+	if len(toleration.Effect) != 0 && !tolerationEffects.Has(toleration.Effect) {
+		errs = append(errs, field.NotSupported(fldPath.Child("effect"), toleration.Effect, []string{
+			string(clusterv1.TaintEffectNoSelect),
+			string(clusterv1.TaintEffectPreferNoSelect),
+			string(clusterv1.TaintEffectNoSelectIfNew),
+		}))
+	}
+
+	if toleration.TolerationSeconds != nil && *toleration.TolerationSeconds < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("tolerationSeconds"), *toleration.TolerationSeconds,
+			"must be greater than or equal to 0"))
+	}
+
+	return errs
+}
@@ -0,0 +1,258 @@
+// Copyright Contributors to the Open Cluster Management project
+package placementvalidating
+
+import (
+	"context"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/utils/ptr"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta1 "open-cluster-management.io/api/cluster/v1beta1"
+)
+
+func TestPlacementValidating(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    clusterv1beta1.PlacementSpec
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			spec: clusterv1beta1.PlacementSpec{
+				NumberOfClusters: ptr.To[int32](2),
+				Predicates: []clusterv1beta1.ClusterPredicate{
+					{
+						RequiredClusterSelector: clusterv1beta1.ClusterSelector{
+							LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+							ClaimSelector: clusterv1beta1.ClusterClaimSelector{
+								MatchExpressions: []metav1.LabelSelectorRequirement{
+									{Key: "region", Operator: metav1.LabelSelectorOpIn, Values: []string{"us"}},
+								},
+							},
+						},
+					},
+				},
+				PrioritizerPolicy: clusterv1beta1.PrioritizerPolicy{
+					Mode: clusterv1beta1.PrioritizerPolicyModeExact,
+					Configurations: []clusterv1beta1.PrioritizerConfig{
+						{
+							ScoreCoordinate: &clusterv1beta1.ScoreCoordinate{
+								Type:    clusterv1beta1.ScoreCoordinateTypeBuiltIn,
+								BuiltIn: "Steady",
+							},
+							Weight: 3,
+						},
+						{
+							ScoreCoordinate: &clusterv1beta1.ScoreCoordinate{
+								Type:  clusterv1beta1.ScoreCoordinateTypeAddOn,
+								AddOn: &clusterv1beta1.AddOnScore{ResourceName: "score", ScoreName: "cpu"},
+							},
+							Weight: -1,
+						},
+					},
+				},
+				SpreadPolicy: clusterv1beta1.SpreadPolicy{
+					SpreadConstraints: []clusterv1beta1.SpreadConstraintsTerm{
+						{TopologyKey: "zone", TopologyKeyType: clusterv1beta1.TopologyKeyTypeLabel, MaxSkew: 1},
+					},
+				},
+				Tolerations: []clusterv1beta1.Toleration{
+					{
+						Key:               "cluster.open-cluster-management.io/unreachable",
+						Operator:          clusterv1beta1.TolerationOpExists,
+						TolerationSeconds: ptr.To[int64](300),
+					},
+					{Operator: clusterv1beta1.TolerationOpExists, Effect: clusterv1.TaintEffectNoSelect},
+				},
+			},
+		},
+		{
+			name:    "negative number of clusters",
+			spec:    clusterv1beta1.PlacementSpec{NumberOfClusters: ptr.To[int32](-1)},
+			wantErr: true,
+		},
+		{
+			name: "invalid label selector",
+			spec: clusterv1beta1.PlacementSpec{
+				Predicates: []clusterv1beta1.ClusterPredicate{
+					{
+						RequiredClusterSelector: clusterv1beta1.ClusterSelector{
+							LabelSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+								{Key: "env", Operator: metav1.LabelSelectorOpIn},
+							}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid claim selector",
+			spec: clusterv1beta1.PlacementSpec{
+				Predicates: []clusterv1beta1.ClusterPredicate{
+					{
+						RequiredClusterSelector: clusterv1beta1.ClusterSelector{
+							ClaimSelector: clusterv1beta1.ClusterClaimSelector{
+								MatchExpressions: []metav1.LabelSelectorRequirement{
+									{Key: "region", Operator: "Unknown"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown prioritizer mode",
+			spec: clusterv1beta1.PlacementSpec{
+				PrioritizerPolicy: clusterv1beta1.PrioritizerPolicy{Mode: "Unknown"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown builtin prioritizer",
+			spec: clusterv1beta1.PlacementSpec{
+				PrioritizerPolicy: clusterv1beta1.PrioritizerPolicy{
+					Configurations: []clusterv1beta1.PrioritizerConfig{
+						{
+							ScoreCoordinate: &clusterv1beta1.ScoreCoordinate{
+								Type:    clusterv1beta1.ScoreCoordinateTypeBuiltIn,
+								BuiltIn: "Unknown",
+							},
+							Weight: 1,
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "prioritizer weight is out of range",
+			spec: clusterv1beta1.PlacementSpec{
+				PrioritizerPolicy: clusterv1beta1.PrioritizerPolicy{
+					Configurations: []clusterv1beta1.PrioritizerConfig{
+						{
+							ScoreCoordinate: &clusterv1beta1.ScoreCoordinate{
+								Type:    clusterv1beta1.ScoreCoordinateTypeBuiltIn,
+								BuiltIn: "Balance",
+							},
+							Weight: 11,
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "addon prioritizer without the score name",
+			spec: clusterv1beta1.PlacementSpec{
+				PrioritizerPolicy: clusterv1beta1.PrioritizerPolicy{
+					Configurations: []clusterv1beta1.PrioritizerConfig{
+						{
+							ScoreCoordinate: &clusterv1beta1.ScoreCoordinate{
+								Type:  clusterv1beta1.ScoreCoordinateTypeAddOn,
+								AddOn: &clusterv1beta1.AddOnScore{ResourceName: "score"},
+							},
+							Weight: 1,
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "prioritizer without the score coordinate",
+			spec: clusterv1beta1.PlacementSpec{
+				PrioritizerPolicy: clusterv1beta1.PrioritizerPolicy{
+					Configurations: []clusterv1beta1.PrioritizerConfig{{Weight: 1}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "spread constraint without the max skew",
+			spec: clusterv1beta1.PlacementSpec{
+				SpreadPolicy: clusterv1beta1.SpreadPolicy{
+					SpreadConstraints: []clusterv1beta1.SpreadConstraintsTerm{
+						{TopologyKey: "zone", TopologyKeyType: clusterv1beta1.TopologyKeyTypeLabel},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "toleration with an empty key and the Equal operator",
+			spec: clusterv1beta1.PlacementSpec{
+				Tolerations: []clusterv1beta1.Toleration{{Operator: clusterv1beta1.TolerationOpEqual, Value: "true"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "toleration with a value and the Exists operator",
+			spec: clusterv1beta1.PlacementSpec{
+				Tolerations: []clusterv1beta1.Toleration{
+					{Key: "gpu", Operator: clusterv1beta1.TolerationOpExists, Value: "true"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "toleration with an unknown effect",
+			spec: clusterv1beta1.PlacementSpec{
+				Tolerations: []clusterv1beta1.Toleration{
+					{Key: "gpu", Operator: clusterv1beta1.TolerationOpExists, Effect: "NoSchedule"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicated decision group names",
+			spec: clusterv1beta1.PlacementSpec{
+				DecisionStrategy: clusterv1beta1.DecisionStrategy{
+					GroupStrategy: clusterv1beta1.GroupStrategy{
+						DecisionGroups: []clusterv1beta1.DecisionGroup{
+							{GroupName: "canary"},
+							{GroupName: "canary"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placement := &clusterv1beta1.Placement{
+				TypeMeta:   metav1.TypeMeta{APIVersion: clusterv1beta1.GroupVersion.String(), Kind: "Placement"},
+				ObjectMeta: metav1.ObjectMeta{Name: "placement1", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+			obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(placement)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attrs := admission.NewAttributesRecord(&unstructured.Unstructured{Object: obj}, nil,
+				clusterv1beta1.GroupVersion.WithKind("Placement"), "default", "placement1",
+				clusterv1beta1.GroupVersion.WithResource("placements"), "", admission.Create, nil,
+				false, &user.DefaultInfo{Name: "user1"})
+			err = NewPlugin().Validate(context.TODO(), attrs, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !apierrors.IsInvalid(err) {
+				t.Errorf("expected invalid error, but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright Contributors to the Open Cluster Management project
+package util
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+)
+
+// ValidateConfigGroupResource validates the addon config group resource is served by the controlplane
+func ValidateConfigGroupResource(gr addonv1alpha1.ConfigGroupResource, fldPath *field.Path,
+	checker *ResourceChecker) field.ErrorList {
+	if len(gr.Resource) == 0 {
+		return field.ErrorList{field.Required(fldPath.Child("resource"), "")}
+	}
+
+	exists, err := checker.Exists(schema.GroupResource{Group: gr.Group, Resource: gr.Resource})
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, err)}
+	}
+	if !exists {
+		return field.ErrorList{field.NotFound(fldPath, fmt.Sprintf("%s.%s", gr.Resource, gr.Group))}
+	}
+	return nil
+}
+
+// ValidateConfigReferent validates the name and namespace of the addon config
+func ValidateConfigReferent(referent addonv1alpha1.ConfigReferent, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(referent.Name) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("name"), ""))
+	}
+	if len(referent.Namespace) != 0 {
+		for _, msg := range validation.ValidateNamespaceName(referent.Namespace, false) {
+			errs = append(errs, field.Invalid(fldPath.Child("namespace"), referent.Namespace, msg))
+		}
+	}
+	return errs
+}
+
+// ValidateAddOnConfigs validates the addon configs, the config resources must be served by the controlplane
+// and the same config can only be referenced once.
+func ValidateAddOnConfigs(configs []addonv1alpha1.AddOnConfig, fldPath *field.Path,
+	checker *ResourceChecker) field.ErrorList {
+	errs := field.ErrorList{}
+
+	existing := sets.New[addonv1alpha1.AddOnConfig]()
+	for i, config := range configs {
+		if existing.Has(config) {
+			errs = append(errs, field.Duplicate(fldPath.Index(i), config))
+			continue
+		}
+		existing.Insert(config)
+
+		errs = append(errs, ValidateConfigGroupResource(config.ConfigGroupResource, fldPath.Index(i), checker)...)
+		errs = append(errs, ValidateConfigReferent(config.ConfigReferent, fldPath.Index(i))...)
+	}
+	return errs
+}
@@ -0,0 +1,61 @@
+// Copyright Contributors to the Open Cluster Management project
+package util
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+)
+
+// resourceCheckerResetInterval is the minimum interval to refresh the discovery result, so the requests with
+// the unknown resources do not trigger a full discovery on each request.
+const resourceCheckerResetInterval = 10 * time.Second
+
+// ResourceChecker checks whether a resource is served by the controlplane, the discovery result is cached
+// and it is refreshed once a resource is not found, at most once per resourceCheckerResetInterval, so the
+// newly installed CRDs can be found.
+type ResourceChecker struct {
+	mapper *restmapper.DeferredDiscoveryRESTMapper
+
+	lock      sync.Mutex
+	lastReset time.Time
+}
+
+func NewResourceChecker(discoveryClient discovery.DiscoveryInterface) *ResourceChecker {
+	return &ResourceChecker{
+		mapper: restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+	}
+}
+
+func (c *ResourceChecker) Exists(gr schema.GroupResource) (bool, error) {
+	_, err := c.mapper.ResourceFor(gr.WithVersion(""))
+	if meta.IsNoMatchError(err) && c.tryReset() {
+		_, err = c.mapper.ResourceFor(gr.WithVersion(""))
+	}
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// tryReset resets the discovery result if it is not reset in the last resourceCheckerResetInterval
+func (c *ResourceChecker) tryReset() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastReset) < resourceCheckerResetInterval {
+		return false
+	}
+	c.lastReset = now
+	c.mapper.Reset()
+	return true
+}
@@ -0,0 +1,121 @@
+// Copyright Contributors to the Open Cluster Management project
+package util
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestResourceChecker_Exists(t *testing.T) {
+	configMaps := &metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+	}
+	foos := &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "foos", Kind: "Foo", Namespaced: true}},
+	}
+	fooResource := schema.GroupResource{Group: "example.com", Resource: "foos"}
+
+	tests := []struct {
+		name string
+		// installed is the resource list that is installed after the first check
+		installed *metav1.APIResourceList
+		// expired is true if the reset interval is passed after the first check
+		expired bool
+		want    bool
+	}{
+		{
+			name: "unknown resource",
+		},
+		{
+			name:      "installed resource is found after the reset interval",
+			installed: foos,
+			expired:   true,
+			want:      true,
+		},
+		{
+			name:      "installed resource is not found in the reset interval",
+			installed: foos,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discoveryClient := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{
+				Resources: []*metav1.APIResourceList{configMaps},
+			}}
+			checker := NewResourceChecker(discoveryClient)
+
+			exists, err := checker.Exists(schema.GroupResource{Resource: "configmaps"})
+			if err != nil || !exists {
+				t.Fatalf("Exists() = %v, error = %v, want the served resource exists", exists, err)
+			}
+
+			exists, err = checker.Exists(fooResource)
+			if err != nil {
+				t.Fatalf("Exists() error = %v", err)
+			}
+			if exists {
+				t.Errorf("Exists() = %v, want the unknown resource does not exist", exists)
+			}
+
+			if tt.installed != nil {
+				discoveryClient.Resources = append(discoveryClient.Resources, tt.installed)
+			}
+			if tt.expired {
+				checker.lastReset = checker.lastReset.Add(-resourceCheckerResetInterval)
+			}
+			discoveryClient.ClearActions()
+
+			exists, err = checker.Exists(fooResource)
+			if err != nil {
+				t.Fatalf("Exists() error = %v", err)
+			}
+			if exists != tt.want {
+				t.Errorf("Exists() = %v, want %v", exists, tt.want)
+			}
+			// the discovery is only refreshed after the reset interval
+			if refreshed := len(discoveryClient.Actions()) != 0; refreshed != tt.expired {
+				t.Errorf("the discovery is refreshed = %v, want %v", refreshed, tt.expired)
+			}
+		})
+	}
+}
+
+func TestResourceChecker_TryReset(t *testing.T) {
+	tests := []struct {
+		name      string
+		lastReset time.Duration
+		want      bool
+	}{
+		{
+			name: "never reset",
+			want: true,
+		},
+		{
+			name:      "reset recently",
+			lastReset: time.Second,
+		},
+		{
+			name:      "reset before the interval",
+			lastReset: resourceCheckerResetInterval + time.Second,
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewResourceChecker(&fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}})
+			if tt.lastReset != 0 {
+				checker.lastReset = time.Now().Add(-tt.lastReset)
+			}
+			if got := checker.tryReset(); got != tt.want {
+				t.Errorf("tryReset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,10 +8,12 @@ import (
 
 	"github.com/spf13/pflag"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 
 	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	"open-cluster-management.io/multicluster-controlplane/plugin/admission/managedclusterdeleteprotection"
 	"open-cluster-management.io/multicluster-controlplane/test/performance/utils"
 )
 
@@ -62,7 +64,14 @@ func (o *clusterCleanupOptions) Run() error {
 		return err
 	}
 
+	// the performance test clusters have the ManifestWorks, force delete them
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, managedclusterdeleteprotection.ForceDeleteAnnotation)
 	for _, cluster := range clusters.Items {
+		if _, err := o.hubClusterClient.ClusterV1().ManagedClusters().Patch(
+			ctx, cluster.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return err
+		}
+
 		if err := o.hubClusterClient.ClusterV1().ManagedClusters().Delete(ctx, cluster.Name, metav1.DeleteOptions{}); err != nil {
 			return err
 		}